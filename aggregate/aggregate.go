@@ -0,0 +1,266 @@
+// Package aggregate reduces the values of a column to a single number. It is
+// shared by the widgets that summarize their rows, by group_by and time
+// buckets, and by queries.
+//
+// The aggregations are sum, avg, count, count_distinct, min, max, median,
+// any percentile written as p50, p90, p95 or p99.9, stddev and variance
+// (of the population), first and last. rate and delta are stateful: they
+// follow a monotonically increasing counter, read from the last value of
+// each refresh, and need an Aggregator.
+package aggregate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Validate reports an unknown aggregation. The empty name is valid and
+// stands for the default of the caller.
+func Validate(name string) error {
+	switch name {
+	case "", "sum", "avg", "count", "count_distinct", "distinct", "min", "max",
+		"median", "stddev", "variance", "first", "last", "rate", "delta":
+		return nil
+	}
+	if _, ok := percentile(name); ok {
+		return nil
+	}
+	return fmt.Errorf("unsupported aggregation '%s'", name)
+}
+
+// Stateful reports whether an aggregation compares the values of successive
+// refreshes.
+func Stateful(name string) bool {
+	return name == "rate" || name == "delta"
+}
+
+// ValidateStateless reports an unknown aggregation, or a stateful one where
+// the values of a single refresh are reduced, such as in groups or buckets.
+func ValidateStateless(name string) error {
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if Stateful(name) {
+		return fmt.Errorf("aggregation '%s' follows a counter across refreshes and can't be used here", name)
+	}
+	return nil
+}
+
+// percentile parses a percentile name such as "p95" into a fraction. Only
+// digits and a decimal point are accepted, since strconv.ParseFloat also
+// reads "nan", "inf", exponents and hexadecimal numbers.
+func percentile(name string) (float64, bool) {
+	if !strings.HasPrefix(name, "p") || strings.Trim(name[1:], "0123456789.") != "" {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(name[1:], 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, false
+	}
+	return p / 100, true
+}
+
+// Reduce aggregates values with a stateless aggregation. It returns NaN when
+// there are no values, except for the counts, which are zero.
+func Reduce(name string, values []float64) (float64, error) {
+	if err := ValidateStateless(name); err != nil {
+		return 0, err
+	}
+	switch name {
+	case "count":
+		return float64(len(values)), nil
+	case "count_distinct", "distinct":
+		seen := make(map[float64]bool, len(values))
+		for _, v := range values {
+			seen[v] = true
+		}
+		return float64(len(seen)), nil
+	}
+	if len(values) == 0 {
+		return math.NaN(), nil
+	}
+
+	switch name {
+	case "sum":
+		return sum(values), nil
+	case "", "avg":
+		return sum(values) / float64(len(values)), nil
+	case "min":
+		min := values[0]
+		for _, v := range values {
+			min = math.Min(min, v)
+		}
+		return min, nil
+	case "max":
+		max := values[0]
+		for _, v := range values {
+			max = math.Max(max, v)
+		}
+		return max, nil
+	case "median":
+		return quantile(values, 0.5), nil
+	case "variance":
+		return variance(values), nil
+	case "stddev":
+		return math.Sqrt(variance(values)), nil
+	case "first":
+		return values[0], nil
+	case "last":
+		return values[len(values)-1], nil
+	}
+	p, _ := percentile(name)
+	return quantile(values, p), nil
+}
+
+// Cells aggregates the raw cells of a column. count counts the cells and
+// count_distinct the distinct non-empty ones, so that they work on any
+// column; the other aggregations use the cells that parse as numbers.
+func Cells(name string, cells []string, parse func(string) (float64, error)) (float64, error) {
+	switch name {
+	case "count":
+		return float64(len(cells)), nil
+	case "count_distinct", "distinct":
+		seen := make(map[string]bool)
+		for _, c := range cells {
+			if c = strings.TrimSpace(c); c != "" {
+				seen[c] = true
+			}
+		}
+		return float64(len(seen)), nil
+	}
+	return Reduce(name, Numbers(cells, parse))
+}
+
+// Numbers returns the cells that parse as numbers.
+func Numbers(cells []string, parse func(string) (float64, error)) []float64 {
+	values := make([]float64, 0, len(cells))
+	for _, c := range cells {
+		if v, err := parse(c); err == nil {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Label returns the name of an aggregation as shown next to its value.
+func Label(name string) string {
+	switch name {
+	case "avg":
+		return "Avg"
+	case "count_distinct", "distinct":
+		return "Distinct"
+	case "stddev":
+		return "Std dev"
+	}
+	if _, ok := percentile(name); ok {
+		return strings.ToUpper(name)
+	}
+	if name == "" {
+		return "Value"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func sum(values []float64) float64 {
+	s := 0.0
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+// variance returns the population variance, computed with Welford's method
+// for numerical stability.
+func variance(values []float64) float64 {
+	mean, m2 := 0.0, 0.0
+	for i, v := range values {
+		d := v - mean
+		mean += d / float64(i+1)
+		m2 += d * (v - mean)
+	}
+	return m2 / float64(len(values))
+}
+
+// quantile returns the p-quantile of the values, interpolating linearly
+// between the closest ranks.
+func quantile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// Aggregator applies an aggregation on every refresh of a widget, keeping
+// the last reading of the counter followed by rate and delta.
+type Aggregator struct {
+	name string
+
+	mu     sync.Mutex
+	last   float64
+	lastAt time.Time
+	primed bool
+}
+
+// New returns an Aggregator for the named aggregation.
+func New(name string) (*Aggregator, error) {
+	if err := Validate(name); err != nil {
+		return nil, err
+	}
+	return &Aggregator{name: name}, nil
+}
+
+// Name returns the name of the aggregation.
+func (a *Aggregator) Name() string {
+	return a.name
+}
+
+// Reduce aggregates the values of a refresh taken at now. rate and delta
+// compare the last value with the one of the previous refresh, treating a
+// decrease as a counter reset; they are NaN on the first refresh.
+func (a *Aggregator) Reduce(values []float64, now time.Time) float64 {
+	if !Stateful(a.name) {
+		v, _ := Reduce(a.name, values)
+		return v
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := values[len(values)-1]
+	last, lastAt, primed := a.last, a.lastAt, a.primed
+	a.last, a.lastAt, a.primed = current, now, true
+	if !primed {
+		return math.NaN()
+	}
+	delta := current - last
+	if delta < 0 {
+		delta = current
+	}
+	if a.name == "delta" {
+		return delta
+	}
+	elapsed := now.Sub(lastAt).Seconds()
+	if elapsed <= 0 {
+		return math.NaN()
+	}
+	return delta / elapsed
+}
+
+// Cells aggregates the raw cells of a refresh taken at now, like the
+// package-level Cells.
+func (a *Aggregator) Cells(cells []string, parse func(string) (float64, error), now time.Time) float64 {
+	if !Stateful(a.name) {
+		v, _ := Cells(a.name, cells, parse)
+		return v
+	}
+	return a.Reduce(Numbers(cells, parse), now)
+}
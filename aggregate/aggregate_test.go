@@ -0,0 +1,100 @@
+package aggregate
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReduce(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 5, 5}
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"", 10.0 / 3},
+		{"sum", 20},
+		{"avg", 10.0 / 3},
+		{"count", 6},
+		{"count_distinct", 5},
+		{"min", 1},
+		{"max", 5},
+		{"median", 3.5},
+		{"p50", 3.5},
+		{"p90", 5},
+		{"p95", 5},
+		{"p99", 5},
+		{"p20", 2},
+		{"variance", 20.0 / 9},
+		{"stddev", math.Sqrt(20.0 / 9)},
+		{"first", 4},
+		{"last", 5},
+	}
+	for _, tt := range tests {
+		got, err := Reduce(tt.name, values)
+		if err != nil {
+			t.Errorf("Reduce(%q) failed: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Reduce(%q) = %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got, _ := Reduce("p25", []float64{1, 2, 3, 4}); got != 1.75 {
+		t.Errorf("percentiles should interpolate between ranks, got %v", got)
+	}
+	if got, _ := Reduce("avg", nil); !math.IsNaN(got) {
+		t.Errorf("Reduce of no values = %v, expected NaN", got)
+	}
+	for _, name := range []string{"mode", "p101", "rate", "pnan", "pNaN", "pinf", "p1e1", "p0x10", "p-5", "p"} {
+		if _, err := Reduce(name, values); err == nil {
+			t.Errorf("Reduce(%q) expected an error", name)
+		}
+	}
+}
+
+func TestCells(t *testing.T) {
+	cells := []string{"3", "", "x", "3", "4"}
+	parse := func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+	for name, want := range map[string]float64{"count": 5, "count_distinct": 3, "sum": 10} {
+		if got, err := Cells(name, cells, parse); err != nil || got != want {
+			t.Errorf("Cells(%q) = %v, %v; expected %v", name, got, err, want)
+		}
+	}
+}
+
+func TestAggregator_Counter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rate, err := New("rate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delta, _ := New("delta")
+
+	readings := []struct {
+		counter   float64
+		rate      float64
+		delta     float64
+		afterSecs int
+	}{
+		{100, math.NaN(), math.NaN(), 0},
+		{130, 3, 30, 10},
+		{10, 0.5, 10, 30},
+	}
+	for _, r := range readings {
+		now := start.Add(time.Duration(r.afterSecs) * time.Second)
+		values := []float64{1, r.counter}
+		if got := rate.Reduce(values, now); !sameFloat(got, r.rate) {
+			t.Errorf("rate at %ds = %v, expected %v", r.afterSecs, got, r.rate)
+		}
+		if got := delta.Reduce(values, now); !sameFloat(got, r.delta) {
+			t.Errorf("delta at %ds = %v, expected %v", r.afterSecs, got, r.delta)
+		}
+	}
+}
+
+func sameFloat(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
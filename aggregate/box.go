@@ -0,0 +1,54 @@
+package aggregate
+
+import (
+	"math"
+	"sort"
+)
+
+// Box summarizes the distribution of values for a box plot: its quartiles,
+// the whiskers reaching the most extreme values within 1.5 times the
+// interquartile range of the box, and the values beyond them.
+type Box struct {
+	Count          int
+	Q1, Median, Q3 float64
+	// Low and High are the ends of the whiskers.
+	Low, High float64
+	// Outliers are the values beyond the whiskers, in increasing order.
+	Outliers []float64
+}
+
+// Boxplot summarizes values, ignoring NaNs, and returns false when there are
+// none. The quartiles interpolate between values like the percentile
+// aggregations.
+func Boxplot(values []float64) (Box, bool) {
+	sorted := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 {
+		return Box{}, false
+	}
+	sort.Float64s(sorted)
+
+	b := Box{
+		Count:  len(sorted),
+		Q1:     quantile(sorted, 0.25),
+		Median: quantile(sorted, 0.5),
+		Q3:     quantile(sorted, 0.75),
+	}
+	fence := 1.5 * (b.Q3 - b.Q1)
+	b.Low, b.High = b.Q1, b.Q3
+	for _, v := range sorted {
+		switch {
+		case v < b.Q1-fence || v > b.Q3+fence:
+			b.Outliers = append(b.Outliers, v)
+		case v < b.Low:
+			b.Low = v
+		case v > b.High:
+			b.High = v
+		}
+	}
+	return b, true
+}
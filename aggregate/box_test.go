@@ -0,0 +1,27 @@
+package aggregate
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBoxplot(t *testing.T) {
+	values := []float64{7, 1, 2, 3, 4, 5, 6, 8, 9, math.NaN(), 40, -30}
+	b, ok := Boxplot(values)
+	if !ok {
+		t.Fatal("Boxplot found no values")
+	}
+	want := Box{Count: 11, Q1: 2.5, Median: 5, Q3: 7.5, Low: 1, High: 9, Outliers: []float64{-30, 40}}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("Boxplot = %+v, expected %+v", b, want)
+	}
+
+	if _, ok := Boxplot([]float64{math.NaN()}); ok {
+		t.Error("Boxplot of no values is not empty")
+	}
+	b, _ = Boxplot([]float64{3})
+	if b.Q1 != 3 || b.Q3 != 3 || b.Low != 3 || b.High != 3 || len(b.Outliers) != 0 {
+		t.Errorf("Boxplot of a single value = %+v", b)
+	}
+}
@@ -0,0 +1,210 @@
+// Package expr implements the small expression language used by widget
+// filters and computed columns, for example:
+//
+//	level == "error" and region in ("EU", "UK")
+//	message =~ "timeout|refused" or latency_ms >= 500
+//	owner is not null
+//	errors / requests * 100
+//	if(amount > 1000, "large", lower(tier))
+//
+// Expressions are compiled once and then bound to the columns of a data
+// source, which resolves column names and how their cells are parsed.
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Error is a syntax error at a position of the source.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// Schema resolves column names and parses cells. It is implemented by
+// loader.DataDataSource.
+type Schema interface {
+	ColumnIndex(name string) int
+	ParseNumber(col int, s string) (float64, error)
+	ParseTime(col int, s string) (time.Time, error)
+}
+
+// ColumnResolver is implemented by the schemas that can tell why a column
+// isn't found, such as a name shared by two joined sources. Bind reports
+// their errors rather than an unknown column.
+type ColumnResolver interface {
+	ResolveColumn(name string) (int, error)
+}
+
+// Expr is a compiled expression.
+type Expr struct {
+	src        string
+	root       node
+	columns    []string
+	aggregates []*Aggregate
+}
+
+// Aggregate is a call to an aggregate function in an expression compiled by
+// CompilePrefix, such as sum(amount), count(*) or count(distinct region).
+type Aggregate struct {
+	// Name is the lower case name of the function.
+	Name string
+	// Arg is the argument of the function, or nil for count(*).
+	Arg *Expr
+	// Distinct reports whether duplicate arguments are ignored.
+	Distinct bool
+}
+
+func newExpr(src string, root node, columns map[string]bool, aggs []*Aggregate) *Expr {
+	names := make([]string, 0, len(columns))
+	for c := range columns {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	return &Expr{src: src, root: root, columns: names, aggregates: aggs}
+}
+
+// Compile parses an expression.
+func Compile(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	p := &parser{src: []rune(src), tokens: tokens, columns: make(map[string]bool)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	return newExpr(src, root, p.columns, nil), nil
+}
+
+// CompilePrefix parses the longest expression at the start of src and
+// returns it with the number of bytes it spans, so that expressions can be
+// embedded in a larger language. Calls to the functions for which
+// isAggregate reports true are compiled as references to values computed
+// by the caller: see Aggregates and EvalGroup. Errors are of type *Error.
+func CompilePrefix(src string, isAggregate func(name string) bool) (*Expr, int, error) {
+	runes := []rune(src)
+	tokens, err := lex(src)
+	if lexErr, ok := err.(*Error); ok && lexErr.Pos > 0 {
+		// The expression may end before text it cannot read.
+		runes = runes[:lexErr.Pos]
+		tokens, err = lex(string(runes))
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	p := &parser{src: runes, tokens: tokens, columns: make(map[string]bool), isAggregate: isAggregate}
+	if p.peek().kind == tokEOF {
+		return nil, 0, &Error{Pos: 0, Msg: "expected an expression"}
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, 0, err
+	}
+	end := len(string(runes[:p.peek().pos]))
+	return newExpr(strings.TrimSpace(src[:end]), root, p.columns, p.aggs), end, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Columns returns the names of the columns the expression reads, including
+// the arguments of its aggregate functions.
+func (e *Expr) Columns() []string {
+	return e.columns
+}
+
+// Aggregates returns the aggregate function calls of the expression, in
+// the order EvalGroup expects their values.
+func (e *Expr) Aggregates() []*Aggregate {
+	return e.aggregates
+}
+
+// Program is an expression bound to the columns of a schema, ready to be
+// evaluated on its records.
+type Program struct {
+	expr   *Expr
+	schema Schema
+	index  map[string]int
+}
+
+// Bind resolves the columns of the expression against a schema, failing if
+// any of them is missing.
+func (e *Expr) Bind(s Schema) (*Program, error) {
+	p := &Program{expr: e, schema: s, index: make(map[string]int, len(e.columns))}
+	for _, c := range e.columns {
+		i, err := resolveColumn(s, c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w", e.src, err)
+		}
+		p.index[c] = i
+	}
+	return p, nil
+}
+
+// resolveColumn returns the index of a column of the schema.
+func resolveColumn(s Schema, name string) (int, error) {
+	if r, ok := s.(ColumnResolver); ok {
+		return r.ResolveColumn(name)
+	}
+	if i := s.ColumnIndex(name); i != -1 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("unknown column '%s'", name)
+}
+
+// Eval evaluates the expression on a record.
+func (p *Program) Eval(record []string) (Value, error) {
+	v, err := p.expr.root.eval(&env{program: p, record: record})
+	if err != nil {
+		return NullValue, fmt.Errorf("evaluating %q: %w", p.expr.src, err)
+	}
+	return v, nil
+}
+
+// EvalGroup evaluates an expression with aggregate functions on a group of
+// records: aggs holds the value of each of its Aggregates and record is a
+// representative record of the group, used by the columns read outside of
+// the aggregate functions.
+func (p *Program) EvalGroup(record []string, aggs []Value) (Value, error) {
+	v, err := p.expr.root.eval(&env{program: p, record: record, aggs: aggs})
+	if err != nil {
+		return NullValue, fmt.Errorf("evaluating %q: %w", p.expr.src, err)
+	}
+	return v, nil
+}
+
+// Match evaluates the expression as a condition on a record.
+func (p *Program) Match(record []string) (bool, error) {
+	v, err := p.Eval(record)
+	if err != nil {
+		return false, err
+	}
+	return v.Truthy(), nil
+}
+
+// env is the evaluation environment of a single record.
+type env struct {
+	program *Program
+	record  []string
+	aggs    []Value
+}
+
+// column returns the value of the named column in the current record.
+func (e *env) column(name string) Value {
+	i := e.program.index[name]
+	if i >= len(e.record) {
+		return NullValue
+	}
+	return cellValue(e.program.schema, i, e.record[i])
+}
@@ -0,0 +1,128 @@
+package expr
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSchema is a schema over a fixed header whose numbers are plain floats.
+type testSchema []string
+
+func (s testSchema) ColumnIndex(name string) int {
+	for i, h := range s {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s testSchema) ParseNumber(col int, v string) (float64, error) {
+	return strconv.ParseFloat(v, 64)
+}
+
+func (s testSchema) ParseTime(col int, v string) (time.Time, error) {
+	return time.Parse("2006-01-02 15:04", v)
+}
+
+func TestMatch(t *testing.T) {
+	schema := testSchema{"level", "region", "latency", "owner", "message"}
+	record := []string{"error", "EU", "512", "", "connection refused"}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`level == "error"`, true},
+		{`level != 'error'`, false},
+		{`latency > 500 and region in ("EU", "UK")`, true},
+		{`latency >= 1000 or region == "US"`, false},
+		{`region not in ["US"]`, true},
+		{`message =~ "timeout|refused"`, true},
+		{`message !~ "^conn"`, false},
+		{`owner is null`, true},
+		{`not (owner is not null)`, true},
+		{`latency < 1e3 && !(level = "info")`, true},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.src, err)
+			continue
+		}
+		p, err := e.Bind(schema)
+		if err != nil {
+			t.Errorf("Bind(%q) failed: %v", tt.src, err)
+			continue
+		}
+		got, err := p.Match(record)
+		if err != nil {
+			t.Errorf("Match(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) = %v, expected %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	schema := testSchema{"errors", "requests", "tier", "at", "owner"}
+	record := []string{"5", "200", " Gold ", "2024-03-15 13:45", ""}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`errors / requests * 100`, "2.5"},
+		{`-errors + 2 * 3`, "1"},
+		{`requests % 7`, "4"},
+		{`errors / 0`, ""},
+		{`"tier: " + trim(tier)`, "tier: Gold"},
+		{`upper(substr(trim(tier), 1, 2))`, "GO"},
+		{`if(errors > 3, "high", "low")`, "high"},
+		{`coalesce(owner, "nobody")`, "nobody"},
+		{`round(requests / 3, 2)`, "66.67"},
+		{`max(errors, requests, 7)`, "200"},
+		{`year(at) * 100 + month(at)`, "202403"},
+		{`weekday(at)`, "Fri"},
+		{`date(time_trunc(at, "1w"))`, "2024-03-11"},
+		{`seconds_between(at, parse_time("2024-03-15 14:00", "2006-01-02 15:04"))`, "900"},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.src, err)
+			continue
+		}
+		p, err := e.Bind(schema)
+		if err != nil {
+			t.Errorf("Bind(%q) failed: %v", tt.src, err)
+			continue
+		}
+		got, err := p.Eval(record)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Eval(%q) = %q, expected %q", tt.src, got.String(), tt.want)
+		}
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, src := range []string{"", `level ==`, `level == "error`, `region in "EU"`, `message =~ "("`, `a b`, `nope(1)`, `round()`, `1 +`} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) expected an error", src)
+		}
+	}
+	e, err := Compile(`missing == 1`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if _, err := e.Bind(testSchema{"level"}); err == nil {
+		t.Errorf("expected an error for an unknown column")
+	}
+}
@@ -0,0 +1,274 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"datacmd/series"
+)
+
+// function is a built-in function. A negative maxArgs accepts any number of
+// arguments.
+type function struct {
+	minArgs, maxArgs int
+	call             func(e *env, args []Value) (Value, error)
+}
+
+// functions holds the built-in functions, keyed by lower case name.
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		// Conditionals.
+		"if": {3, 3, func(e *env, args []Value) (Value, error) {
+			if args[0].Truthy() {
+				return args[1], nil
+			}
+			return args[2], nil
+		}},
+		"coalesce": {1, -1, func(e *env, args []Value) (Value, error) {
+			for _, a := range args {
+				if !a.IsNull() {
+					return a, nil
+				}
+			}
+			return NullValue, nil
+		}},
+
+		// Math.
+		"abs":   mathFunc(math.Abs),
+		"floor": mathFunc(math.Floor),
+		"ceil":  mathFunc(math.Ceil),
+		"sqrt":  mathFunc(math.Sqrt),
+		"round": {1, 2, func(e *env, args []Value) (Value, error) {
+			x, ok := args[0].Float()
+			if !ok {
+				return NullValue, nil
+			}
+			scale := 1.0
+			if len(args) == 2 {
+				digits, ok := args[1].Float()
+				if !ok {
+					return NullValue, nil
+				}
+				scale = math.Pow(10, digits)
+			}
+			return NumberValue(math.Round(x*scale) / scale), nil
+		}},
+		"pow": {2, 2, func(e *env, args []Value) (Value, error) {
+			x, ok1 := args[0].Float()
+			y, ok2 := args[1].Float()
+			if !ok1 || !ok2 {
+				return NullValue, nil
+			}
+			return NumberValue(math.Pow(x, y)), nil
+		}},
+		"min": {1, -1, func(e *env, args []Value) (Value, error) {
+			return pick(args, -1), nil
+		}},
+		"max": {1, -1, func(e *env, args []Value) (Value, error) {
+			return pick(args, 1), nil
+		}},
+		"number": {1, 1, func(e *env, args []Value) (Value, error) {
+			if _, ok := args[0].Float(); ok {
+				return args[0], nil
+			}
+			return NullValue, nil
+		}},
+
+		// Strings.
+		"upper": stringFunc(strings.ToUpper),
+		"lower": stringFunc(strings.ToLower),
+		"trim":  stringFunc(strings.TrimSpace),
+		"string": {1, 1, func(e *env, args []Value) (Value, error) {
+			if args[0].IsNull() {
+				return NullValue, nil
+			}
+			return StringValue(args[0].String()), nil
+		}},
+		"len": {1, 1, func(e *env, args []Value) (Value, error) {
+			if args[0].IsNull() {
+				return NullValue, nil
+			}
+			return NumberValue(float64(utf8.RuneCountInString(args[0].String()))), nil
+		}},
+		"concat": {1, -1, func(e *env, args []Value) (Value, error) {
+			var b strings.Builder
+			for _, a := range args {
+				b.WriteString(a.String())
+			}
+			return StringValue(b.String()), nil
+		}},
+		"substr": {2, 3, func(e *env, args []Value) (Value, error) {
+			// Positions start at 1, as in SQL.
+			runes := []rune(args[0].String())
+			start, ok := args[1].Float()
+			if !ok {
+				return NullValue, nil
+			}
+			from := int(math.Max(start-1, 0))
+			to := len(runes)
+			if len(args) == 3 {
+				n, ok := args[2].Float()
+				if !ok {
+					return NullValue, nil
+				}
+				to = from + int(math.Max(n, 0))
+			}
+			from, to = min(from, len(runes)), min(to, len(runes))
+			return StringValue(string(runes[from:to])), nil
+		}},
+		"contains":    stringPredicate(strings.Contains),
+		"starts_with": stringPredicate(strings.HasPrefix),
+		"ends_with":   stringPredicate(strings.HasSuffix),
+		"replace": {3, 3, func(e *env, args []Value) (Value, error) {
+			if args[0].IsNull() {
+				return NullValue, nil
+			}
+			return StringValue(strings.ReplaceAll(args[0].String(), args[1].String(), args[2].String())), nil
+		}},
+
+		// Dates.
+		"now": {0, 0, func(e *env, args []Value) (Value, error) {
+			return TimeValue(time.Now()), nil
+		}},
+		"parse_time": {1, 2, func(e *env, args []Value) (Value, error) {
+			if len(args) == 2 {
+				t, err := time.ParseInLocation(args[1].String(), args[0].String(), time.UTC)
+				if err != nil {
+					return NullValue, nil
+				}
+				return TimeValue(t), nil
+			}
+			return e.toTime(args[0]), nil
+		}},
+		"year":    timeFunc(func(t time.Time) Value { return NumberValue(float64(t.Year())) }),
+		"month":   timeFunc(func(t time.Time) Value { return NumberValue(float64(t.Month())) }),
+		"day":     timeFunc(func(t time.Time) Value { return NumberValue(float64(t.Day())) }),
+		"hour":    timeFunc(func(t time.Time) Value { return NumberValue(float64(t.Hour())) }),
+		"minute":  timeFunc(func(t time.Time) Value { return NumberValue(float64(t.Minute())) }),
+		"weekday": timeFunc(func(t time.Time) Value { return StringValue(t.Weekday().String()[:3]) }),
+		"date":    timeFunc(func(t time.Time) Value { return StringValue(t.Format("2006-01-02")) }),
+		"epoch":   timeFunc(func(t time.Time) Value { return NumberValue(float64(t.Unix())) }),
+		"format_time": {2, 2, func(e *env, args []Value) (Value, error) {
+			t := e.toTime(args[0])
+			if t.IsNull() {
+				return NullValue, nil
+			}
+			return StringValue(t.t.Format(args[1].String())), nil
+		}},
+		"time_trunc": {2, 2, func(e *env, args []Value) (Value, error) {
+			step, err := series.ParseBucket(args[1].String())
+			if err != nil {
+				return NullValue, err
+			}
+			t := e.toTime(args[0])
+			if t.IsNull() {
+				return NullValue, nil
+			}
+			return TimeValue(series.Floor(t.t, step, t.t.Location())), nil
+		}},
+		"seconds_between": {2, 2, func(e *env, args []Value) (Value, error) {
+			a, b := e.toTime(args[0]), e.toTime(args[1])
+			if a.IsNull() || b.IsNull() {
+				return NullValue, nil
+			}
+			return NumberValue(b.t.Sub(a.t).Seconds()), nil
+		}},
+	}
+}
+
+// toTime converts a value to a timestamp, parsing text with the layout of
+// the column it was read from. Values that are not times become null.
+func (e *env) toTime(v Value) Value {
+	switch v.kind {
+	case Time:
+		return v
+	case Null, Bool:
+		return NullValue
+	}
+	t, err := e.program.schema.ParseTime(v.col-1, v.str)
+	if err != nil {
+		return NullValue
+	}
+	return TimeValue(t)
+}
+
+// mathFunc wraps a numeric function of one argument.
+func mathFunc(f func(float64) float64) function {
+	return function{1, 1, func(e *env, args []Value) (Value, error) {
+		x, ok := args[0].Float()
+		if !ok {
+			return NullValue, nil
+		}
+		return NumberValue(f(x)), nil
+	}}
+}
+
+// stringFunc wraps a string function of one argument.
+func stringFunc(f func(string) string) function {
+	return function{1, 1, func(e *env, args []Value) (Value, error) {
+		if args[0].IsNull() {
+			return NullValue, nil
+		}
+		return StringValue(f(args[0].String())), nil
+	}}
+}
+
+// stringPredicate wraps a string predicate of two arguments.
+func stringPredicate(f func(string, string) bool) function {
+	return function{2, 2, func(e *env, args []Value) (Value, error) {
+		return BoolValue(f(args[0].String(), args[1].String())), nil
+	}}
+}
+
+// timeFunc wraps a function of one timestamp.
+func timeFunc(f func(time.Time) Value) function {
+	return function{1, 1, func(e *env, args []Value) (Value, error) {
+		t := e.toTime(args[0])
+		if t.IsNull() {
+			return NullValue, nil
+		}
+		return f(t.t), nil
+	}}
+}
+
+// pick returns the smallest (dir < 0) or largest (dir > 0) non-null value.
+func pick(args []Value, dir int) Value {
+	best := NullValue
+	for _, a := range args {
+		if a.IsNull() {
+			continue
+		}
+		if best.IsNull() || compare(a, best)*dir > 0 {
+			best = a
+		}
+	}
+	return best
+}
+
+// call is a call to a built-in function.
+type call struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n *call) eval(e *env) (Value, error) {
+	args := make([]Value, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(e)
+		if err != nil {
+			return NullValue, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(e, args)
+	if err != nil {
+		return NullValue, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,139 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind classifies a lexical token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokKeyword
+)
+
+// keywords are reserved words, matched case-insensitively.
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "is": true,
+	"null": true, "true": true, "false": true, "matches": true,
+}
+
+// operators are the symbolic operators, longest first.
+var operators = []string{
+	"==", "!=", "<>", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "=", "!", "(", ")", "[", "]", ",",
+	"+", "-", "*", "/", "%",
+}
+
+// token is a lexical token with its position in the source.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is reports whether the token is the given operator or keyword.
+func (t token) is(text string) bool {
+	return (t.kind == tokOp || t.kind == tokKeyword) && t.text == text
+}
+
+// lex splits src into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, &Error{Pos: start, Msg: "unterminated string"}
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					b.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == r {
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		case r == '`':
+			// Backquotes allow column names with spaces or symbols.
+			start := i
+			end := strings.IndexRune(string(runes[i+1:]), '`')
+			if end < 0 {
+				return nil, &Error{Pos: start, Msg: "unterminated column name"}
+			}
+			name := []rune(string(runes[i+1:])[:end])
+			tokens = append(tokens, token{kind: tokIdent, text: string(name), pos: start})
+			i += len(name) + 2
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				j := i + 1
+				if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+					j++
+				}
+				if j < len(runes) && unicode.IsDigit(runes[j]) {
+					i = j
+					for i < len(runes) && unicode.IsDigit(runes[i]) {
+						i++
+					}
+				}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			if keywords[strings.ToLower(text)] {
+				tokens = append(tokens, token{kind: tokKeyword, text: strings.ToLower(text), pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokIdent, text: text, pos: start})
+			}
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes)}), nil
+}
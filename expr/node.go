@@ -0,0 +1,245 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sync"
+)
+
+// node is an element of the syntax tree.
+type node interface {
+	eval(e *env) (Value, error)
+}
+
+type literal struct {
+	v Value
+}
+
+func (n *literal) eval(*env) (Value, error) {
+	return n.v, nil
+}
+
+type column struct {
+	name string
+}
+
+func (n *column) eval(e *env) (Value, error) {
+	return e.column(n.name), nil
+}
+
+// aggregateRef is the value of an aggregate function, computed by the
+// caller for the group being evaluated.
+type aggregateRef struct {
+	index int
+}
+
+func (n *aggregateRef) eval(e *env) (Value, error) {
+	if n.index >= len(e.aggs) {
+		return NullValue, fmt.Errorf("aggregate functions are not allowed here")
+	}
+	return e.aggs[n.index], nil
+}
+
+// logical is "and" or "or", evaluated with short-circuit.
+type logical struct {
+	and  bool
+	l, r node
+}
+
+func (n *logical) eval(e *env) (Value, error) {
+	l, err := n.l.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	if l.Truthy() != n.and {
+		return BoolValue(!n.and), nil
+	}
+	r, err := n.r.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	return BoolValue(r.Truthy()), nil
+}
+
+type not struct {
+	x node
+}
+
+func (n *not) eval(e *env) (Value, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	return BoolValue(!x.Truthy()), nil
+}
+
+// comparison is one of ==, !=, <, <=, > and >=. Ordering a null value is
+// always false.
+type comparison struct {
+	op   string
+	l, r node
+}
+
+func (n *comparison) eval(e *env) (Value, error) {
+	l, err := n.l.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	r, err := n.r.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	switch n.op {
+	case "==":
+		return BoolValue(equal(l, r)), nil
+	case "!=":
+		return BoolValue(!equal(l, r)), nil
+	}
+	if l.IsNull() || r.IsNull() {
+		return BoolValue(false), nil
+	}
+	c := compare(l, r)
+	switch n.op {
+	case "<":
+		return BoolValue(c < 0), nil
+	case "<=":
+		return BoolValue(c <= 0), nil
+	case ">":
+		return BoolValue(c > 0), nil
+	case ">=":
+		return BoolValue(c >= 0), nil
+	}
+	return NullValue, fmt.Errorf("unknown operator %q", n.op)
+}
+
+// arithmetic is one of +, -, *, / and %. Adding text concatenates it; any
+// other operation on null or non-numeric values is null, as is a division
+// by zero.
+type arithmetic struct {
+	op   string
+	l, r node
+}
+
+func (n *arithmetic) eval(e *env) (Value, error) {
+	l, err := n.l.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	r, err := n.r.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	if l.IsNull() || r.IsNull() {
+		return NullValue, nil
+	}
+	x, okL := l.Float()
+	y, okR := r.Float()
+	if !okL || !okR {
+		if n.op == "+" {
+			return StringValue(l.String() + r.String()), nil
+		}
+		return NullValue, nil
+	}
+	switch n.op {
+	case "+":
+		return NumberValue(x + y), nil
+	case "-":
+		return NumberValue(x - y), nil
+	case "*":
+		return NumberValue(x * y), nil
+	case "/":
+		if y == 0 {
+			return NullValue, nil
+		}
+		return NumberValue(x / y), nil
+	case "%":
+		if y == 0 {
+			return NullValue, nil
+		}
+		return NumberValue(math.Mod(x, y)), nil
+	}
+	return NullValue, fmt.Errorf("unknown operator %q", n.op)
+}
+
+type inList struct {
+	x      node
+	list   []node
+	negate bool
+}
+
+func (n *inList) eval(e *env) (Value, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	for _, item := range n.list {
+		v, err := item.eval(e)
+		if err != nil {
+			return NullValue, err
+		}
+		if equal(x, v) {
+			return BoolValue(!n.negate), nil
+		}
+	}
+	return BoolValue(n.negate), nil
+}
+
+type isNull struct {
+	x      node
+	negate bool
+}
+
+func (n *isNull) eval(e *env) (Value, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	return BoolValue(x.IsNull() != n.negate), nil
+}
+
+// match is a regular expression match. Literal patterns are compiled with
+// the expression, others on first use.
+type match struct {
+	x       node
+	pattern node
+	re      *regexp.Regexp
+	negate  bool
+}
+
+func (n *match) eval(e *env) (Value, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return NullValue, err
+	}
+	re := n.re
+	if re == nil {
+		pattern, err := n.pattern.eval(e)
+		if err != nil {
+			return NullValue, err
+		}
+		if re, err = compileRegexp(pattern.String()); err != nil {
+			return NullValue, err
+		}
+	}
+	if x.IsNull() {
+		return BoolValue(n.negate), nil
+	}
+	return BoolValue(re.MatchString(x.String()) != n.negate), nil
+}
+
+// regexpCache holds the patterns compiled at evaluation time.
+var regexpCache sync.Map
+
+// compileRegexp compiles a pattern, caching the result.
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+	}
+	regexpCache.Store(pattern, re)
+	return re, nil
+}
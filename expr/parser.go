@@ -0,0 +1,390 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive descent parser. From the lowest precedence:
+//
+//	or      := and ("or" | "||") and ...
+//	and     := not ("and" | "&&") not ...
+//	not     := ("not" | "!") not | compare
+//	compare := sum [op sum | [not] in list | is [not] null]
+//	sum     := product (("+" | "-") product)...
+//	product := unary (("*" | "/" | "%") unary)...
+//	unary   := "-" unary | operand
+//	operand := literal | column | call | "(" or ")"
+//	call    := name "(" [or ("," or)...] ")"
+type parser struct {
+	src     []rune
+	tokens  []token
+	pos     int
+	columns map[string]bool
+
+	// isAggregate tells the aggregate functions allowed in the expression,
+	// and aggs holds the calls to them found so far.
+	isAggregate func(name string) bool
+	aggs        []*Aggregate
+	inAggregate bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords.
+func (p *parser) accept(texts ...string) (token, bool) {
+	t := p.peek()
+	for _, text := range texts {
+		if t.is(text) {
+			p.pos++
+			return t, true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		t := p.peek()
+		return &Error{Pos: t.pos, Msg: fmt.Sprintf("expected %q, found %s", text, t)}
+	}
+	return nil
+}
+
+func (p *parser) parse() (node, error) {
+	if p.peek().kind == tokEOF {
+		return nil, &Error{Pos: 0, Msg: "empty expression"}
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &logical{and: false, l: l, r: r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return l, nil
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &logical{and: true, l: l, r: r}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := p.accept("==", "=", "!=", "<>", "<", "<=", ">", ">="); ok {
+		r, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		op := t.text
+		switch op {
+		case "=":
+			op = "=="
+		case "<>":
+			op = "!="
+		}
+		return &comparison{op: op, l: l, r: r}, nil
+	}
+
+	if t, ok := p.accept("=~", "!~", "matches"); ok {
+		r, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		m := &match{x: l, pattern: r, negate: t.text == "!~"}
+		if lit, ok := r.(*literal); ok {
+			re, err := compileRegexp(lit.v.String())
+			if err != nil {
+				return nil, &Error{Pos: t.pos, Msg: err.Error()}
+			}
+			m.re = re
+		}
+		return m, nil
+	}
+
+	negate := false
+	if p.peek().is("not") && p.tokens[p.pos+1].is("in") {
+		p.next()
+		negate = true
+	}
+	if _, ok := p.accept("in"); ok {
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inList{x: l, list: list, negate: negate}, nil
+	}
+
+	if _, ok := p.accept("is"); ok {
+		_, negate := p.accept("not")
+		if err := p.expect("null"); err != nil {
+			return nil, err
+		}
+		return &isNull{x: l, negate: negate}, nil
+	}
+	return l, nil
+}
+
+// parseList parses "(a, b, ...)" or "[a, b, ...]".
+func (p *parser) parseList() ([]node, error) {
+	open, ok := p.accept("(", "[")
+	if !ok {
+		return nil, &Error{Pos: open.pos, Msg: fmt.Sprintf("expected a list after 'in', found %s", open)}
+	}
+	closing := ")"
+	if open.text == "[" {
+		closing = "]"
+	}
+	var list []node
+	for {
+		n, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if err := p.expect(closing); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = &arithmetic{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) parseProduct() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("*", "/", "%")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &arithmetic{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithmetic{op: "-", l: &literal{v: NumberValue(0)}, r: x}, nil
+	}
+	return p.parseOperand()
+}
+
+// parseCall parses the arguments of a call to the named function.
+func (p *parser) parseCall(name token) (node, error) {
+	lower := strings.ToLower(name.text)
+	if p.isAggregate != nil && p.isAggregate(lower) {
+		if n, ok, err := p.parseAggregate(name); ok {
+			return n, err
+		}
+	}
+	fn, ok := functions[lower]
+	if !ok {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("unknown function '%s'", name.text)}
+	}
+	var args []node
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("wrong number of arguments for '%s'", name.text)}
+	}
+	return &call{name: strings.ToLower(name.text), fn: fn, args: args}, nil
+}
+
+// parseAggregate parses a call to an aggregate function, such as sum(x),
+// count(*) or count(distinct x). It reports false, without consuming any
+// token, when the call is to the scalar function of the same name, as in
+// max(a, b).
+func (p *parser) parseAggregate(name token) (node, bool, error) {
+	lower := strings.ToLower(name.text)
+	_, scalar := functions[lower]
+	if p.inAggregate {
+		if scalar {
+			return nil, false, nil
+		}
+		return nil, true, &Error{Pos: name.pos, Msg: fmt.Sprintf("aggregate function '%s' cannot be nested", name.text)}
+	}
+	start := p.pos
+	agg := &Aggregate{Name: lower}
+
+	if _, ok := p.accept("*"); ok {
+		if lower != "count" {
+			return nil, true, &Error{Pos: name.pos, Msg: fmt.Sprintf("only count accepts '*', not '%s'", name.text)}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, true, err
+		}
+		p.aggs = append(p.aggs, agg)
+		return &aggregateRef{index: len(p.aggs) - 1}, true, nil
+	}
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, "distinct") && !p.tokens[p.pos+1].is(")") && !p.tokens[p.pos+1].is(",") {
+		p.next()
+		agg.Distinct = true
+	}
+
+	outer := p.columns
+	p.columns = make(map[string]bool)
+	p.inAggregate = true
+	argStart := p.peek().pos
+	arg, err := p.parseOr()
+	p.inAggregate = false
+	columns := p.columns
+	p.columns = outer
+	if err != nil {
+		return nil, true, err
+	}
+	argEnd := p.peek().pos
+	if _, ok := p.accept(")"); !ok {
+		if scalar && !agg.Distinct && p.peek().is(",") {
+			p.pos = start
+			return nil, false, nil
+		}
+		return nil, true, &Error{Pos: name.pos, Msg: fmt.Sprintf("aggregate function '%s' takes a single argument", name.text)}
+	}
+	for c := range columns {
+		outer[c] = true
+	}
+	agg.Arg = newExpr(strings.TrimSpace(string(p.src[argStart:argEnd])), arg, columns, nil)
+	p.aggs = append(p.aggs, agg)
+	return &aggregateRef{index: len(p.aggs) - 1}, true, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("invalid number %q", t.text)}
+		}
+		return &literal{v: NumberValue(f)}, nil
+	case tokString:
+		return &literal{v: StringValue(t.text)}, nil
+	case tokIdent:
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t)
+		}
+		p.columns[t.text] = true
+		return &column{name: t.text}, nil
+	case tokKeyword:
+		switch t.text {
+		case "true":
+			return &literal{v: BoolValue(true)}, nil
+		case "false":
+			return &literal{v: BoolValue(false)}, nil
+		case "null":
+			return &literal{v: NullValue}, nil
+		}
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+}
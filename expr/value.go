@@ -0,0 +1,159 @@
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the dynamic type of a Value.
+type Kind int
+
+const (
+	Null Kind = iota
+	Number
+	String
+	Bool
+	Time
+)
+
+// Value is the result of evaluating an expression. Cells read from a column
+// are numbers when they parse as such, and keep their original text so that
+// they can still be compared as strings.
+type Value struct {
+	kind Kind
+	num  float64
+	str  string
+	b    bool
+	t    time.Time
+	// col is one plus the index of the column the value was read from, so
+	// that date functions can parse it with the column's time layout.
+	col int
+}
+
+// NullValue is the value of empty cells.
+var NullValue = Value{}
+
+// NumberValue returns a numeric Value.
+func NumberValue(f float64) Value {
+	return Value{kind: Number, num: f, str: strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+// StringValue returns a string Value.
+func StringValue(s string) Value {
+	return Value{kind: String, str: s}
+}
+
+// BoolValue returns a boolean Value.
+func BoolValue(b bool) Value {
+	return Value{kind: Bool, b: b}
+}
+
+// TimeValue returns a timestamp Value.
+func TimeValue(t time.Time) Value {
+	return Value{kind: Time, t: t, str: t.Format(time.RFC3339)}
+}
+
+// cellValue converts a raw cell, parsed with the column's number format.
+func cellValue(s Schema, col int, cell string) Value {
+	if strings.TrimSpace(cell) == "" {
+		return NullValue
+	}
+	if f, err := s.ParseNumber(col, cell); err == nil {
+		return Value{kind: Number, num: f, str: cell, col: col + 1}
+	}
+	return Value{kind: String, str: cell, col: col + 1}
+}
+
+// Kind returns the dynamic type of the value.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// IsNull reports whether the value is null.
+func (v Value) IsNull() bool {
+	return v.kind == Null
+}
+
+// Float returns the numeric value, if the value is a number.
+func (v Value) Float() (float64, bool) {
+	return v.num, v.kind == Number
+}
+
+// Time returns the timestamp, if the value is a time.
+func (v Value) Time() (time.Time, bool) {
+	return v.t, v.kind == Time
+}
+
+// String returns the text of the value; null is the empty string.
+func (v Value) String() string {
+	switch v.kind {
+	case Null:
+		return ""
+	case Bool:
+		return strconv.FormatBool(v.b)
+	}
+	return v.str
+}
+
+// Truthy reports whether the value counts as true in a condition.
+func (v Value) Truthy() bool {
+	switch v.kind {
+	case Bool:
+		return v.b
+	case Number:
+		return v.num != 0
+	case String:
+		return v.str != ""
+	case Time:
+		return !v.t.IsZero()
+	}
+	return false
+}
+
+// equal compares two values; numbers are compared numerically and anything
+// else by its text. Null only equals null.
+func equal(a, b Value) bool {
+	switch {
+	case a.kind == Null || b.kind == Null:
+		return a.kind == b.kind
+	case a.kind == Number && b.kind == Number:
+		return a.num == b.num
+	case a.kind == Time && b.kind == Time:
+		return a.t.Equal(b.t)
+	case a.kind == Bool || b.kind == Bool:
+		return a.Truthy() == b.Truthy()
+	}
+	return a.str == b.str
+}
+
+// compare orders two non-null values and returns -1, 0 or 1.
+func compare(a, b Value) int {
+	if a.kind == Number && b.kind == Number {
+		switch {
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+		return 0
+	}
+	if a.kind == Time && b.kind == Time {
+		return a.t.Compare(b.t)
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// Compare orders two values and returns -1, 0 or 1. Null sorts before
+// anything else, numbers and times compare by value and the rest by text.
+func Compare(a, b Value) int {
+	switch {
+	case a.kind == Null && b.kind == Null:
+		return 0
+	case a.kind == Null:
+		return -1
+	case b.kind == Null:
+		return 1
+	}
+	return compare(a, b)
+}
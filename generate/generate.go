@@ -1,15 +1,15 @@
 package generate
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"strconv"
 	"strings"
 
+	"datacmd/loader"
+
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
 )
@@ -29,6 +29,10 @@ type Source struct {
 	Type string `yaml:"type"`
 	Path string `yaml:"path,omitempty"`
 	URL  string `yaml:"url,omitempty"`
+	// Pagination follows the pages of an API.
+	Pagination *loader.Pagination `yaml:"pagination,omitempty"`
+	// Reshape pivots or melts the rows before they are drawn.
+	loader.Reshape `yaml:",inline"`
 }
 
 // WidgetConfig holds the configuration for a single widget.
@@ -50,7 +54,6 @@ type TableColumn struct {
 	DataIndex string `yaml:"dataIndex"`
 }
 
-
 // DataDataSource holds the loaded data.
 type DataDataSource struct {
 	Header  []string
@@ -107,32 +110,23 @@ func (j *JSONDataSource) Load() (*DataDataSource, error) {
 	return &data, nil
 }
 
-// APIDataSource handles loading data from an API.
+// APIDataSource handles loading data from an API, following its pages
+// like the dashboard does.
 type APIDataSource struct {
-	URL string
+	URL        string
+	Pagination *loader.Pagination
 }
 
 func (a *APIDataSource) Load() (*DataDataSource, error) {
-	resp, err := http.Get(a.URL)
+	api, err := loader.NewAPIDataSource(context.Background(), &loader.Source{Type: "api", URL: a.URL, Pagination: a.Pagination})
 	if err != nil {
-		return nil, fmt.Errorf("unable to make API request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API response failed, status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
+	data, err := api.Load()
 	if err != nil {
-		return nil, fmt.Errorf("unable to read response body: %w", err)
+		return nil, err
 	}
-
-	var data DataDataSource
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, fmt.Errorf("unable to decode API JSON response: %w", err)
-	}
-	return &data, nil
+	return &DataDataSource{Header: data.Header, Records: data.Records}, nil
 }
 
 // SystemMetricsDataSource handles loading system metrics.
@@ -160,14 +154,34 @@ func (s *SystemMetricsDataSource) Load() (*DataDataSource, error) {
 	return &data, nil
 }
 
-// isNumeric checks if a string can be parsed as a float.
+// isNumeric checks if a string can be parsed as a number, using the same
+// parser as the dashboard so that values such as "$5,000" or "12%" count.
 func isNumeric(s string) bool {
-	_, err := strconv.ParseFloat(s, 64)
-	return err == nil
+	return loader.IsNumber(s, "")
 }
 
-// GenerateDashboardConfig generates a dashboard configuration based on the provided source.
-func GenerateDashboardConfig(sourcePath string) (*Config, error) {
+// isTimeColumn checks whether the first non-empty values of a column are
+// all timestamps.
+func isTimeColumn(data *DataDataSource, colIndex int) bool {
+	sampled := 0
+	for _, record := range data.Records {
+		if sampled == 5 {
+			break
+		}
+		if strings.TrimSpace(record[colIndex]) == "" {
+			continue
+		}
+		if !loader.IsTime(data.Header[colIndex], record[colIndex]) {
+			return false
+		}
+		sampled++
+	}
+	return sampled > 0
+}
+
+// GenerateDashboardConfig generates a dashboard configuration based on the provided source,
+// reshaped as requested. The pagination is followed for API sources.
+func GenerateDashboardConfig(sourcePath string, reshape loader.Reshape, pagination *loader.Pagination) (*Config, error) {
 
 	// Evinct type from path
 	var sourceType string
@@ -200,7 +214,7 @@ func GenerateDashboardConfig(sourcePath string) (*Config, error) {
 		if sourcePath == "" {
 			return nil, fmt.Errorf("error: URL is required for 'api' type")
 		}
-		dataSource = &APIDataSource{URL: sourcePath}
+		dataSource = &APIDataSource{URL: sourcePath, Pagination: pagination}
 		sourceTitle = "Dashboard for " + sourcePath
 	case "system":
 		dataSource = &SystemMetricsDataSource{}
@@ -213,12 +227,25 @@ func GenerateDashboardConfig(sourcePath string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error loading data: %w", err)
 	}
+	reshaped, err := reshape.Apply(&loader.DataDataSource{Header: data.Header, Records: data.Records})
+	if err != nil {
+		return nil, fmt.Errorf("error reshaping data: %w", err)
+	}
+	data = &DataDataSource{Header: reshaped.Header, Records: reshaped.Records}
 
 	numericCols := make(map[string]bool)
 	var firstNumericCol string
 	var firstCategoricCol string
+	var firstTimeCol string
 
-	for _, header := range data.Header {
+	for colIndex, header := range data.Header {
+		// Timestamps, including epochs, are neither numeric nor categorical.
+		if isTimeColumn(data, colIndex) {
+			if firstTimeCol == "" {
+				firstTimeCol = header
+			}
+			continue
+		}
 		isNum := false
 		if len(data.Records) > 0 {
 			for i := 0; i < 5 && i < len(data.Records); i++ {
@@ -283,12 +310,14 @@ func GenerateDashboardConfig(sourcePath string) (*Config, error) {
 					XCol:  firstCategoricCol,
 					YCol:  header,
 				})
-				widgets = append(widgets, WidgetConfig{
-					Type:  "line",
-					Title: fmt.Sprintf("Line Chart (%s)", header),
-					XCol:  firstCategoricCol,
-					YCol:  header,
-				})
+				if firstTimeCol == "" {
+					widgets = append(widgets, WidgetConfig{
+						Type:  "line",
+						Title: fmt.Sprintf("Line Chart (%s)", header),
+						XCol:  firstCategoricCol,
+						YCol:  header,
+					})
+				}
 				widgets = append(widgets, WidgetConfig{
 					Type:     "radar",
 					Title:    fmt.Sprintf("Radar Chart (%s)", header),
@@ -297,6 +326,16 @@ func GenerateDashboardConfig(sourcePath string) (*Config, error) {
 				})
 			}
 
+			// A time column gives the line chart a real time axis.
+			if firstTimeCol != "" {
+				widgets = append(widgets, WidgetConfig{
+					Type:  "line",
+					Title: fmt.Sprintf("Line Chart (%s over %s)", header, firstTimeCol),
+					XCol:  firstTimeCol,
+					YCol:  header,
+				})
+			}
+
 			// Widgets without dependency on categorical columns
 			widgets = append(widgets, WidgetConfig{
 				Type:     "gauge",
@@ -316,11 +355,16 @@ func GenerateDashboardConfig(sourcePath string) (*Config, error) {
 		Title:   sourceTitle,
 		Refresh: 5,
 		Source: Source{
-			Type: sourceType,
-			Path: sourcePath,
+			Type:    sourceType,
+			Path:    sourcePath,
+			Reshape: reshape,
 		},
 		Widgets: widgets,
 	}
+	if sourceType == "api" {
+		config.Source.Path, config.Source.URL = "", sourcePath
+		config.Source.Pagination = pagination
+	}
 
 	return config, nil
 }
@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CachedDataSource keeps the last good copy of a remote source on disk. The
+// copy is served instead of fetching the source again until it is older
+// than TTL, and whenever fetching fails; in Offline mode it is the only
+// data served.
+type CachedDataSource struct {
+	Source DataSource
+	// Path is the file holding the cached copy.
+	Path    string
+	TTL     time.Duration
+	Offline bool
+}
+
+// cacheEntry is the content of a cache file.
+type cacheEntry struct {
+	Fetched time.Time  `json:"fetched"`
+	Header  []string   `json:"header"`
+	Records [][]string `json:"records"`
+}
+
+// CachePath returns the cache file of a remote source identified by key,
+// such as its URL, in dir, or in the user cache directory when dir is
+// empty.
+func CachePath(dir, key string) (string, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("Unable to find the cache directory: %w", err)
+		}
+		dir = filepath.Join(base, "datacmd")
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".json"), nil
+}
+
+func (c *CachedDataSource) Load() (*DataDataSource, error) {
+	cached, cacheErr := c.read()
+	expired := cacheErr == nil && time.Since(cached.fetched) >= c.TTL
+	if cacheErr == nil && (c.Offline || !expired) {
+		cached.stale = expired
+		return cached, nil
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("no cached copy available offline: %w", cacheErr)
+	}
+
+	data, err := c.Source.Load()
+	if err != nil {
+		if cacheErr != nil {
+			return nil, err
+		}
+		// The last good copy is shown, marked as stale, until the source
+		// is back.
+		cached.stale = true
+		return cached, nil
+	}
+	data.fetched = time.Now()
+	// The cache is only a fallback: failing to write it doesn't fail the
+	// load.
+	_ = c.write(data)
+	return data, nil
+}
+
+func (c *CachedDataSource) read() (*DataDataSource, error) {
+	content, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, fmt.Errorf("Unable to decode cache file %s: %w", c.Path, err)
+	}
+	return &DataDataSource{Header: entry.Header, Records: entry.Records, fetched: entry.Fetched}, nil
+}
+
+// write replaces the cache file, through a temporary file so that a reader
+// never sees half of it.
+func (c *CachedDataSource) write(data *DataDataSource) error {
+	content, err := json.Marshal(cacheEntry{Fetched: data.fetched, Header: data.Header, Records: data.Records})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return fmt.Errorf("Unable to create the cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), ".cache-*")
+	if err != nil {
+		return fmt.Errorf("Unable to write cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Unable to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Unable to write cache file: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
@@ -0,0 +1,66 @@
+package loader
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// flakySource serves a value that changes on every load, or fails.
+type flakySource struct {
+	loads int
+	fail  bool
+}
+
+func (f *flakySource) Load() (*DataDataSource, error) {
+	if f.fail {
+		return nil, errors.New("connection refused")
+	}
+	f.loads++
+	return &DataDataSource{Header: []string{"n"}, Records: [][]string{{string(rune('0' + f.loads))}}}, nil
+}
+
+func TestCachedDataSource(t *testing.T) {
+	remote := &flakySource{}
+	cache := &CachedDataSource{Source: remote, Path: filepath.Join(t.TempDir(), "cache", "api.json"), TTL: time.Hour}
+
+	if _, err := (&CachedDataSource{Source: remote, Path: cache.Path, Offline: true}).Load(); err == nil {
+		t.Errorf("expected an error offline without a cached copy")
+	}
+	data, err := cache.Load()
+	if err != nil || data.Records[0][0] != "1" || data.stale {
+		t.Fatalf("first load = %v, %v; expected a fresh copy", data, err)
+	}
+	if data, _ := cache.Load(); remote.loads != 1 || data.Records[0][0] != "1" || data.stale {
+		t.Errorf("the cached copy should be served within the TTL, got %v after %d loads", data.Records, remote.loads)
+	}
+
+	cache.TTL = 0
+	remote.fail = true
+	data, err = cache.Load()
+	if err != nil || data.Records[0][0] != "1" || !data.stale {
+		t.Errorf("a failed fetch should serve the cached copy as stale, got %v, %v", data, err)
+	}
+	if time.Since(data.fetched) > time.Minute {
+		t.Errorf("the cached copy should keep its fetch time, got %v", data.fetched)
+	}
+
+	cache.Offline = true
+	remote.fail = false
+	if data, err := cache.Load(); err != nil || remote.loads != 1 || !data.stale {
+		t.Errorf("offline loads should only read the expired cache, got %v, %v after %d loads", data, err, remote.loads)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	a := &Source{URL: "https://example.com/data"}
+	b := &Source{URL: a.URL, Pagination: &Pagination{Type: "page"}}
+	c := &Source{URL: a.URL, Pagination: &Pagination{Type: "page", MaxPages: 3}}
+	if cacheKey(a) != a.URL {
+		t.Errorf("sources without pagination should be keyed by their URL, got %q", cacheKey(a))
+	}
+	if cacheKey(a) == cacheKey(b) || cacheKey(b) == cacheKey(c) {
+		t.Errorf("sources with other pagination settings should not share a cached copy")
+	}
+}
@@ -0,0 +1,247 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"datacmd/query"
+)
+
+// DefaultSourceName is the name of the `source` of a configuration that
+// does not set one.
+const DefaultSourceName = "src"
+
+// Catalog holds the datasets of a dashboard: its named sources and the
+// results of the widget queries, which read them.
+type Catalog struct {
+	sources map[string]*Dataset
+	// order is the order of the refreshes: sources come before the
+	// datasets derived from them.
+	order   []*Dataset
+	def     *Dataset
+	widgets map[*WidgetConfig]*Dataset
+
+	// cacheDir and opts set how remote sources are loaded.
+	cacheDir string
+	opts     Options
+}
+
+func newCatalog(cacheDir string, opts Options) *Catalog {
+	return &Catalog{
+		sources:  make(map[string]*Dataset),
+		widgets:  make(map[*WidgetConfig]*Dataset),
+		cacheDir: cacheDir,
+		opts:     opts,
+	}
+}
+
+// add loads a source and adds it to the catalog.
+func (c *Catalog) add(s *Source) error {
+	if s.Name == "" {
+		s.Name = DefaultSourceName
+	}
+	if _, ok := c.sources[s.Name]; ok {
+		return fmt.Errorf("source '%s' is defined twice", s.Name)
+	}
+	var dataSource DataSource
+	switch {
+	case s.From != "" && s.Join != nil:
+		return fmt.Errorf("source '%s' can't both derive from a source and join two", s.Name)
+	case s.From != "":
+		from, err := c.input(s, s.From)
+		if err != nil {
+			return err
+		}
+		dataSource = &DerivedDataSource{From: from}
+	case s.Join != nil:
+		if err := s.Join.validate(); err != nil {
+			return fmt.Errorf("source '%s': %w", s.Name, err)
+		}
+		left, err := c.input(s, s.Join.Left)
+		if err != nil {
+			return err
+		}
+		right, err := c.input(s, s.Join.Right)
+		if err != nil {
+			return err
+		}
+		dataSource = &JoinDataSource{Join: s.Join, Left: left, Right: right}
+	default:
+		var err error
+		if dataSource, err = newDataSource(c.opts.Context, s); err != nil {
+			return fmt.Errorf("source '%s': %w", s.Name, err)
+		}
+		if s.Type == "api" {
+			if dataSource, err = c.cached(s, dataSource); err != nil {
+				return fmt.Errorf("source '%s': %w", s.Name, err)
+			}
+		}
+	}
+	d, err := NewDataset(s, dataSource)
+	if err != nil {
+		return fmt.Errorf("source '%s': %w", s.Name, err)
+	}
+	c.sources[s.Name] = d
+	c.order = append(c.order, d)
+	if c.def == nil {
+		c.def = d
+	}
+	return nil
+}
+
+// cached wraps a remote source with its disk cache.
+func (c *Catalog) cached(s *Source, dataSource DataSource) (DataSource, error) {
+	var ttl time.Duration
+	if s.CacheTTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(s.CacheTTL); err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid cache_ttl %q", s.CacheTTL)
+		}
+	}
+	path, err := CachePath(c.cacheDir, cacheKey(s))
+	if err != nil {
+		return nil, err
+	}
+	return &CachedDataSource{Source: dataSource, Path: path, TTL: ttl, Offline: c.opts.Offline}, nil
+}
+
+// cacheKey identifies the data fetched for a remote source: its URL, and
+// the settings changing what is fetched from it, so that sources sharing a
+// URL with other settings don't share a cached copy.
+func cacheKey(s *Source) string {
+	key := s.URL
+	if s.Pagination != nil {
+		settings, _ := json.Marshal(s.Pagination)
+		key += "\x00" + string(settings)
+	}
+	return key
+}
+
+// input returns a source read by the derived source s. Inputs must be
+// defined before the sources that read them, so that they are refreshed
+// first.
+func (c *Catalog) input(s *Source, name string) (*Dataset, error) {
+	d, ok := c.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("source '%s': unknown source '%s', sources can only read the ones defined before them", s.Name, name)
+	}
+	return d, nil
+}
+
+// addQuery runs the query of a widget for the first time and adds its
+// result to the catalog.
+func (c *Catalog) addQuery(w *WidgetConfig) error {
+	q, err := query.Compile(w.Query)
+	if err != nil {
+		return fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	for _, name := range q.Tables() {
+		if _, ok := c.sources[name]; !ok {
+			return fmt.Errorf("widget '%s': unknown source '%s' in query", w.Title, name)
+		}
+	}
+	d, err := NewDataset(&Source{Name: fmt.Sprintf("query of widget '%s'", w.Title)}, &QueryDataSource{Query: q, Catalog: c})
+	if err != nil {
+		return fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	c.widgets[w] = d
+	c.order = append(c.order, d)
+	return nil
+}
+
+// Source returns the named source.
+func (c *Catalog) Source(name string) (*Dataset, bool) {
+	d, ok := c.sources[name]
+	return d, ok
+}
+
+// Widget returns the data a widget draws: the result of its query, its
+// source, or the default source.
+func (c *Catalog) Widget(w *WidgetConfig) *Dataset {
+	if d, ok := c.widgets[w]; ok {
+		return d
+	}
+	if d, ok := c.sources[w.Source]; ok {
+		return d
+	}
+	return c.def
+}
+
+// Refresh reloads every source, then the datasets derived from them. The
+// datasets that fail to reload keep their last good data.
+func (c *Catalog) Refresh() error {
+	var errs []error
+	for _, d := range c.order {
+		if err := d.Refresh(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.source.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Err returns the errors of the last refresh of the datasets, or nil if
+// they all succeeded.
+func (c *Catalog) Err() error {
+	var errs []error
+	for _, d := range c.order {
+		if err := d.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.source.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// QueryDataSource runs a SQL query over the sources of a catalog.
+type QueryDataSource struct {
+	Query   *query.Query
+	Catalog *Catalog
+}
+
+func (q *QueryDataSource) Load() (*DataDataSource, error) {
+	inputs := make(map[string]*DataDataSource)
+	tables := make(map[string]*query.Table)
+	var sources []*Dataset
+	for _, name := range q.Query.Tables() {
+		d, ok := q.Catalog.Source(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown source '%s'", name)
+		}
+		sources = append(sources, d)
+		data := d.Data()
+		inputs[name] = data
+		tables[name] = &query.Table{Header: data.Header, Records: data.Records, Schema: data}
+	}
+	res, err := q.Query.Run(tables)
+	if err != nil {
+		return nil, err
+	}
+
+	// The columns copied from a source keep its parsing settings; computed
+	// ones are written with a decimal point.
+	data := &DataDataSource{Header: res.Header, Records: res.Records, columns: make(map[string]Column, len(res.Header))}
+	for i, o := range res.Origins {
+		c := Column{Locale: "en"}
+		if in, ok := inputs[o.Table]; ok {
+			c = in.columns[in.Header[o.Column]]
+		}
+		data.columns[res.Header[i]] = c
+	}
+	data.fetched, data.stale = freshness(sources...)
+	return data, nil
+}
+
+// DerivedDataSource reads the data of another source, as of its last
+// refresh.
+type DerivedDataSource struct {
+	From *Dataset
+}
+
+func (d *DerivedDataSource) Load() (*DataDataSource, error) {
+	data := d.From.Data()
+	// The copy keeps the column settings, which the loader replaces.
+	derived := &DataDataSource{Header: data.Header, Records: data.Records, columns: data.columns}
+	derived.fetched, derived.stale = freshness(d.From)
+	return derived, nil
+}
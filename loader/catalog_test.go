@@ -0,0 +1,146 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigAndData_Query(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sales.csv": "region,host,amount\nEU,a,\"1.000,5\"\nUS,b,20\nEU,b,\"2,5\"\n",
+		"hosts.csv": "host,team\na,core\nb,edge\n",
+		"config.yml": `
+source:
+  type: csv
+  path: ` + filepath.Join(dir, "sales.csv") + `
+  locale: de
+sources:
+  - name: hosts
+    type: csv
+    path: ` + filepath.Join(dir, "hosts.csv") + `
+widgets:
+  - type: bar
+    title: By team
+    query: SELECT team, sum(amount) AS total FROM src JOIN hosts h ON src.host = h.host GROUP BY team ORDER BY total DESC
+    filter: total > 0
+    transform: [moving_avg: 3, cumsum]
+  - type: table
+    title: Raw
+  - type: bar
+    title: Top sale
+    query: SELECT region, max(amount) AS top FROM src GROUP BY region ORDER BY region
+  - type: table
+    title: Amounts
+    query: SELECT coalesce(amount, 0) AS amount, amount AS raw FROM src
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, catalog, err := LoadConfigAndData(filepath.Join(dir, "config.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigAndData failed: %v", err)
+	}
+	if got, want := config.Widgets[0].Transform, []Transform{{"moving_avg", 3}, {"cumsum", 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transform = %v, expected %v", got, want)
+	}
+	data := catalog.Widget(&config.Widgets[0]).Data()
+	want := [][]string{{"core", "1000.5"}, {"edge", "22.5"}}
+	if !reflect.DeepEqual(data.Records, want) {
+		t.Errorf("query result = %v, expected %v", data.Records, want)
+	}
+	if v, err := data.ParseNumber(1, "1000.5"); err != nil || v != 1000.5 {
+		t.Errorf("computed columns should parse with a decimal point, got %v, %v", v, err)
+	}
+	if got := catalog.Widget(&config.Widgets[1]).Data(); len(got.Records) != 3 || got.Header[0] != "region" {
+		t.Errorf("widgets without a query should draw the main source")
+	}
+	top := catalog.Widget(&config.Widgets[2]).Data()
+	if want := [][]string{{"EU", "1000.5"}, {"US", "20"}}; !reflect.DeepEqual(top.Records, want) {
+		t.Errorf("aggregates should write numbers with a decimal point, got %v, expected %v", top.Records, want)
+	}
+	amounts := catalog.Widget(&config.Widgets[3]).Data()
+	if got := amounts.Records[0]; got[0] != "1000.5" || got[1] != "1.000,5" {
+		t.Errorf("computed columns should write numbers with a decimal point and copies keep the source text, got %v", got)
+	}
+	for i, cell := range amounts.Records[0] {
+		if v, err := amounts.ParseNumber(i, cell); err != nil || v != 1000.5 {
+			t.Errorf("column %d: got %v, %v, expected 1000.5", i, v, err)
+		}
+	}
+	if err := catalog.Refresh(); err != nil {
+		t.Errorf("Refresh failed: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(`
+source:
+  type: csv
+  path: `+filepath.Join(dir, "sales.csv")+`
+widgets:
+  - type: table
+    title: Broken
+    query: SELECT team FROM teams
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadConfigAndData(filepath.Join(dir, "config.yml")); err == nil {
+		t.Errorf("expected an error for a query over an unknown source")
+	}
+}
+
+func TestLoadConfigAndData_Derived(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wide.csv")
+	if err := os.WriteFile(path, []byte("region,jan,feb\nEU,1,2\nUS,3,4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(configPath, []byte(`
+sources:
+  - name: wide
+    type: csv
+    path: `+path+`
+  - name: long
+    from: wide
+    melt:
+      id: [region]
+      variable: month
+      value: sales
+widgets:
+  - type: bar
+    title: Sales
+    source: long
+  - type: table
+    title: Wide
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, catalog, err := LoadConfigAndData(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigAndData failed: %v", err)
+	}
+	long := catalog.Widget(&config.Widgets[0]).Data()
+	if want := []string{"region", "month", "sales"}; !reflect.DeepEqual(long.Header, want) || len(long.Records) != 4 {
+		t.Errorf("derived source = %v %v, expected 4 rows of %v", long.Header, long.Records, want)
+	}
+	if wide := catalog.Widget(&config.Widgets[1]).Data(); len(wide.Header) != 3 || wide.Header[1] != "jan" {
+		t.Errorf("widgets without a source should draw the first one, got %v", wide.Header)
+	}
+
+	if err := os.WriteFile(path, []byte("region,jan,feb\nEU,5,6\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if got := catalog.Widget(&config.Widgets[0]).Data().Records; len(got) != 2 || got[0][2] != "5" {
+		t.Errorf("derived sources should follow their input, got %v", got)
+	}
+}
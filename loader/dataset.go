@@ -0,0 +1,252 @@
+package loader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"datacmd/expr"
+)
+
+// ComputedColumn is a column derived from the other columns of each row,
+// e.g. `errors / requests * 100`.
+type ComputedColumn struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+
+	// expr is the compiled Expr.
+	expr *expr.Expr
+}
+
+// compileColumns compiles the computed columns of the source.
+func (s *Source) compileColumns() error {
+	seen := make(map[string]bool)
+	for i := range s.ComputedColumns {
+		c := &s.ComputedColumns[i]
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("computed column %d has no name", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("computed column '%s' is defined twice", c.Name)
+		}
+		seen[c.Name] = true
+		e, err := expr.Compile(c.Expr)
+		if err != nil {
+			return fmt.Errorf("computed column '%s': %w", c.Name, err)
+		}
+		c.expr = e
+	}
+	return nil
+}
+
+// addComputedColumns appends the computed columns of the source to every
+// record, in order, so that a column may use the ones defined before it.
+func (d *DataDataSource) addComputedColumns(s *Source) error {
+	if len(s.ComputedColumns) == 0 {
+		return nil
+	}
+	records := make([][]string, len(d.Records))
+	for i, record := range d.Records {
+		records[i] = append(make([]string, 0, len(record)+len(s.ComputedColumns)), record...)
+	}
+	d.Records = records
+
+	for _, c := range s.ComputedColumns {
+		if d.ColumnIndex(c.Name) != -1 {
+			return fmt.Errorf("computed column '%s' already exists in the data", c.Name)
+		}
+		p, err := c.expr.Bind(d)
+		if err != nil {
+			return fmt.Errorf("computed column '%s': %w", c.Name, err)
+		}
+		values := make([]string, len(d.Records))
+		for i, record := range d.Records {
+			v, err := p.Eval(record)
+			if err != nil {
+				return fmt.Errorf("computed column '%s': %w", c.Name, err)
+			}
+			// Numbers read from a cell, as in coalesce(price, 0), keep the
+			// text written in the locale of their column.
+			if f, ok := v.Float(); ok {
+				values[i] = strconv.FormatFloat(f, 'f', -1, 64)
+			} else {
+				values[i] = v.String()
+			}
+		}
+
+		// Computed numbers are always written with a decimal point.
+		col, err := s.column(c.Name)
+		if err != nil {
+			return err
+		}
+		col.Locale = "en"
+		d.Header = append(d.Header[:len(d.Header):len(d.Header)], c.Name)
+		d.columns[c.Name] = col
+		for i := range d.Records {
+			d.Records[i] = append(d.Records[i], values[i])
+		}
+	}
+	return nil
+}
+
+// Dataset is the data of a source, reloaded on every refresh together with
+// its computed columns. When a reload fails the last good data is kept.
+type Dataset struct {
+	source     *Source
+	dataSource DataSource
+
+	mu   sync.RWMutex
+	data *DataDataSource
+	err  error
+}
+
+// NewDataset loads the data of a source for the first time.
+func NewDataset(source *Source, dataSource DataSource) (*Dataset, error) {
+	if err := source.Reshape.validate(); err != nil {
+		return nil, err
+	}
+	if err := source.compileColumns(); err != nil {
+		return nil, err
+	}
+	d := &Dataset{source: source, dataSource: dataSource}
+	if err := d.Refresh(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Data returns the last data loaded successfully.
+func (d *Dataset) Data() *DataDataSource {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.data
+}
+
+// Err returns the error of the last reload, or nil if it succeeded.
+func (d *Dataset) Err() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.err
+}
+
+// Refresh reloads the data. The columns must not change between reloads,
+// since widgets resolve them once when they are created.
+func (d *Dataset) Refresh() error {
+	data, err := d.load()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil && d.data != nil && !sameHeader(d.data.Header, data.Header) {
+		err = fmt.Errorf("the columns of the data changed from %v to %v", d.data.Header, data.Header)
+	}
+	d.err = err
+	if err != nil {
+		return err
+	}
+	d.data = data
+	return nil
+}
+
+// Age returns how long ago the data was fetched from its origin, and
+// whether it is stale: served from a cache after a failed fetch, kept after
+// a failed refresh, or derived from stale data.
+func (d *Dataset) Age() (time.Duration, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return time.Since(d.data.fetched), d.data.stale || d.err != nil
+}
+
+// Fetched returns when the data was fetched from its origin. It only
+// changes when a refresh fetched new data.
+func (d *Dataset) Fetched() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.data.fetched
+}
+
+// freshness returns when the oldest of the datasets was fetched, and
+// whether any of them is stale.
+func freshness(inputs ...*Dataset) (time.Time, bool) {
+	var oldest time.Time
+	stale := false
+	for _, in := range inputs {
+		in.mu.RLock()
+		if oldest.IsZero() || in.data.fetched.Before(oldest) {
+			oldest = in.data.fetched
+		}
+		stale = stale || in.data.stale || in.err != nil
+		in.mu.RUnlock()
+	}
+	return oldest, stale
+}
+
+func (d *Dataset) load() (*DataDataSource, error) {
+	data, err := d.dataSource.Load()
+	if err != nil {
+		return nil, err
+	}
+	fetched, stale := data.fetched, data.stale
+	if fetched.IsZero() {
+		fetched = time.Now()
+	}
+	for _, record := range data.Records {
+		if len(record) != len(data.Header) {
+			return nil, fmt.Errorf("record with number of columns not matching header: %v", record)
+		}
+	}
+	if err := data.applySource(d.source); err != nil {
+		return nil, err
+	}
+	if err := data.clean(); err != nil {
+		return nil, err
+	}
+	if d.source.Pivot != nil || d.source.Melt != nil {
+		reshape := d.reshape()
+		if data, err = reshape.Apply(data); err != nil {
+			return nil, err
+		}
+		// The new columns may have settings of their own.
+		if err := data.applySource(d.source); err != nil {
+			return nil, err
+		}
+	}
+	if err := data.addComputedColumns(d.source); err != nil {
+		return nil, err
+	}
+	data.fetched, data.stale = fetched, stale
+	return data, nil
+}
+
+// reshape returns how the source is reshaped. A pivot without columns
+// keeps those of the first load, leaving out the values of its variable
+// that show up later, since the columns must not change between reloads.
+func (d *Dataset) reshape() Reshape {
+	r := d.source.Reshape
+	p := r.Pivot
+	if p == nil || len(p.Columns) > 0 {
+		return r
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.data == nil {
+		return r
+	}
+	fixed := *p
+	header := d.data.Header
+	fixed.Columns = header[len(p.ID) : len(header)-len(d.source.ComputedColumns)]
+	r.Pivot = &fixed
+	return r
+}
+
+func sameHeader(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+// staticSource returns a copy of the same data on every load.
+type staticSource struct {
+	header  []string
+	records [][]string
+}
+
+func (s *staticSource) Load() (*DataDataSource, error) {
+	records := make([][]string, len(s.records))
+	for i, r := range s.records {
+		records[i] = append([]string(nil), r...)
+	}
+	return &DataDataSource{Header: append([]string(nil), s.header...), Records: records}, nil
+}
+
+func TestDataset_ComputedColumns(t *testing.T) {
+	src := &staticSource{
+		header:  []string{"errors", "requests", "at", "price"},
+		records: [][]string{{"5", "200", "2024-03-15 13:45", "1.234,5"}, {"1", "0", "2024-03-16 08:00", ""}},
+	}
+	source := &Source{
+		Locale: "de",
+		ComputedColumns: []ComputedColumn{
+			{Name: "error_rate", Expr: "errors / requests * 100"},
+			{Name: "label", Expr: `if(error_rate >= 2, "high", "ok")`},
+			{Name: "day", Expr: "date(at)"},
+			{Name: "cost", Expr: "coalesce(price, 0)"},
+		},
+	}
+	d, err := NewDataset(source, src)
+	if err != nil {
+		t.Fatalf("NewDataset failed: %v", err)
+	}
+	data := d.Data()
+	if want := []string{"errors", "requests", "at", "price", "error_rate", "label", "day", "cost"}; !reflect.DeepEqual(data.Header, want) {
+		t.Fatalf("Header = %v, expected %v", data.Header, want)
+	}
+	if want := []string{"5", "200", "2024-03-15 13:45", "1.234,5", "2.5", "high", "2024-03-15", "1234.5"}; !reflect.DeepEqual(data.Records[0], want) {
+		t.Errorf("Records[0] = %v, expected %v", data.Records[0], want)
+	}
+	if got := data.Records[1][4]; got != "" {
+		t.Errorf("a division by zero should be empty, got %q", got)
+	}
+	if v, err := data.ParseNumber(4, data.Records[0][4]); err != nil || v != 2.5 {
+		t.Errorf("computed numbers should ignore the source locale, got %v, %v", v, err)
+	}
+	if v, err := data.ParseNumber(7, data.Records[1][7]); err != nil || v != 0 {
+		t.Errorf("coalesce should fall back to 0, got %v, %v", v, err)
+	}
+
+	src.records = append(src.records, []string{"9", "100", "2024-03-17 09:00", "7"})
+	if err := d.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if got := d.Data().Records[2][5]; got != "high" {
+		t.Errorf("computed columns should be recomputed on refresh, got %q", got)
+	}
+
+	src.header = []string{"errors", "total", "at", "price"}
+	if err := d.Refresh(); err == nil {
+		t.Errorf("expected an error when the columns change")
+	}
+	if len(d.Data().Records) != 3 || d.Err() == nil {
+		t.Errorf("a failed refresh should keep the last good data and report the error")
+	}
+}
+
+func TestDataset_PivotKeepsColumns(t *testing.T) {
+	src := &staticSource{
+		header:  []string{"region", "month", "sales"},
+		records: [][]string{{"EU", "jan", "1"}, {"US", "feb", "2"}},
+	}
+	source := &Source{
+		Reshape:         Reshape{Pivot: &Pivot{ID: []string{"region"}, Variable: "month", Value: "sales"}},
+		ComputedColumns: []ComputedColumn{{Name: "total", Expr: "coalesce(jan, 0) + coalesce(feb, 0)"}},
+	}
+	d, err := NewDataset(source, src)
+	if err != nil {
+		t.Fatalf("NewDataset failed: %v", err)
+	}
+	src.records = [][]string{{"EU", "mar", "3"}, {"EU", "feb", "4"}}
+	if err := d.Refresh(); err != nil {
+		t.Fatalf("Refresh failed when a new pivot column showed up: %v", err)
+	}
+	data := d.Data()
+	if want := []string{"region", "jan", "feb", "total"}; !reflect.DeepEqual(data.Header, want) {
+		t.Errorf("Header = %v, expected %v", data.Header, want)
+	}
+	if want := [][]string{{"EU", "", "4", "4"}}; !reflect.DeepEqual(data.Records, want) {
+		t.Errorf("Records = %v, expected %v", data.Records, want)
+	}
+}
+
+func TestDataset_InvalidComputedColumn(t *testing.T) {
+	src := &staticSource{header: []string{"a"}, records: [][]string{{"1"}}}
+	for _, c := range []ComputedColumn{
+		{Name: "b", Expr: "a +"},
+		{Name: "b", Expr: "missing * 2"},
+		{Name: "a", Expr: "1"},
+		{Expr: "a"},
+	} {
+		if _, err := NewDataset(&Source{ComputedColumns: []ComputedColumn{c}}, src); err == nil {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Join describes a source made of the rows of two other sources whose key
+// columns match.
+type Join struct {
+	Left  string `yaml:"left"`
+	Right string `yaml:"right"`
+	// On lists the key columns of the left source, and of the right one
+	// unless RightOn names them differently.
+	On      []string `yaml:"on"`
+	RightOn []string `yaml:"right_on,omitempty"`
+	// Type is "inner", the default, which keeps the rows that match, or
+	// "left", which keeps every row of the left source.
+	Type string `yaml:"type,omitempty"`
+	// LeftPrefix and RightPrefix are prepended to the columns found on both
+	// sides; they default to the source names followed by "_".
+	LeftPrefix  string `yaml:"left_prefix,omitempty"`
+	RightPrefix string `yaml:"right_prefix,omitempty"`
+}
+
+// validate checks the settings of the join.
+func (j *Join) validate() error {
+	if j.Left == "" || j.Right == "" {
+		return fmt.Errorf("join needs a left and a right source")
+	}
+	if len(j.On) == 0 {
+		return fmt.Errorf("join needs the key columns in 'on'")
+	}
+	if len(j.RightOn) > 0 && len(j.RightOn) != len(j.On) {
+		return fmt.Errorf("join has %d key columns on the left and %d on the right", len(j.On), len(j.RightOn))
+	}
+	if j.Type != "" && j.Type != "inner" && j.Type != "left" {
+		return fmt.Errorf("unsupported join type '%s'", j.Type)
+	}
+	return nil
+}
+
+// JoinDataSource joins the data of two sources as of their last refresh.
+// Rows whose keys are empty never match, like SQL nulls.
+type JoinDataSource struct {
+	Join        *Join
+	Left, Right *Dataset
+}
+
+func (j *JoinDataSource) Load() (*DataDataSource, error) {
+	left, right := j.Left.Data(), j.Right.Data()
+	rightOn := j.Join.RightOn
+	if len(rightOn) == 0 {
+		rightOn = j.Join.On
+	}
+	leftKeys, err := columnIndexes(left, j.Join.On)
+	if err != nil {
+		return nil, fmt.Errorf("left source '%s': %w", j.Join.Left, err)
+	}
+	rightKeys, err := columnIndexes(right, rightOn)
+	if err != nil {
+		return nil, fmt.Errorf("right source '%s': %w", j.Join.Right, err)
+	}
+
+	// The key columns of the right source are dropped, since they repeat
+	// those of the left one.
+	isKey := make(map[int]bool, len(rightKeys))
+	for _, col := range rightKeys {
+		isKey[col] = true
+	}
+	var rightCols []int
+	for i := range right.Header {
+		if !isKey[i] {
+			rightCols = append(rightCols, i)
+		}
+	}
+
+	leftPrefix, rightPrefix := j.Join.LeftPrefix, j.Join.RightPrefix
+	if leftPrefix == "" {
+		leftPrefix = j.Join.Left + "_"
+	}
+	if rightPrefix == "" {
+		rightPrefix = j.Join.Right + "_"
+	}
+	inRight := make(map[string]bool, len(rightCols))
+	for _, col := range rightCols {
+		inRight[right.Header[col]] = true
+	}
+	header := make([]string, 0, len(left.Header)+len(rightCols))
+	columns := make(map[string]Column, cap(header))
+	for _, h := range left.Header {
+		name := h
+		if inRight[h] {
+			name = leftPrefix + h
+		}
+		header = append(header, name)
+		columns[name] = left.columns[h]
+	}
+	for _, col := range rightCols {
+		h := right.Header[col]
+		name := h
+		if left.ColumnIndex(h) != -1 {
+			name = rightPrefix + h
+		}
+		header = append(header, name)
+		columns[name] = right.columns[h]
+	}
+	if err := checkHeader(header); err != nil {
+		return nil, err
+	}
+
+	matches := make(map[string][][]string)
+	for _, record := range right.Records {
+		if key, ok := joinKey(record, rightKeys); ok {
+			matches[key] = append(matches[key], record)
+		}
+	}
+	var records [][]string
+	for _, record := range left.Records {
+		var found [][]string
+		if key, ok := joinKey(record, leftKeys); ok {
+			found = matches[key]
+		}
+		if len(found) == 0 && j.Join.Type == "left" {
+			found = [][]string{nil}
+		}
+		for _, match := range found {
+			row := append(make([]string, 0, len(header)), record...)
+			for _, col := range rightCols {
+				cell := ""
+				if match != nil {
+					cell = match[col]
+				}
+				row = append(row, cell)
+			}
+			records = append(records, row)
+		}
+	}
+	data := &DataDataSource{Header: header, Records: records, columns: columns}
+	data.fetched, data.stale = freshness(j.Left, j.Right)
+	return data, nil
+}
+
+// joinKey returns the key cells of a record, or false if any is empty.
+func joinKey(record []string, cols []int) (string, bool) {
+	cells := make([]string, len(cols))
+	for i, col := range cols {
+		cells[i] = strings.TrimSpace(record[col])
+		if cells[i] == "" {
+			return "", false
+		}
+	}
+	return strings.Join(cells, "\x00"), true
+}
@@ -0,0 +1,68 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinDataSource(t *testing.T) {
+	metrics, err := NewDataset(&Source{Name: "metrics"}, &staticSource{
+		header:  []string{"host", "cpu", "name"},
+		records: [][]string{{"a", "10", "web"}, {"b", "20", "db"}, {"c", "30", "cache"}, {"", "40", "?"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := &staticSource{
+		header:  []string{"hostname", "team", "name"},
+		records: [][]string{{"a", "core", "web-1"}, {"b", "edge", "db-1"}, {"b", "ops", "db-2"}},
+	}
+	lookup, err := NewDataset(&Source{Name: "hosts"}, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join := &Join{Left: "metrics", Right: "hosts", On: []string{"host"}, RightOn: []string{"hostname"}}
+	source := &JoinDataSource{Join: join, Left: metrics, Right: lookup}
+	data, err := source.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := []string{"host", "cpu", "metrics_name", "team", "hosts_name"}; !reflect.DeepEqual(data.Header, want) {
+		t.Errorf("header = %v, expected %v", data.Header, want)
+	}
+	want := [][]string{
+		{"a", "10", "web", "core", "web-1"},
+		{"b", "20", "db", "edge", "db-1"},
+		{"b", "20", "db", "ops", "db-2"},
+	}
+	if !reflect.DeepEqual(data.Records, want) {
+		t.Errorf("inner join = %v, expected %v", data.Records, want)
+	}
+
+	join.Type = "left"
+	join.LeftPrefix, join.RightPrefix = "m.", "h."
+	if data, err = source.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if data.Header[2] != "m.name" || data.Header[4] != "h.name" {
+		t.Errorf("expected the configured prefixes, got %v", data.Header)
+	}
+	want = append(want, []string{"c", "30", "cache", "", ""}, []string{"", "40", "?", "", ""})
+	if !reflect.DeepEqual(data.Records, want) {
+		t.Errorf("left join = %v, expected %v", data.Records, want)
+	}
+
+	// Joins follow the refreshes of their inputs.
+	hosts.records = [][]string{{"c", "infra", "cache-1"}}
+	if err := lookup.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	join.Type = "inner"
+	if data, err = source.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := [][]string{{"c", "30", "cache", "infra", "cache-1"}}; !reflect.DeepEqual(data.Records, want) {
+		t.Errorf("join after refresh = %v, expected %v", data.Records, want)
+	}
+}
@@ -1,12 +1,17 @@
 package loader
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"math"
 	"os"
+	"strings"
+	"time"
+
+	"datacmd/expr"
+	"datacmd/series"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
@@ -14,34 +19,405 @@ import (
 )
 
 type Config struct {
-	Title   string         `yaml:"title"`
-	Refresh int            `yaml:"refresh"`
-	Source  Source         `yaml:"source"`
+	Title   string `yaml:"title"`
+	Refresh int    `yaml:"refresh"`
+	Source  Source `yaml:"source"`
+	// Sources are additional named sources, which widgets can draw and
+	// queries can read and join.
+	Sources []Source       `yaml:"sources,omitempty"`
 	Widgets []WidgetConfig `yaml:"widgets"`
+	// CacheDir holds the cached copies of remote sources. It defaults to a
+	// datacmd directory in the user cache directory.
+	CacheDir string `yaml:"cache_dir,omitempty"`
+}
+
+// Options change how LoadConfigAndDataWith loads the sources.
+type Options struct {
+	// Offline serves remote sources from their cached copies only.
+	Offline bool
+	// Context bounds the requests of remote sources, at load time and on
+	// every refresh; they are cancelled once it is done.
+	Context context.Context
 }
 
 type WidgetConfig struct {
-	Type        string `yaml:"type"`
-	Title       string `yaml:"title"`
-	ValueCol    string `yaml:"value_col,omitempty"`
-	LabelCol    string `yaml:"label_col,omitempty"`
-	XCol        string `yaml:"x_col,omitempty"`
-	YCol        string `yaml:"y_col,omitempty"`
-	ZCol        string `yaml:"z_col,omitempty"`
-	CatCol      string `yaml:"cat_col,omitempty"`
-	Aggregation string `yaml:"aggregation,omitempty"`
-	MaxValue    int    `yaml:"max_value,omitempty"`
+	Type        string  `yaml:"type"`
+	Title       string  `yaml:"title"`
+	ValueCol    string  `yaml:"value_col,omitempty"`
+	LabelCol    string  `yaml:"label_col,omitempty"`
+	XCol        string  `yaml:"x_col,omitempty"`
+	YCol        string  `yaml:"y_col,omitempty"`
+	ZCol        string  `yaml:"z_col,omitempty"`
+	CatCol      string  `yaml:"cat_col,omitempty"`
+	Aggregation string  `yaml:"aggregation,omitempty"`
+	MaxValue    int     `yaml:"max_value,omitempty"`
+	Bins        int     `yaml:"bins,omitempty"`
+	Threshold   float64 `yaml:"threshold,omitempty"`
+	AlertColor  int     `yaml:"alert_color,omitempty"`
+	// Bucket resamples time series into fixed intervals such as "1m" or
+	// "1d", combining the values of each interval with Aggregation.
+	Bucket string `yaml:"bucket,omitempty"`
+	// GroupBy aggregates the rows sharing the same value of this column
+	// into a single bar, slice or stage, using Aggregation.
+	GroupBy string `yaml:"group_by,omitempty"`
+	// TopN keeps the largest groups and merges the others into "Other".
+	TopN int `yaml:"top_n,omitempty"`
+	// Filter is an expression selecting the rows the widget draws, e.g.
+	// `level == "error" and region in ("EU", "UK")`.
+	Filter string `yaml:"filter,omitempty"`
+	// Query is a SQL query over the sources whose result the widget draws,
+	// e.g. `SELECT region, sum(amount) FROM src GROUP BY region`.
+	Query string `yaml:"query,omitempty"`
+	// Source is the name of the source the widget draws, by default the
+	// main one.
+	Source string `yaml:"source,omitempty"`
+	// SortBy orders the rows, or the groups of GroupBy, by a column. Groups
+	// are ordered by name for the group or label column and by value for
+	// any other. It defaults to the value column when Order is set.
+	SortBy string `yaml:"sort_by,omitempty"`
+	// Order is "asc", the default, or "desc".
+	Order string `yaml:"order,omitempty"`
+	// Limit keeps the first rows or groups, after filtering, grouping and
+	// sorting.
+	Limit int `yaml:"limit,omitempty"`
+	// Transform is a pipeline applied in order to the series drawn by line,
+	// sparkline and bar widgets, e.g. [moving_avg: 7, pct_change].
+	Transform []Transform `yaml:"transform,omitempty"`
+	// YCols draws several columns as the series of a line widget, instead
+	// of YCol.
+	YCols []string `yaml:"y_cols,omitempty"`
+	// SeriesBy splits the rows of a line widget into a series for each
+	// value of this column; bar widgets draw the series side by side.
+	SeriesBy string `yaml:"series_by,omitempty"`
+	// StackBy splits the bars of a bar widget into stacked segments, one
+	// for each value of this column.
+	StackBy string `yaml:"stack_by,omitempty"`
+	// Orientation draws the bars of a bar widget "vertical", the default,
+	// or "horizontal", and the boxes of a boxplot widget "horizontal", its
+	// default, or "vertical".
+	Orientation string `yaml:"orientation,omitempty"`
+	// SecondaryY lists the series of a line widget, by column or SeriesBy
+	// value, drawn against a second Y axis on the right.
+	SecondaryY []string `yaml:"secondary_y,omitempty"`
+	// Legend places the legend of pie, line and bar widgets: "auto", the
+	// default, "right", "below" or "none".
+	Legend string `yaml:"legend,omitempty"`
+	// InnerRadius is the hole of a pie drawn as a donut, as a fraction of
+	// its radius. It defaults to 0.6, and 0 draws a full pie.
+	InnerRadius *float64 `yaml:"inner_radius,omitempty"`
+	// SliceLabels writes the percentage of the larger slices on a pie.
+	SliceLabels bool `yaml:"slice_labels,omitempty"`
+	// Colors is the color scale of heatmap and calendar widgets.
+	Colors ColorScale `yaml:"colors,omitempty"`
+	// TimeCol, OpenCol, HighCol, LowCol and CloseCol are the columns of
+	// the candles of a candlestick widget, and VolumeCol the optional
+	// column of their volume.
+	TimeCol   string `yaml:"time_col,omitempty"`
+	OpenCol   string `yaml:"open_col,omitempty"`
+	HighCol   string `yaml:"high_col,omitempty"`
+	LowCol    string `yaml:"low_col,omitempty"`
+	CloseCol  string `yaml:"close_col,omitempty"`
+	VolumeCol string `yaml:"volume_col,omitempty"`
+	// UpColor and DownColor are the terminal colors (0-255) of the candles
+	// of a candlestick widget closing up and down.
+	UpColor   int `yaml:"up_color,omitempty"`
+	DownColor int `yaml:"down_color,omitempty"`
+	// Unit is written after the value of a stat widget, e.g. "ms".
+	Unit string `yaml:"unit,omitempty"`
+	// Format writes the value of a stat widget: a printf format such as
+	// "%.1f" or "$%.2f", or "compact" for values like 1.2k.
+	Format string `yaml:"format,omitempty"`
+	// Compare is what a stat widget compares its value with: "previous",
+	// the default, for the value of the previous refresh, or a duration
+	// such as "1h" or "1d" to compare the last window of x_col with the
+	// one before it.
+	Compare string `yaml:"compare,omitempty"`
+	// Good is the direction of a good change of a stat widget: "up", the
+	// default, or "down".
+	Good string `yaml:"good,omitempty"`
+	// PathCols are the levels of the hierarchy of a treemap widget, from
+	// the outermost, e.g. [team, service].
+	PathCols []string `yaml:"path_cols,omitempty"`
+	// Template formats the rows of a log widget, with the names of columns
+	// between braces, e.g. "{time} [{level}] {message}".
+	Template string `yaml:"template,omitempty"`
+	// LevelCol colors the lines of a log widget by level, e.g. red for
+	// "error" and orange for "warn".
+	LevelCol string `yaml:"level_col,omitempty"`
+	// MaxLines is the number of lines a log widget keeps, 1000 by default.
+	MaxLines int `yaml:"max_lines,omitempty"`
+
+	// filter is the compiled Filter.
+	filter *expr.Expr
+}
+
+// FilterExpr returns the compiled filter, or nil when the widget draws
+// every row.
+func (w *WidgetConfig) FilterExpr() *expr.Expr {
+	return w.filter
+}
+
+// compileFilter compiles the widget's filter and checks it against the
+// columns of the data.
+func (w *WidgetConfig) compileFilter(data *DataDataSource) error {
+	if strings.TrimSpace(w.Filter) == "" {
+		return nil
+	}
+	e, err := expr.Compile(w.Filter)
+	if err != nil {
+		return fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	if _, err := e.Bind(data); err != nil {
+		return fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	w.filter = e
+	return nil
+}
+
+// Transform is a step of a widget's transform pipeline, written either as a
+// name, such as "cumsum", or as a name with a parameter, such as
+// "moving_avg: 7".
+type Transform struct {
+	Name  string
+	Param float64
+}
+
+func (t *Transform) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*t = Transform{Name: name}
+		return nil
+	}
+	var step map[string]float64
+	if err := unmarshal(&step); err != nil || len(step) != 1 {
+		return fmt.Errorf("a transform is a name such as 'cumsum' or a name with a parameter such as 'moving_avg: 7'")
+	}
+	for name, param := range step {
+		*t = Transform{Name: name, Param: param}
+	}
+	return nil
+}
+
+func (t Transform) MarshalYAML() (interface{}, error) {
+	if t.Param == 0 {
+		return t.Name, nil
+	}
+	return map[string]float64{t.Name: t.Param}, nil
+}
+
+// ColorScale is a color scale written either as the name of a scale, such
+// as "viridis", or as a list of colors such as ["#000080", "#ffff00"].
+type ColorScale []string
+
+func (c *ColorScale) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*c = ColorScale{name}
+		return nil
+	}
+	var colors []string
+	if err := unmarshal(&colors); err != nil {
+		return fmt.Errorf("a color scale is a name such as 'viridis' or a list of colors such as ['#000080', '#ffff00']")
+	}
+	*c = colors
+	return nil
+}
+
+func (c ColorScale) MarshalYAML() (interface{}, error) {
+	if len(c) == 1 {
+		return c[0], nil
+	}
+	return []string(c), nil
+}
+
+// validateSort checks the order of the widget and that its sort column is
+// one of the columns of the data.
+func (w *WidgetConfig) validateSort(data *DataDataSource) error {
+	if w.Order != "" && w.Order != "asc" && w.Order != "desc" {
+		return fmt.Errorf("widget '%s': invalid order '%s', expected asc or desc", w.Title, w.Order)
+	}
+	if w.Limit < 0 {
+		return fmt.Errorf("widget '%s': limit can't be negative", w.Title)
+	}
+	if w.SortBy != "" && data.ColumnIndex(w.SortBy) == -1 {
+		return fmt.Errorf("column '%s' not found for widget '%s'", w.SortBy, w.Title)
+	}
+	return nil
+}
+
+// validateTransform checks the steps of the widget's transform pipeline.
+func (w *WidgetConfig) validateTransform() error {
+	for _, t := range w.Transform {
+		if err := series.ValidateTransform(t.Name, t.Param); err != nil {
+			return fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+	}
+	return nil
 }
 
 type Source struct {
+	// Name identifies the source in queries. The main source defaults to
+	// "src".
+	Name string `yaml:"name,omitempty"`
 	Type string `yaml:"type"`
 	Path string `yaml:"path"`
 	URL  string `yaml:"url"`
+	// From derives the source from the data of another source, defined
+	// before it, instead of loading it; it is usually reshaped.
+	From string `yaml:"from,omitempty"`
+	// Join derives the source from the rows of two sources, defined before
+	// it, whose key columns match.
+	Join *Join `yaml:"join,omitempty"`
+	// CacheTTL is how long the cached copy of a remote source is served
+	// without fetching the source again, e.g. "30s" or "1h". Remote sources
+	// are always cached, so that their last good copy can be shown when
+	// they can't be fetched.
+	CacheTTL string `yaml:"cache_ttl,omitempty"`
+	// Timeout bounds each request of a remote source, e.g. "5s". It
+	// defaults to 10 seconds.
+	Timeout string `yaml:"timeout,omitempty"`
+	// Retry sets how failed requests of a remote source are retried.
+	Retry Retry `yaml:"retry,omitempty"`
+	// Pagination follows the pages of a remote source.
+	Pagination *Pagination `yaml:"pagination,omitempty"`
+	// Reshape pivots or melts the rows, before the computed columns are
+	// added.
+	Reshape `yaml:",inline"`
+	// Locale is the default locale used to parse numbers, e.g. "en" or "de".
+	// When empty the separators are inferred from each value.
+	Locale string `yaml:"locale,omitempty"`
+	// Timezone is the IANA zone used for timestamps without an offset and
+	// for time axis labels. It defaults to UTC.
+	Timezone string `yaml:"timezone,omitempty"`
+	// NullValues are the cells meaning "no value" in every column.
+	NullValues []string `yaml:"null_values,omitempty"`
+	// Columns holds per-column parsing settings, keyed by column name.
+	Columns map[string]Column `yaml:"columns,omitempty"`
+	// ComputedColumns are appended to every row, in order, each time the
+	// data is loaded.
+	ComputedColumns []ComputedColumn `yaml:"computed_columns,omitempty"`
+}
+
+// Column describes how the cells of a single column are parsed.
+type Column struct {
+	// Locale overrides the source locale for this column.
+	Locale string `yaml:"locale,omitempty"`
+	// TimeLayout is a Go reference layout, "epoch" or "epoch_ms". Setting it
+	// marks the column as a time column.
+	TimeLayout string `yaml:"time_layout,omitempty"`
+	// Timezone overrides the source timezone for this column.
+	Timezone string `yaml:"timezone,omitempty"`
+	// NullValues are the cells meaning "no value", such as "NA" or "-",
+	// which are loaded as empty cells. They override the source's list.
+	NullValues []string `yaml:"null_values,omitempty"`
+	// OnInvalid is what happens to the cells that are empty or not numbers:
+	// "skip" leaves them out of the widgets, the default; "zero" replaces
+	// them with 0; "interpolate" with a value interpolated between the
+	// closest valid rows; and "error" fails the load.
+	OnInvalid string `yaml:"on_invalid,omitempty"`
+
+	// location is the resolved Timezone.
+	location *time.Location
+}
+
+// column returns the parsing settings of the named column, falling back to
+// the source defaults.
+func (s *Source) column(name string) (Column, error) {
+	c := s.Columns[name]
+	if c.Locale == "" {
+		c.Locale = s.Locale
+	}
+	if c.Timezone == "" {
+		c.Timezone = s.Timezone
+	}
+	if c.NullValues == nil {
+		c.NullValues = s.NullValues
+	}
+	switch c.OnInvalid {
+	case "", "skip", "zero", "interpolate", "error":
+	default:
+		return c, fmt.Errorf("invalid on_invalid '%s' for column '%s', expected skip, zero, interpolate or error", c.OnInvalid, name)
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return c, fmt.Errorf("invalid timezone for column '%s': %w", name, err)
+	}
+	c.location = loc
+	return c, nil
 }
 
 type DataDataSource struct {
 	Header  []string
 	Records [][]string
+
+	// columns holds the parsing settings of each column, keyed by name.
+	columns map[string]Column
+	// imputed counts the cells of each column replaced by their OnInvalid
+	// policy when the data was loaded.
+	imputed map[string]int
+	// fetched is when the data was fetched from its origin, when it
+	// differs from when it was loaded, as for cached copies.
+	fetched time.Time
+	// stale marks data served in place of a fresh copy that couldn't be
+	// fetched.
+	stale bool
+}
+
+// ColumnIndex returns the index of the named column, or -1 if it is missing.
+func (d *DataDataSource) ColumnIndex(name string) int {
+	for i, header := range d.Header {
+		if header == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseNumber parses a cell of the column at index col using the locale
+// configured for that column.
+func (d *DataDataSource) ParseNumber(col int, s string) (float64, error) {
+	var locale string
+	if col >= 0 && col < len(d.Header) {
+		locale = d.columns[d.Header[col]].Locale
+	}
+	return ParseNumber(s, locale)
+}
+
+// WithRecords returns a copy of the data holding the given records, with
+// the same columns and parsing settings.
+func (d *DataDataSource) WithRecords(records [][]string) *DataDataSource {
+	return &DataDataSource{Header: d.Header, Records: records, columns: d.columns, imputed: d.imputed, fetched: d.fetched, stale: d.stale}
+}
+
+// ParseInt parses a cell like ParseNumber and rounds it to the nearest integer.
+func (d *DataDataSource) ParseInt(col int, s string) (int, error) {
+	v, err := d.ParseNumber(col, s)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(v)), nil
+}
+
+// applySource resolves the per-column parsing settings of the source. Data
+// derived from other sources keeps the settings of its columns unless the
+// source overrides them.
+func (d *DataDataSource) applySource(s *Source) error {
+	columns := make(map[string]Column, len(d.Header))
+	for _, header := range d.Header {
+		if c, ok := d.columns[header]; ok {
+			if _, configured := s.Columns[header]; !configured {
+				columns[header] = c
+				continue
+			}
+		}
+		c, err := s.column(header)
+		if err != nil {
+			return err
+		}
+		columns[header] = c
+	}
+	d.columns = columns
+	return nil
 }
 
 type DataSource interface {
@@ -101,22 +477,43 @@ func (j *JSONDataSource) Load() (*DataDataSource, error) {
 
 type APIDataSource struct {
 	URL string
+	// Context, Timeout and Retry bound the requests, see fetch.
+	Context context.Context
+	Timeout time.Duration
+	Retry   RetryPolicy
+	// Pagination, when set, concatenates the records of the pages.
+	Pagination *Pagination
 }
 
-func (a *APIDataSource) Load() (*DataDataSource, error) {
-	resp, err := http.Get(a.URL)
+// NewAPIDataSource returns the loader of a remote source, whose requests
+// are bound to ctx.
+func NewAPIDataSource(ctx context.Context, s *Source) (*APIDataSource, error) {
+	timeout := DefaultTimeout
+	if s.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(s.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", s.Timeout)
+		}
+	}
+	retry, err := s.Retry.policy()
 	if err != nil {
-		return nil, fmt.Errorf("Unable to make API request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API response failed, status code: %d", resp.StatusCode)
+	if s.Pagination != nil {
+		if err := s.Pagination.validate(); err != nil {
+			return nil, err
+		}
 	}
+	return &APIDataSource{URL: s.URL, Context: ctx, Timeout: timeout, Retry: retry, Pagination: s.Pagination}, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (a *APIDataSource) Load() (*DataDataSource, error) {
+	if a.Pagination != nil {
+		return a.loadPages()
+	}
+	body, _, err := fetch(a.Context, a.URL, a.Timeout, a.Retry)
 	if err != nil {
-		return nil, fmt.Errorf("Unable to read response body: %w", err)
+		return nil, err
 	}
 
 	var data DataDataSource
@@ -151,7 +548,29 @@ func (s *SystemMetricsDataSource) Load() (*DataDataSource, error) {
 	return &data, nil
 }
 
-func LoadConfigAndData(configPath string) (*Config, *DataDataSource, error) {
+// newDataSource returns the loader of a source. The requests of remote
+// sources are bound to ctx.
+func newDataSource(ctx context.Context, s *Source) (DataSource, error) {
+	switch s.Type {
+	case "csv":
+		return &CSVDataSource{Path: s.Path}, nil
+	case "json":
+		return &JSONDataSource{Path: s.Path}, nil
+	case "api":
+		return NewAPIDataSource(ctx, s)
+	case "system":
+		return &SystemMetricsDataSource{}, nil
+	}
+	return nil, fmt.Errorf("Unsupported data source type: %s", s.Type)
+}
+
+// LoadConfigAndData loads a configuration and the data of its sources.
+func LoadConfigAndData(configPath string) (*Config, *Catalog, error) {
+	return LoadConfigAndDataWith(configPath, Options{})
+}
+
+// LoadConfigAndDataWith is LoadConfigAndData with options.
+func LoadConfigAndDataWith(configPath string, opts Options) (*Config, *Catalog, error) {
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Unable to read config file: %w", err)
@@ -162,24 +581,44 @@ func LoadConfigAndData(configPath string) (*Config, *DataDataSource, error) {
 		return nil, nil, fmt.Errorf("Unable to parse YAML config file: %w", err)
 	}
 
-	var dataSource DataSource
-	switch config.Source.Type {
-	case "csv":
-		dataSource = &CSVDataSource{Path: config.Source.Path}
-	case "json":
-		dataSource = &JSONDataSource{Path: config.Source.Path}
-	case "api":
-		dataSource = &APIDataSource{URL: config.Source.URL}
-	case "system":
-		dataSource = &SystemMetricsDataSource{}
-	default:
-		return nil, nil, fmt.Errorf("Unsupported data source type: %s", config.Source.Type)
+	catalog := newCatalog(config.CacheDir, opts)
+	if config.Source.Type != "" || len(config.Sources) == 0 {
+		if err := catalog.add(&config.Source); err != nil {
+			return nil, nil, err
+		}
+	}
+	for i := range config.Sources {
+		if config.Sources[i].Name == "" {
+			return nil, nil, fmt.Errorf("source %d has no name", i+1)
+		}
+		if err := catalog.add(&config.Sources[i]); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	data, err := dataSource.Load()
-	if err != nil {
-		return nil, nil, err
+	for i := range config.Widgets {
+		w := &config.Widgets[i]
+		if strings.TrimSpace(w.Query) != "" {
+			if w.Source != "" {
+				return nil, nil, fmt.Errorf("widget '%s' can't have both a query and a source", w.Title)
+			}
+			if err := catalog.addQuery(w); err != nil {
+				return nil, nil, err
+			}
+		}
+		if _, ok := catalog.Source(w.Source); w.Source != "" && !ok {
+			return nil, nil, fmt.Errorf("widget '%s': unknown source '%s'", w.Title, w.Source)
+		}
+		if err := w.compileFilter(catalog.Widget(w).Data()); err != nil {
+			return nil, nil, err
+		}
+		if err := w.validateSort(catalog.Widget(w).Data()); err != nil {
+			return nil, nil, err
+		}
+		if err := w.validateTransform(); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return &config, data, nil
+	return &config, catalog, nil
 }
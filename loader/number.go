@@ -0,0 +1,258 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrEmptyValue is returned when a cell holds no value at all.
+var ErrEmptyValue = errors.New("empty value")
+
+// currencySymbols are stripped from the start or the end of a number.
+const currencySymbols = "$€£¥₹₽₩₺₪฿¢"
+
+// currencyCodes are ISO 4217 codes accepted before or after a number.
+var currencyCodes = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "JPY": true, "CHF": true,
+	"CNY": true, "INR": true, "CAD": true, "AUD": true, "SEK": true,
+	"NOK": true, "DKK": true, "PLN": true, "BRL": true, "MXN": true,
+}
+
+// unitMultipliers maps SI and IEC suffixes to their multiplier.
+var unitMultipliers = map[string]float64{
+	"":  1,
+	"B": 1,
+	"k": 1e3, "K": 1e3, "kB": 1e3, "KB": 1e3,
+	"M": 1e6, "MB": 1e6,
+	"G": 1e9, "GB": 1e9,
+	"T": 1e12, "TB": 1e12,
+	"P": 1e15, "PB": 1e15,
+	"Ki": 1 << 10, "KiB": 1 << 10,
+	"Mi": 1 << 20, "MiB": 1 << 20,
+	"Gi": 1 << 30, "GiB": 1 << 30,
+	"Ti": 1 << 40, "TiB": 1 << 40,
+	"Pi": 1 << 50, "PiB": 1 << 50,
+}
+
+// decimalCommaLanguages lists the languages writing numbers as 1.234,50.
+var decimalCommaLanguages = map[string]bool{
+	"de": true, "it": true, "fr": true, "es": true, "pt": true, "nl": true,
+	"ru": true, "pl": true, "sv": true, "da": true, "nb": true, "no": true,
+	"fi": true, "cs": true, "tr": true, "id": true, "ro": true, "hu": true,
+}
+
+// separators returns the decimal separator for a locale such as "en",
+// "de_DE" or "it-IT". An empty locale returns 0, meaning that the separator
+// is inferred from the value.
+func separators(locale string) rune {
+	if locale == "" || locale == "auto" {
+		return 0
+	}
+	lang, region := locale, ""
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		lang, region = locale[:i], locale[i+1:]
+	}
+	lang = strings.ToLower(lang)
+	if strings.EqualFold(region, "CH") {
+		// Switzerland uses 1'234.50 regardless of the language.
+		return '.'
+	}
+	if decimalCommaLanguages[lang] {
+		return ','
+	}
+	return '.'
+}
+
+// isGroupRune reports whether r is only ever used to group thousands.
+func isGroupRune(r rune) bool {
+	return r == '\'' || r == ' ' || r == ' ' || r == ' ' || r == '’'
+}
+
+// ParseNumber parses a human formatted number as found in real exports, such
+// as "1,234.50", "1.234,50", "$5,000", "12%", "3.2k", "512MiB" or "1.5e3".
+// The locale selects the decimal separator; when empty it is inferred from
+// the value. Percentages keep their written value, so "12%" parses as 12.
+func ParseNumber(s, locale string) (float64, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrEmptyValue
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		// Accounting notation for negative amounts.
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	s, sign := trimSign(s)
+	negative = negative != sign
+	s = trimCurrency(s)
+	s, sign = trimSign(s)
+	negative = negative != sign
+	s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+
+	body, unit := splitUnit(s)
+	mult, ok := unitMultipliers[strings.TrimSpace(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid number %q: unknown unit %q", orig, unit)
+	}
+
+	normalized, err := normalizeSeparators(body, separators(locale))
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %w", orig, err)
+	}
+	v, err := strconv.ParseFloat(normalized, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid number %q", orig)
+	}
+	v *= mult
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// IsNumber reports whether s can be parsed by ParseNumber.
+func IsNumber(s, locale string) bool {
+	_, err := ParseNumber(s, locale)
+	return err == nil
+}
+
+// trimSign removes a leading sign and reports whether it was negative.
+func trimSign(s string) (string, bool) {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return strings.TrimSpace(s[1:]), true
+	case strings.HasPrefix(s, "−"):
+		return strings.TrimSpace(s[len("−"):]), true
+	case strings.HasPrefix(s, "+"):
+		return strings.TrimSpace(s[1:]), false
+	}
+	return s, false
+}
+
+// trimCurrency removes a currency symbol or ISO code at either end of s.
+func trimCurrency(s string) string {
+	s = strings.TrimSpace(strings.TrimLeft(s, currencySymbols))
+	s = strings.TrimSpace(strings.TrimRight(s, currencySymbols))
+	if len(s) > 3 && currencyCodes[s[:3]] {
+		s = strings.TrimSpace(s[3:])
+	}
+	if len(s) > 3 && currencyCodes[s[len(s)-3:]] {
+		s = strings.TrimSpace(s[:len(s)-3])
+	}
+	return s
+}
+
+// splitUnit splits s into its numeric body and a trailing unit suffix.
+// An exponent is kept in the body when it is followed by digits.
+func splitUnit(s string) (string, string) {
+	runes := []rune(s)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsDigit(r), r == '.', r == ',', isGroupRune(r):
+			i++
+		case (r == 'e' || r == 'E') && i > 0 && exponentAt(runes, i+1):
+			i++
+			if runes[i] == '+' || runes[i] == '-' {
+				i++
+			}
+		default:
+			return strings.TrimSpace(string(runes[:i])), string(runes[i:])
+		}
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// exponentAt reports whether runes[i:] starts with an optionally signed digit.
+func exponentAt(runes []rune, i int) bool {
+	if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+		i++
+	}
+	return i < len(runes) && unicode.IsDigit(runes[i])
+}
+
+// normalizeSeparators rewrites body so that strconv can parse it: grouping
+// separators are removed and the decimal separator becomes a dot. A zero
+// decimal separator infers it from the value. Grouped digits must come in
+// groups of three after the first one, so that "10.0.0.1" or "1.2.3" aren't
+// read as numbers.
+func normalizeSeparators(body string, decimal rune) (string, error) {
+	if body == "" {
+		return "", errors.New("no digits")
+	}
+	mantissa, exponent := body, ""
+	if i := strings.IndexAny(body, "eE"); i >= 0 {
+		mantissa, exponent = body[:i], body[i:]
+	}
+
+	if decimal == 0 {
+		decimal = inferDecimal(mantissa)
+	}
+
+	var b strings.Builder
+	seenDecimal, grouped := false, false
+	// digits counts the digits of the current group of the integer part.
+	digits := 0
+	for _, r := range mantissa {
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+			digits++
+		case r == decimal:
+			if seenDecimal {
+				return "", errors.New("more than one decimal separator")
+			}
+			if grouped && digits != 3 {
+				return "", errors.New("digit groups must have three digits")
+			}
+			seenDecimal = true
+			b.WriteRune('.')
+		case r == '.' || r == ',' || isGroupRune(r):
+			if seenDecimal {
+				return "", errors.New("grouping separator after the decimal separator")
+			}
+			if digits == 0 || (grouped && digits != 3) {
+				return "", errors.New("digit groups must have three digits")
+			}
+			grouped, digits = true, 0
+		}
+	}
+	if grouped && !seenDecimal && digits != 3 {
+		return "", errors.New("digit groups must have three digits")
+	}
+	return b.String() + exponent, nil
+}
+
+// inferDecimal guesses the decimal separator of a mantissa written in an
+// unknown locale. When both '.' and ',' appear, the last one is the decimal
+// separator. A lone comma followed by exactly three digits is read as a
+// thousands separator, as in "1,234".
+func inferDecimal(mantissa string) rune {
+	lastDot := strings.LastIndex(mantissa, ".")
+	lastComma := strings.LastIndex(mantissa, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastDot > lastComma {
+			return '.'
+		}
+		return ','
+	case lastComma >= 0:
+		if strings.Count(mantissa, ",") > 1 || len(mantissa)-lastComma-1 == 3 {
+			return '.'
+		}
+		return ','
+	case lastDot >= 0 && strings.Count(mantissa, ".") > 1:
+		// "1.234.567" only makes sense as grouping.
+		return ','
+	}
+	return '.'
+}
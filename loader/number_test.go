@@ -0,0 +1,57 @@
+package loader
+
+import (
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in     string
+		locale string
+		want   float64
+	}{
+		{"42", "", 42},
+		{"1,234.50", "", 1234.5},
+		{"1.234,50", "", 1234.5},
+		{"1.234,50", "de", 1234.5},
+		{"1.234", "de", 1234},
+		{"1,5", "", 1.5},
+		{"1'234.5", "de_CH", 1234.5},
+		{"$5,000", "", 5000},
+		{"-€12.30", "", -12.3},
+		{"(1,200)", "", -1200},
+		{"250 EUR", "", 250},
+		{"12%", "", 12},
+		{"3.2k", "", 3200},
+		{"512MiB", "", 512 * 1024 * 1024},
+		{"2 GB", "", 2e9},
+		{"1.5e3", "", 1500},
+		{"-2E-2", "", -0.02},
+		{"1,234,567.5", "", 1234567.5},
+		{"1 234 567,5", "fr", 1234567.5},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.in, tt.locale)
+		if err != nil {
+			t.Errorf("ParseNumber(%q, %q) failed: %v", tt.in, tt.locale, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseNumber(%q, %q) = %v, expected %v", tt.in, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumber_Invalid(t *testing.T) {
+	for _, in := range []string{"", "abc", "12 apples", "1.2.3,4,5", "-", "NaN",
+		"10.0.0.1", "1.2.3", "1,2,3", "1,23,456", "1.2345,6", ",123"} {
+		if _, err := ParseNumber(in, ""); err == nil {
+			t.Errorf("ParseNumber(%q) expected an error", in)
+		}
+	}
+	for _, in := range []string{"1,5", "1,2345.6"} {
+		if _, err := ParseNumber(in, "en"); err == nil {
+			t.Errorf("ParseNumber(%q, \"en\") expected an error", in)
+		}
+	}
+}
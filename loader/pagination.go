@@ -0,0 +1,241 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxPages caps the pages read from a paginated source without
+// max_pages.
+const DefaultMaxPages = 10
+
+// Pagination describes how the pages of a remote source are followed. Every
+// page is a dataset with the same header, and their records are
+// concatenated.
+type Pagination struct {
+	// Type is how the next page is found:
+	//   - "next": the URL in NextField of the response body;
+	//   - "cursor": the token in CursorField, sent back in CursorParam;
+	//   - "page": increasing PageParam, starting from StartPage, until a
+	//     page has fewer than PerPage rows or none;
+	//   - "link": the rel="next" URL of the Link header (RFC 5988).
+	Type string `yaml:"type"`
+	// NextField and CursorField are dotted paths in the response body, such
+	// as "links.next". They default to "next" and "next_cursor".
+	NextField   string `yaml:"next_field,omitempty"`
+	CursorField string `yaml:"cursor_field,omitempty"`
+	// CursorParam is the query parameter carrying the cursor, "cursor" by
+	// default.
+	CursorParam string `yaml:"cursor_param,omitempty"`
+	// PageParam and PerPageParam are the query parameters of the page
+	// number and size, "page" and "per_page" by default. The size is only
+	// sent when PerPage is set.
+	PageParam    string `yaml:"page_param,omitempty"`
+	PerPageParam string `yaml:"per_page_param,omitempty"`
+	PerPage      int    `yaml:"per_page,omitempty"`
+	StartPage    *int   `yaml:"start_page,omitempty"`
+	// MaxPages caps the pages read, 10 by default, and MaxRows the records
+	// kept, without limit by default.
+	MaxPages int `yaml:"max_pages,omitempty"`
+	MaxRows  int `yaml:"max_rows,omitempty"`
+}
+
+// validate checks the settings of the pagination.
+func (p *Pagination) validate() error {
+	switch p.Type {
+	case "next", "cursor", "page", "link":
+	case "":
+		return fmt.Errorf("pagination needs a type: next, cursor, page or link")
+	default:
+		return fmt.Errorf("unsupported pagination type '%s'", p.Type)
+	}
+	if p.MaxPages < 0 || p.MaxRows < 0 || p.PerPage < 0 {
+		return fmt.Errorf("pagination max_pages, max_rows and per_page can't be negative")
+	}
+	return nil
+}
+
+// loadPages follows the pages of the source from its URL.
+func (a *APIDataSource) loadPages() (*DataDataSource, error) {
+	p := a.Pagination
+	maxPages := p.MaxPages
+	if maxPages == 0 {
+		maxPages = DefaultMaxPages
+	}
+	page := 1
+	if p.StartPage != nil {
+		page = *p.StartPage
+	}
+	pageURL := a.URL
+	if p.Type == "page" {
+		var err error
+		if pageURL, err = p.pageURL(a.URL, page); err != nil {
+			return nil, err
+		}
+	}
+
+	var data *DataDataSource
+	for n := 1; ; n++ {
+		body, header, err := fetch(a.Context, pageURL, a.Timeout, a.Retry)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		var current DataDataSource
+		if err := json.Unmarshal(body, &current); err != nil {
+			return nil, fmt.Errorf("Unable to decode API JSON response of page %d: %w", n, err)
+		}
+		if data == nil {
+			data = &current
+		} else if len(current.Records) > 0 {
+			if !slices.Equal(data.Header, current.Header) {
+				return nil, fmt.Errorf("page %d has columns %v instead of %v", n, current.Header, data.Header)
+			}
+			data.Records = append(data.Records, current.Records...)
+		}
+		if p.MaxRows > 0 && len(data.Records) >= p.MaxRows {
+			data.Records = data.Records[:p.MaxRows:p.MaxRows]
+			return data, nil
+		}
+		if n >= maxPages || len(current.Records) == 0 {
+			return data, nil
+		}
+
+		next, err := p.next(pageURL, body, header, page+n, len(current.Records))
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		if next == "" {
+			return data, nil
+		}
+		pageURL = next
+	}
+}
+
+// next returns the URL of the page after the one read from current, or ""
+// after the last page. page is the number of the next page and rows the
+// number of records of the current one.
+func (p *Pagination) next(current string, body []byte, header http.Header, page, rows int) (string, error) {
+	switch p.Type {
+	case "page":
+		if p.PerPage > 0 && rows < p.PerPage {
+			return "", nil
+		}
+		return p.pageURL(current, page)
+	case "link":
+		return resolveURL(current, nextLink(header.Values("Link")))
+	}
+
+	// Numbers are kept as written, so that numeric cursors beyond the
+	// precision of a float64 are sent back unchanged.
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return "", fmt.Errorf("Unable to decode API JSON response: %w", err)
+	}
+	if p.Type == "next" {
+		return resolveURL(current, lookupField(fields, defaultString(p.NextField, "next")))
+	}
+	cursor := lookupField(fields, defaultString(p.CursorField, "next_cursor"))
+	if cursor == "" {
+		return "", nil
+	}
+	return setQuery(current, map[string]string{defaultString(p.CursorParam, "cursor"): cursor})
+}
+
+// pageURL sets the page number, and size if any, of a URL.
+func (p *Pagination) pageURL(u string, page int) (string, error) {
+	params := map[string]string{defaultString(p.PageParam, "page"): strconv.Itoa(page)}
+	if p.PerPage > 0 {
+		params[defaultString(p.PerPageParam, "per_page")] = strconv.Itoa(p.PerPage)
+	}
+	return setQuery(u, params)
+}
+
+func setQuery(u string, params map[string]string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", u, err)
+	}
+	q := parsed.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	parsed.RawQuery = q.Encode()
+	return parsed.String(), nil
+}
+
+// resolveURL resolves a possibly relative next link against the current
+// URL; an empty link stays empty.
+func resolveURL(current, link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", current, err)
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page URL %q: %w", link, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+var linkRel = regexp.MustCompile(`(?i);\s*rel\s*=\s*"?([^";]*)"?`)
+
+// nextLink returns the target of the rel="next" link of Link headers, such
+// as `<https://api.example.com/items?page=2>; rel="next"`.
+func nextLink(headers []string) string {
+	for _, h := range headers {
+		for _, link := range strings.Split(h, ",") {
+			link = strings.TrimSpace(link)
+			end := strings.Index(link, ">")
+			if !strings.HasPrefix(link, "<") || end == -1 {
+				continue
+			}
+			for _, m := range linkRel.FindAllStringSubmatch(link[end+1:], -1) {
+				for _, rel := range strings.Fields(m[1]) {
+					if strings.EqualFold(rel, "next") {
+						return link[1:end]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// lookupField returns the value at a dotted path of a JSON object as a
+// string, or "" if it is missing or null.
+func lookupField(fields map[string]interface{}, path string) string {
+	var v interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = obj[key]
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
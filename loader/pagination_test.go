@@ -0,0 +1,116 @@
+package loader
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pagedServer serves 7 rows, 3 per page, announcing the next page the way
+// the handler for the given pagination type expects.
+func pagedServer(t *testing.T, typ string) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 1
+		switch typ {
+		case "page":
+			page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+		case "cursor":
+			if c := r.URL.Query().Get("cursor"); c != "" {
+				page, _ = strconv.Atoi(strings.TrimPrefix(c, "c"))
+			}
+		default:
+			if p := r.URL.Query().Get("p"); p != "" {
+				page, _ = strconv.Atoi(p)
+			}
+		}
+		var rows []string
+		for i := (page-1)*3 + 1; i <= page*3 && i <= 7; i++ {
+			rows = append(rows, fmt.Sprintf(`["%d"]`, i))
+		}
+		more := page*3 < 7
+		extra := ""
+		switch {
+		case typ == "next" && more:
+			extra = fmt.Sprintf(`, "links": {"next": "/items?p=%d"}`, page+1)
+		case typ == "cursor" && more:
+			extra = fmt.Sprintf(`, "meta": {"cursor": "c%d"}`, page+1)
+		case typ == "link" && more:
+			w.Header().Add("Link", fmt.Sprintf(`<%s/items?p=1>; rel="first", <%s/items?p=%d>; rel="next"`, srv.URL, srv.URL, page+1))
+		}
+		fmt.Fprintf(w, `{"header": ["n"], "records": [%s]%s}`, strings.Join(rows, ","), extra)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAPIDataSource_Pagination(t *testing.T) {
+	retry, _ := Retry{MaxAttempts: 1}.policy()
+	tests := []struct {
+		name       string
+		pagination Pagination
+		want       int
+	}{
+		{"next", Pagination{Type: "next", NextField: "links.next"}, 7},
+		{"cursor", Pagination{Type: "cursor", CursorField: "meta.cursor"}, 7},
+		{"page", Pagination{Type: "page", PerPage: 3}, 7},
+		{"link", Pagination{Type: "link"}, 7},
+		{"max pages", Pagination{Type: "link", MaxPages: 2}, 6},
+		{"max rows", Pagination{Type: "link", MaxRows: 4}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := pagedServer(t, tt.pagination.Type)
+			api := &APIDataSource{URL: srv.URL + "/items", Timeout: time.Second, Retry: retry, Pagination: &tt.pagination}
+			data, err := api.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data.Records) != tt.want || data.Records[len(data.Records)-1][0] != strconv.Itoa(tt.want) {
+				t.Errorf("got %v, expected rows 1 to %d", data.Records, tt.want)
+			}
+		})
+	}
+}
+
+func TestPagination_NumericCursor(t *testing.T) {
+	p := &Pagination{Type: "cursor"}
+	body := []byte(`{"next_cursor": 9007199254740993}`)
+	next, err := p.next("https://example.com/items", body, nil, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/items?cursor=9007199254740993"; next != want {
+		t.Errorf("next = %q, expected %q", next, want)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		headers []string
+		want    string
+	}{
+		{[]string{`<https://a.io/x?page=2>; rel="next"`}, "https://a.io/x?page=2"},
+		{[]string{`<https://a.io/x?page=1>; rel=prev, <https://a.io/x?page=3>; rel=next`}, "https://a.io/x?page=3"},
+		{[]string{`<https://a.io/x?page=1>; rel="prev"`, `<https://a.io/x?page=3>; rel="next last"`}, "https://a.io/x?page=3"},
+		{[]string{`<https://a.io/x?page=1>; rel="prev"`}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.headers); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, expected %q", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestPaginationValidate(t *testing.T) {
+	for _, p := range []Pagination{{}, {Type: "offset"}, {Type: "page", MaxPages: -1}} {
+		if err := p.validate(); err == nil {
+			t.Errorf("expected an error for %+v", p)
+		}
+	}
+}
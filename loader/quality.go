@@ -0,0 +1,139 @@
+package loader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Imputed returns the number of cells of the column at index col that were
+// replaced by its on_invalid policy when the data was loaded.
+func (d *DataDataSource) Imputed(col int) int {
+	if col < 0 || col >= len(d.Header) {
+		return 0
+	}
+	return d.imputed[d.Header[col]]
+}
+
+// clean applies the null values and the on_invalid policy of each column.
+// The records are copied before they are changed, since they may be shared
+// with the source a derived source reads.
+func (d *DataDataSource) clean() error {
+	d.imputed = nil
+	copied := false
+	set := func(row, col int, v string) {
+		if !copied {
+			records := make([][]string, len(d.Records))
+			for i, record := range d.Records {
+				records[i] = append([]string(nil), record...)
+			}
+			d.Records = records
+			copied = true
+		}
+		d.Records[row][col] = v
+	}
+
+	for col, header := range d.Header {
+		c := d.columns[header]
+		if len(c.NullValues) > 0 {
+			for i, record := range d.Records {
+				if isNullValue(record[col], c.NullValues) {
+					set(i, col, "")
+				}
+			}
+		}
+		if c.OnInvalid == "" || c.OnInvalid == "skip" {
+			continue
+		}
+
+		var invalid []int
+		for i, record := range d.Records {
+			if _, err := d.ParseNumber(col, record[col]); err != nil {
+				invalid = append(invalid, i)
+			}
+		}
+		if len(invalid) == 0 {
+			continue
+		}
+		switch c.OnInvalid {
+		case "error":
+			i := invalid[0]
+			return fmt.Errorf("column '%s' has an invalid value %q in row %d", header, d.Records[i][col], i+1)
+		case "zero":
+			for _, i := range invalid {
+				set(i, col, "0")
+			}
+		case "interpolate":
+			values := d.interpolate(col, invalid)
+			if values == nil {
+				continue
+			}
+			for j, i := range invalid {
+				set(i, col, formatLocaleNumber(values[j], c.Locale))
+			}
+		}
+		if d.imputed == nil {
+			d.imputed = make(map[string]int)
+		}
+		d.imputed[header] = len(invalid)
+	}
+	return nil
+}
+
+// interpolate returns a value for each of the invalid rows of the column,
+// interpolated linearly between the closest valid rows before and after
+// it, or copied from the closest one at either end. It returns nil when the
+// column has no valid rows.
+func (d *DataDataSource) interpolate(col int, invalid []int) []float64 {
+	values := make([]float64, 0, len(invalid))
+	prev, next := -1, 0
+	var prevValue, nextValue float64
+	for _, i := range invalid {
+		for ; next < len(d.Records); next++ {
+			if next < i {
+				if v, err := d.ParseNumber(col, d.Records[next][col]); err == nil {
+					prev, prevValue = next, v
+				}
+				continue
+			}
+			if next > i {
+				if v, err := d.ParseNumber(col, d.Records[next][col]); err == nil {
+					nextValue = v
+					break
+				}
+			}
+		}
+		switch {
+		case prev == -1 && next == len(d.Records):
+			return nil
+		case prev == -1:
+			values = append(values, nextValue)
+		case next == len(d.Records):
+			values = append(values, prevValue)
+		default:
+			frac := float64(i-prev) / float64(next-prev)
+			values = append(values, prevValue+(nextValue-prevValue)*frac)
+		}
+	}
+	return values
+}
+
+func isNullValue(cell string, nulls []string) bool {
+	cell = strings.TrimSpace(cell)
+	for _, n := range nulls {
+		if cell == n {
+			return true
+		}
+	}
+	return false
+}
+
+// formatLocaleNumber writes a number with the decimal separator of the
+// locale, so that it parses back like the other cells of its column.
+func formatLocaleNumber(v float64, locale string) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if separators(locale) == ',' {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDataset_OnInvalid(t *testing.T) {
+	src := &staticSource{
+		header: []string{"a", "b", "c", "d"},
+		records: [][]string{
+			{"NA", "1", "x", "1"},
+			{"2", "-", "", "1"},
+			{"x", "NA", "3", "1"},
+			{"-", "8,5", "", "?"},
+		},
+	}
+	source := &Source{
+		NullValues: []string{"NA", "-"},
+		Columns: map[string]Column{
+			"a": {OnInvalid: "zero"},
+			"b": {OnInvalid: "interpolate", Locale: "de"},
+			"c": {OnInvalid: "interpolate", NullValues: []string{"x"}},
+		},
+	}
+	d, err := NewDataset(source, src)
+	if err != nil {
+		t.Fatalf("NewDataset failed: %v", err)
+	}
+	data := d.Data()
+	want := [][]string{
+		{"0", "1", "3", "1"},
+		{"2", "3,5", "3", "1"},
+		{"0", "6", "3", "1"},
+		{"0", "8,5", "3", "?"},
+	}
+	if !reflect.DeepEqual(data.Records, want) {
+		t.Errorf("records = %v, expected %v", data.Records, want)
+	}
+	for col, n := range []int{3, 2, 3, 0} {
+		if got := data.Imputed(col); got != n {
+			t.Errorf("Imputed(%d) = %d, expected %d", col, got, n)
+		}
+	}
+	if src.records[0][0] != "NA" {
+		t.Errorf("the loaded records should not be modified")
+	}
+
+	source.Columns["d"] = Column{OnInvalid: "error"}
+	if err := d.Refresh(); err == nil {
+		t.Errorf("expected an error for an invalid value with on_invalid: error")
+	}
+	source.Columns["d"] = Column{OnInvalid: "ignore"}
+	if _, err := NewDataset(source, src); err == nil {
+		t.Errorf("expected an error for an unknown on_invalid policy")
+	}
+}
@@ -0,0 +1,138 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout bounds each request of a remote source without a timeout.
+const DefaultTimeout = 10 * time.Second
+
+// Retry is the retry policy of a remote source. Network errors and
+// timeouts are always retried, as well as the statuses in RetryOn.
+type Retry struct {
+	// MaxAttempts is the number of requests made before giving up, 3 by
+	// default; 1 disables retries.
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// Backoff is the delay before the first retry, "500ms" by default,
+	// doubling at every attempt up to MaxBackoff, "30s" by default. Each
+	// delay is picked at random up to that value, so that dashboards don't
+	// retry in lockstep.
+	Backoff    string `yaml:"backoff,omitempty"`
+	MaxBackoff string `yaml:"max_backoff,omitempty"`
+	// RetryOn lists the HTTP statuses that are retried, by default 408,
+	// 429, 500, 502, 503 and 504.
+	RetryOn []int `yaml:"retry_on,omitempty"`
+}
+
+// RetryPolicy is a Retry with its defaults applied.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	RetryOn     map[int]bool
+}
+
+// policy applies the defaults of the retry settings.
+func (r Retry) policy() (RetryPolicy, error) {
+	p := RetryPolicy{MaxAttempts: r.MaxAttempts, Backoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+	if p.MaxAttempts < 0 {
+		return p, fmt.Errorf("retry max_attempts can't be negative")
+	}
+	for _, d := range []struct {
+		setting string
+		out     *time.Duration
+	}{{r.Backoff, &p.Backoff}, {r.MaxBackoff, &p.MaxBackoff}} {
+		if d.setting == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.setting)
+		if err != nil || v < 0 {
+			return p, fmt.Errorf("invalid retry backoff %q", d.setting)
+		}
+		*d.out = v
+	}
+	statuses := r.RetryOn
+	if statuses == nil {
+		statuses = []int{408, 429, 500, 502, 503, 504}
+	}
+	p.RetryOn = make(map[int]bool, len(statuses))
+	for _, s := range statuses {
+		p.RetryOn[s] = true
+	}
+	return p, nil
+}
+
+// delay returns the random wait before the retry following the given
+// attempt, counted from zero.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	limit := p.Backoff
+	for i := 0; i < attempt && limit < p.MaxBackoff; i++ {
+		limit *= 2
+	}
+	limit = min(limit, p.MaxBackoff)
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// fetch gets a URL with the retry policy, bounding each request by timeout
+// and stopping as soon as ctx is done. It returns the body and the headers
+// of the response.
+func fetch(ctx context.Context, url string, timeout time.Duration, retry RetryPolicy) ([]byte, http.Header, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for attempt := 0; ; attempt++ {
+		body, header, retriable, err := get(ctx, url, timeout, retry)
+		if err == nil {
+			return body, header, nil
+		}
+		if !retriable || attempt+1 >= retry.MaxAttempts || ctx.Err() != nil {
+			if attempt > 0 {
+				err = fmt.Errorf("%w (after %d attempts)", err, attempt+1)
+			}
+			return nil, nil, err
+		}
+		timer := time.NewTimer(retry.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, err
+		}
+	}
+}
+
+// get makes a single request, reporting whether a failure is worth
+// retrying.
+func get(ctx context.Context, url string, timeout time.Duration, retry RetryPolicy) ([]byte, http.Header, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("Unable to make API request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, true, fmt.Errorf("Unable to make API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, retry.RetryOn[resp.StatusCode], fmt.Errorf("API response failed, status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, true, fmt.Errorf("Unable to read response body: %w", err)
+	}
+	return body, resp.Header, false, nil
+}
@@ -0,0 +1,100 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingServer answers with status for the first failures requests, then
+// with a small JSON dataset.
+func failingServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"header": ["n"], "records": [["1"]]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestAPIDataSource_Retry(t *testing.T) {
+	policy, err := Retry{Backoff: "1ms", MaxBackoff: "2ms"}.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, requests := failingServer(t, 2, http.StatusServiceUnavailable)
+	data, err := (&APIDataSource{URL: srv.URL, Timeout: time.Second, Retry: policy}).Load()
+	if err != nil || len(data.Records) != 1 || *requests != 3 {
+		t.Errorf("expected success on the third attempt, got %v, %v after %d requests", data, err, *requests)
+	}
+
+	srv, requests = failingServer(t, 5, http.StatusBadGateway)
+	_, err = (&APIDataSource{URL: srv.URL, Timeout: time.Second, Retry: policy}).Load()
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") || *requests != 3 {
+		t.Errorf("expected giving up after 3 attempts, got %v after %d requests", err, *requests)
+	}
+
+	srv, requests = failingServer(t, 1, http.StatusNotFound)
+	if _, err := (&APIDataSource{URL: srv.URL, Timeout: time.Second, Retry: policy}).Load(); err == nil || *requests != 1 {
+		t.Errorf("expected no retry on 404, got %v after %d requests", err, *requests)
+	}
+
+	policy, _ = Retry{Backoff: "1ms", RetryOn: []int{404}}.policy()
+	srv, requests = failingServer(t, 1, http.StatusNotFound)
+	if _, err := (&APIDataSource{URL: srv.URL, Timeout: time.Second, Retry: policy}).Load(); err != nil || *requests != 2 {
+		t.Errorf("expected a retry on a configured status, got %v after %d requests", err, *requests)
+	}
+}
+
+func TestAPIDataSource_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	policy, _ := Retry{MaxAttempts: 2, Backoff: "1ms"}.policy()
+	start := time.Now()
+	_, err := (&APIDataSource{URL: srv.URL, Timeout: 20 * time.Millisecond, Retry: policy}).Load()
+	if err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("expected the requests to time out, got %v after %v", err, time.Since(start))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy, _ = Retry{MaxAttempts: 5, Backoff: "1h"}.policy()
+	start = time.Now()
+	if _, err := (&APIDataSource{URL: srv.URL, Context: ctx, Timeout: time.Hour, Retry: policy}).Load(); err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("expected a cancelled context to stop the load, got %v after %v", err, time.Since(start))
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p, err := Retry{}.policy()
+	if err != nil || p.MaxAttempts != 3 || !p.RetryOn[503] || p.RetryOn[404] {
+		t.Errorf("unexpected defaults %+v, %v", p, err)
+	}
+	for attempt := 0; attempt < 10; attempt++ {
+		if d := p.delay(attempt); d < 0 || d > p.MaxBackoff || d > p.Backoff<<attempt {
+			t.Errorf("delay(%d) = %v is out of range", attempt, d)
+		}
+	}
+	for _, r := range []Retry{{MaxAttempts: -1}, {Backoff: "soon"}, {MaxBackoff: "-1s"}} {
+		if _, err := r.policy(); err == nil {
+			t.Errorf("expected an error for %+v", r)
+		}
+	}
+}
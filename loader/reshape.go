@@ -0,0 +1,267 @@
+package loader
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"datacmd/aggregate"
+)
+
+// Reshape turns wide rows into long ones with Melt, or long rows into wide
+// ones with Pivot. At most one of them may be set.
+type Reshape struct {
+	Pivot *Pivot `yaml:"pivot,omitempty"`
+	Melt  *Melt  `yaml:"melt,omitempty"`
+}
+
+// Pivot spreads the values of a column over new columns, one for each value
+// of the Variable column, with a row for each combination of the ID
+// columns.
+type Pivot struct {
+	ID       []string `yaml:"id"`
+	Variable string   `yaml:"variable"`
+	Value    string   `yaml:"value"`
+	// Columns fixes the values of Variable that become columns, in order.
+	// By default they are those of the first load, in order of appearance,
+	// and the values showing up on later refreshes are left out.
+	Columns []string `yaml:"columns,omitempty"`
+	// Aggregation combines the values that fall in the same cell. It
+	// defaults to sum; first and last keep the cell as it is.
+	Aggregation string `yaml:"aggregation,omitempty"`
+}
+
+// Melt turns each of the Columns of a row into a row of its own, holding the
+// ID columns, the name of the column in Variable and its cell in Value.
+type Melt struct {
+	ID []string `yaml:"id,omitempty"`
+	// Columns are the columns to melt, by default all but the ID columns.
+	Columns []string `yaml:"columns,omitempty"`
+	// Variable and Value name the new columns; they default to "variable"
+	// and "value".
+	Variable string `yaml:"variable,omitempty"`
+	Value    string `yaml:"value,omitempty"`
+}
+
+// validate checks the settings of the reshape.
+func (r *Reshape) validate() error {
+	if r.Pivot != nil && r.Melt != nil {
+		return fmt.Errorf("a source can't both pivot and melt")
+	}
+	if p := r.Pivot; p != nil {
+		if len(p.ID) == 0 || p.Variable == "" || p.Value == "" {
+			return fmt.Errorf("pivot needs id, variable and value columns")
+		}
+		if err := aggregate.ValidateStateless(p.Aggregation); err != nil {
+			return fmt.Errorf("pivot: %w", err)
+		}
+	}
+	return nil
+}
+
+// Apply returns the reshaped data, or data itself when there is nothing to
+// do. Data is not modified.
+func (r *Reshape) Apply(data *DataDataSource) (*DataDataSource, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Pivot != nil:
+		return r.Pivot.apply(data)
+	case r.Melt != nil:
+		return r.Melt.apply(data)
+	}
+	return data, nil
+}
+
+// columnIndexes resolves the named columns of data.
+func columnIndexes(data *DataDataSource, names []string) ([]int, error) {
+	indexes := make([]int, len(names))
+	for i, name := range names {
+		if indexes[i] = data.ColumnIndex(name); indexes[i] == -1 {
+			return nil, fmt.Errorf("column '%s' not found", name)
+		}
+	}
+	return indexes, nil
+}
+
+// checkHeader reports a column that appears twice in a reshaped header.
+func checkHeader(header []string) error {
+	seen := make(map[string]bool, len(header))
+	for _, h := range header {
+		if seen[h] {
+			return fmt.Errorf("column '%s' would appear twice", h)
+		}
+		seen[h] = true
+	}
+	return nil
+}
+
+func (p *Pivot) apply(data *DataDataSource) (*DataDataSource, error) {
+	ids, err := columnIndexes(data, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("pivot: %w", err)
+	}
+	cols, err := columnIndexes(data, []string{p.Variable, p.Value})
+	if err != nil {
+		return nil, fmt.Errorf("pivot: %w", err)
+	}
+	variableCol, valueCol := cols[0], cols[1]
+
+	columns := p.Columns
+	fixed := len(columns) > 0
+	position := make(map[string]int)
+	for i, c := range columns {
+		position[c] = i
+	}
+
+	// Rows are identified by their ID cells and keep the order in which
+	// they first appear.
+	var keys []string
+	rows := make(map[string][]string)
+	cells := make(map[string][][]string)
+	for _, record := range data.Records {
+		idCells := make([]string, len(ids))
+		for j, col := range ids {
+			idCells[j] = record[col]
+		}
+		key := strings.Join(idCells, "\x00")
+		if _, ok := rows[key]; !ok {
+			keys = append(keys, key)
+			rows[key] = idCells
+		}
+
+		variable := record[variableCol]
+		i, ok := position[variable]
+		if !ok {
+			if fixed {
+				continue
+			}
+			i = len(columns)
+			position[variable] = i
+			columns = append(columns, variable)
+		}
+		for len(cells[key]) <= i {
+			cells[key] = append(cells[key], nil)
+		}
+		cells[key][i] = append(cells[key][i], record[valueCol])
+	}
+
+	header := append(append([]string(nil), p.ID...), columns...)
+	if err := checkHeader(header); err != nil {
+		return nil, fmt.Errorf("pivot: %w", err)
+	}
+	agg := p.Aggregation
+	if agg == "" {
+		agg = "sum"
+	}
+	raw := agg == "first" || agg == "last"
+	parse := func(s string) (float64, error) { return data.ParseNumber(valueCol, s) }
+
+	records := make([][]string, len(keys))
+	for r, key := range keys {
+		record := append(make([]string, 0, len(header)), rows[key]...)
+		for i := range columns {
+			var group []string
+			if i < len(cells[key]) {
+				group = cells[key][i]
+			}
+			switch {
+			case len(group) == 0:
+				record = append(record, "")
+			case raw && agg == "first":
+				record = append(record, group[0])
+			case raw:
+				record = append(record, group[len(group)-1])
+			default:
+				v, err := aggregate.Cells(agg, group, parse)
+				if err != nil {
+					return nil, fmt.Errorf("pivot: %w", err)
+				}
+				record = append(record, formatNumber(v))
+			}
+		}
+		records[r] = record
+	}
+
+	// Aggregated cells are written with a decimal point; kept ones keep the
+	// settings of the value column.
+	settings := make(map[string]Column, len(header))
+	for _, id := range p.ID {
+		settings[id] = data.columns[id]
+	}
+	for _, c := range columns {
+		settings[c] = Column{Locale: "en"}
+		if raw {
+			settings[c] = data.columns[p.Value]
+		}
+	}
+	return &DataDataSource{Header: header, Records: records, columns: settings}, nil
+}
+
+func (m *Melt) apply(data *DataDataSource) (*DataDataSource, error) {
+	ids, err := columnIndexes(data, m.ID)
+	if err != nil {
+		return nil, fmt.Errorf("melt: %w", err)
+	}
+	melted := m.Columns
+	if len(melted) == 0 {
+		isID := make(map[string]bool, len(m.ID))
+		for _, id := range m.ID {
+			isID[id] = true
+		}
+		for _, h := range data.Header {
+			if !isID[h] {
+				melted = append(melted, h)
+			}
+		}
+	}
+	if len(melted) == 0 {
+		return nil, fmt.Errorf("melt: there are no columns to melt")
+	}
+	cols, err := columnIndexes(data, melted)
+	if err != nil {
+		return nil, fmt.Errorf("melt: %w", err)
+	}
+	variable, value := m.Variable, m.Value
+	if variable == "" {
+		variable = "variable"
+	}
+	if value == "" {
+		value = "value"
+	}
+
+	header := append(append([]string(nil), m.ID...), variable, value)
+	if err := checkHeader(header); err != nil {
+		return nil, fmt.Errorf("melt: %w", err)
+	}
+	records := make([][]string, 0, len(data.Records)*len(cols))
+	for _, record := range data.Records {
+		for i, col := range cols {
+			row := make([]string, 0, len(header))
+			for _, id := range ids {
+				row = append(row, record[id])
+			}
+			records = append(records, append(row, melted[i], record[col]))
+		}
+	}
+
+	// The value column takes the settings of the first melted column.
+	settings := make(map[string]Column, len(header))
+	for _, id := range m.ID {
+		settings[id] = data.columns[id]
+	}
+	settings[variable] = Column{}
+	settings[value] = data.columns[melted[0]]
+	return &DataDataSource{Header: header, Records: records, columns: settings}, nil
+}
+
+// formatNumber writes an aggregated number with a decimal point, or an
+// empty cell for NaN.
+func formatNumber(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,68 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReshape_Pivot(t *testing.T) {
+	data := &DataDataSource{
+		Header: []string{"region", "month", "sales"},
+		Records: [][]string{
+			{"EU", "jan", "1"},
+			{"US", "jan", "3"},
+			{"EU", "feb", "2"},
+			{"EU", "jan", "4,5"},
+		},
+	}
+	r := Reshape{Pivot: &Pivot{ID: []string{"region"}, Variable: "month", Value: "sales"}}
+	got, err := r.Apply(data)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if want := []string{"region", "jan", "feb"}; !reflect.DeepEqual(got.Header, want) {
+		t.Errorf("header = %v, expected %v", got.Header, want)
+	}
+	if want := [][]string{{"EU", "5.5", "2"}, {"US", "3", ""}}; !reflect.DeepEqual(got.Records, want) {
+		t.Errorf("records = %v, expected %v", got.Records, want)
+	}
+
+	r.Pivot.Columns = []string{"feb", "mar"}
+	r.Pivot.Aggregation = "last"
+	got, err = r.Apply(data)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if want := [][]string{{"EU", "2", ""}, {"US", "", ""}}; !reflect.DeepEqual(got.Records, want) {
+		t.Errorf("records with fixed columns = %v, expected %v", got.Records, want)
+	}
+
+	r.Pivot.Aggregation = "rate"
+	if _, err := r.Apply(data); err == nil {
+		t.Errorf("expected an error for a stateful aggregation")
+	}
+}
+
+func TestReshape_Melt(t *testing.T) {
+	data := &DataDataSource{
+		Header:  []string{"region", "jan", "feb"},
+		Records: [][]string{{"EU", "1", "2"}, {"US", "3", "4"}},
+	}
+	r := Reshape{Melt: &Melt{ID: []string{"region"}, Value: "sales"}}
+	got, err := r.Apply(data)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if want := []string{"region", "variable", "sales"}; !reflect.DeepEqual(got.Header, want) {
+		t.Errorf("header = %v, expected %v", got.Header, want)
+	}
+	want := [][]string{{"EU", "jan", "1"}, {"EU", "feb", "2"}, {"US", "jan", "3"}, {"US", "feb", "4"}}
+	if !reflect.DeepEqual(got.Records, want) {
+		t.Errorf("records = %v, expected %v", got.Records, want)
+	}
+
+	r.Melt.Value = "region"
+	if _, err := r.Apply(data); err == nil {
+		t.Errorf("expected an error for a value column named like an id column")
+	}
+}
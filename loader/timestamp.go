@@ -0,0 +1,212 @@
+package loader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Special time layouts accepted in a column's time_layout.
+const (
+	LayoutEpoch   = "epoch"
+	LayoutEpochMs = "epoch_ms"
+)
+
+// timeLayouts are the layouts tried, in order, when a column has no
+// configured layout.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"01/02/2006 15:04:05",
+	"01/02/2006",
+	"02.01.2006 15:04:05",
+	"02.01.2006",
+	"02 Jan 2006 15:04",
+	"02 Jan 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+	time.UnixDate,
+	"2006-01",
+}
+
+// Epoch values are only detected in columns named like times, and when they
+// fall between 1990 and 2100, so that plain integer columns such as byte
+// counts or IDs are not mistaken for timestamps.
+const (
+	minDetectedEpoch = 631152000
+	maxDetectedEpoch = 4102444800
+)
+
+// timeNameWords are the words of a column name, as in "ts", "created_at"
+// or "eventTime", telling that its numbers are epoch timestamps.
+var timeNameWords = map[string]bool{
+	"ts": true, "time": true, "timestamp": true, "date": true,
+	"datetime": true, "epoch": true, "unix": true, "at": true,
+}
+
+// isTimeName reports whether a column name has one of timeNameWords, its
+// words being separated by punctuation or a change to upper case.
+func isTimeName(name string) bool {
+	var word []rune
+	found := false
+	flush := func() {
+		found = found || timeNameWords[strings.ToLower(string(word))]
+		word = word[:0]
+	}
+	prev := rune(0)
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	flush()
+	return found
+}
+
+// ParseTime parses a timestamp. The layout may be a Go reference layout,
+// LayoutEpoch or LayoutEpochMs; when empty, RFC 3339 and a set of common
+// date formats are tried. Timestamps without a zone are read in loc, which
+// defaults to UTC.
+func ParseTime(s, layout string, loc *time.Location) (time.Time, error) {
+	return parseTime(s, layout, loc, false)
+}
+
+// parseTime is ParseTime, also detecting epoch seconds or milliseconds when
+// there is no layout and epochs is true.
+func parseTime(s, layout string, loc *time.Location, epochs bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, ErrEmptyValue
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	switch layout {
+	case "":
+	case LayoutEpoch:
+		return parseEpoch(s, time.Second, loc)
+	case LayoutEpochMs:
+		return parseEpoch(s, time.Millisecond, loc)
+	default:
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+		}
+		return t, nil
+	}
+
+	if epochs {
+		if t, err := detectEpoch(s, loc); err == nil {
+			return t, nil
+		}
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: unknown format", s)
+}
+
+// parseEpoch parses a possibly fractional epoch value expressed in unit.
+func parseEpoch(s string, unit time.Duration, loc *time.Location) (time.Time, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch %q", s)
+	}
+	return time.Unix(0, int64(v*float64(unit))).In(loc), nil
+}
+
+// detectEpoch recognizes epoch seconds or milliseconds in a plausible range.
+func detectEpoch(s string, loc *time.Location) (time.Time, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch {
+	case v >= minDetectedEpoch && v <= maxDetectedEpoch:
+		return parseEpoch(s, time.Second, loc)
+	case v >= minDetectedEpoch*1e3 && v <= maxDetectedEpoch*1e3:
+		return parseEpoch(s, time.Millisecond, loc)
+	}
+	return time.Time{}, fmt.Errorf("%q is not an epoch", s)
+}
+
+// IsTime reports whether s, a cell of the named column, is a timestamp when
+// the column has no layout. Epochs are only recognized in columns named
+// like times.
+func IsTime(name, s string) bool {
+	_, err := parseTime(s, "", time.UTC, isTimeName(name))
+	return err == nil
+}
+
+// ParseTime parses a cell of the column at index col with the layout and
+// timezone configured for that column. Without a layout, epochs are only
+// recognized in columns named like times.
+func (d *DataDataSource) ParseTime(col int, s string) (time.Time, error) {
+	var c Column
+	name := ""
+	if col >= 0 && col < len(d.Header) {
+		name = d.Header[col]
+		c = d.columns[name]
+	}
+	return parseTime(s, c.TimeLayout, c.location, isTimeName(name))
+}
+
+// Location returns the timezone configured for the column at index col.
+func (d *DataDataSource) Location(col int) *time.Location {
+	if col >= 0 && col < len(d.Header) {
+		if loc := d.columns[d.Header[col]].location; loc != nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// IsTimeColumn reports whether the column at index col holds timestamps:
+// either a time_layout is configured for it, or its first non-empty values
+// all parse as times.
+func (d *DataDataSource) IsTimeColumn(col int) bool {
+	if col < 0 || col >= len(d.Header) {
+		return false
+	}
+	if d.columns[d.Header[col]].TimeLayout != "" {
+		return true
+	}
+	sampled := 0
+	for _, record := range d.Records {
+		if sampled == 5 {
+			break
+		}
+		if strings.TrimSpace(record[col]) == "" {
+			continue
+		}
+		if _, err := d.ParseTime(col, record[col]); err != nil {
+			return false
+		}
+		sampled++
+	}
+	return sampled > 0
+}
@@ -0,0 +1,58 @@
+package loader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	for _, tt := range []struct{ in, layout string }{
+		{"2024-03-01T12:30:00Z", ""},
+		{"2024-03-01 12:30:00", ""},
+		{"1709296200", LayoutEpoch},
+		{"1709296200000", LayoutEpochMs},
+	} {
+		got, err := ParseTime(tt.in, tt.layout, time.UTC)
+		if err != nil {
+			t.Errorf("ParseTime(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("ParseTime(%q) = %v, expected %v", tt.in, got, want)
+		}
+	}
+	if _, err := ParseTime("1709296200", "", time.UTC); err == nil {
+		t.Errorf("an epoch should need a layout or a column named like a time")
+	}
+
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	got, err := ParseTime("01/03/2024 13:30", "02/01/2006 15:04", rome)
+	if err != nil || !got.Equal(want) {
+		t.Errorf("ParseTime with layout and zone = %v, %v; expected %v", got, err, want)
+	}
+	if IsTime("ts", "75") {
+		t.Errorf("a small integer must not be detected as a time")
+	}
+}
+
+func TestIsTimeColumn_Epochs(t *testing.T) {
+	data := &DataDataSource{
+		Header:  []string{"ts", "createdAt", "bytes", "revenue", "id"},
+		Records: [][]string{{"1709296200", "1709296200000", "1073741824", "1500000000", "1200000000"}},
+	}
+	if err := data.applySource(&Source{Columns: map[string]Column{"id": {TimeLayout: LayoutEpoch}}}); err != nil {
+		t.Fatal(err)
+	}
+	for col, want := range []bool{true, true, false, false, true} {
+		if got := data.IsTimeColumn(col); got != want {
+			t.Errorf("IsTimeColumn(%q) = %v, expected %v", data.Header[col], got, want)
+		}
+	}
+	if isTimeName("data") || isTimeName("status") || !isTimeName("EVENT_TIME") {
+		t.Errorf("unexpected time names")
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"datacmd/aggregate"
 	"datacmd/generate"
 	"datacmd/loader"
+	"datacmd/series"
+	"datacmd/transform"
 	"datacmd/widgets"
 	"flag"
 	"fmt"
@@ -17,21 +20,25 @@ import (
 	"github.com/mum4k/termdash/terminal/termbox"
 	"github.com/mum4k/termdash/terminal/terminalapi"
 	"github.com/mum4k/termdash/widgetapi"
-	"github.com/mum4k/termdash/widgets/barchart"
 	"github.com/mum4k/termdash/widgets/donut"
 	"github.com/mum4k/termdash/widgets/gauge"
-	"github.com/mum4k/termdash/widgets/linechart"
 	"github.com/mum4k/termdash/widgets/segmentdisplay"
 	"github.com/mum4k/termdash/widgets/sparkline"
 	"github.com/mum4k/termdash/widgets/text"
 	"gopkg.in/yaml.v2"
 	"log"
+	"math"
 	"os"
+	"slices"
 	"sort"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// pieSliceLabelMin is the smallest percentage written on a pie slice.
+const pieSliceLabelMin = 5
+
 // redrawInterval is how often termdash redraws the screen.
 const redrawInterval = 250 * time.Millisecond
 
@@ -51,6 +58,10 @@ func main() {
 	configPath := flag.String("config", "config.yml", "Path to the YAML configuration file.")
 	sourcePath := flag.String("source", "", "Path to the data source file or URL.")
 	generatePtr := flag.Bool("generate", false, "Generate a dashboard configuration based on the provided source type and path.")
+	pivotPtr := flag.String("pivot", "", "Pivot the generated source, e.g. '{id: [region], variable: month, value: sales}'.")
+	offlinePtr := flag.Bool("offline", false, "Show the cached copies of remote sources without fetching them.")
+	paginationPtr := flag.String("pagination", "", "Follow the pages of the generated API source, e.g. '{type: link, max_pages: 5}'.")
+	meltPtr := flag.String("melt", "", "Melt the generated source, e.g. '{id: [region], variable: month, value: sales}'.")
 	helpPtr := flag.Bool("help", false, "Show help information.")
 	flag.Parse()
 
@@ -63,7 +74,21 @@ func main() {
 	// if --generate is provided, call GenerateDashboardConfig and then load the generated config
 
 	if *generatePtr {
-		config, err := generate.GenerateDashboardConfig(*sourcePath)
+		var reshape loader.Reshape
+		if err := parseYAMLFlag(*pivotPtr, &reshape.Pivot); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --pivot: %v\n", err)
+			os.Exit(1)
+		}
+		if err := parseYAMLFlag(*meltPtr, &reshape.Melt); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --melt: %v\n", err)
+			os.Exit(1)
+		}
+		var pagination *loader.Pagination
+		if err := parseYAMLFlag(*paginationPtr, &pagination); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --pagination: %v\n", err)
+			os.Exit(1)
+		}
+		config, err := generate.GenerateDashboardConfig(*sourcePath, reshape, pagination)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error generating dashboard: %v\n", err)
 			os.Exit(1)
@@ -82,9 +107,14 @@ func main() {
 
 	}
 
-	config, csvData, err := loader.LoadConfigAndData(*configPath)
+	// The requests of remote sources are cancelled when the dashboard quits.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config, catalog, err := loader.LoadConfigAndDataWith(*configPath, loader.Options{Offline: *offlinePtr, Context: ctx})
 	if err != nil {
-		log.Fatalf("Error loading config or data: %v", err)
+		fmt.Fprintf(os.Stderr, "Error loading config or data: %v\n", err)
+		os.Exit(1)
 	}
 
 	var t terminalapi.Terminal
@@ -103,20 +133,25 @@ func main() {
 	}
 	defer t.Close()
 
-	c, err := container.New(t, container.ID(rootID))
+	c, err := container.New(t, container.ID(rootID),
+		container.KeyFocusNext(keyboard.KeyTab), container.KeyFocusPrevious(keyboard.KeyBacktab))
 	if err != nil {
 		panic(err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// A failed reload keeps the last good data on screen, and is reported
+	// in the status line.
+	go periodic(ctx, time.Duration(config.Refresh)*time.Second, catalog.Refresh)
 
 	// Crea i widget dinamicamente in base alla configurazione YAML.
-	dynamicWidgets, err := createWidgets(ctx, config, csvData, t)
+	dynamicWidgets, err := createWidgets(ctx, config, catalog, t)
 	if err != nil {
 		panic(err)
 	}
 
+	titleText := dynamicWidgets["title"].(*text.Text)
+	status.show(dynamicWidgets["status"].(*text.Text))
+
 	// Costruisci il layout in modo dinamico.
 	gridOpts, err := dynamicGridLayout(dynamicWidgets, config)
 	if err != nil {
@@ -127,6 +162,11 @@ func main() {
 		panic(err)
 	}
 
+	showQuality := func() error {
+		return showDataQuality(c, config, catalog, titleText)
+	}
+	go periodicNow(ctx, time.Duration(config.Refresh)*time.Second, showQuality)
+
 	quitter := func(k *terminalapi.Keyboard) {
 		if k.Key == keyboard.KeyEsc || k.Key == keyboard.KeyCtrlC {
 			cancel()
@@ -138,10 +178,12 @@ func main() {
 }
 
 // createWidgets creates a map of widgets based on the YAML configuration.
-func createWidgets(ctx context.Context, config *loader.Config, csvData *loader.DataDataSource, t terminalapi.Terminal) (map[string]interface{}, error) {
+func createWidgets(ctx context.Context, config *loader.Config, catalog *loader.Catalog, t terminalapi.Terminal) (map[string]interface{}, error) {
 	widgets := make(map[string]interface{})
 
-	for _, w := range config.Widgets {
+	for i := range config.Widgets {
+		w := &config.Widgets[i]
+		src := catalog.Widget(w)
 		var widget interface{}
 		var err error
 
@@ -149,29 +191,43 @@ func createWidgets(ctx context.Context, config *loader.Config, csvData *loader.D
 		// Altri tipi come heatmap, matrix, pie, radar, scatter richiedono librerie dedicate o implementazioni personalizzate.
 		switch w.Type {
 		case "sparkline":
-			widget, err = createSparkline(ctx, &w, csvData, config.Refresh)
+			widget, err = createSparkline(ctx, w, src, config.Refresh)
 		case "gauge":
-			widget, err = createGauge(ctx, &w, csvData, config.Refresh)
+			widget, err = createGauge(ctx, w, src, config.Refresh)
 		case "line":
-			widget, err = createLineChart(ctx, &w, csvData, config.Refresh)
+			widget, err = createLineChart(ctx, w, src, config.Refresh)
 		case "bar":
-			widget, err = createBarChart(ctx, &w, csvData, config.Refresh)
+			widget, err = createBarChart(ctx, w, src, config.Refresh)
 		case "donut":
-			widget, err = createDonut(ctx, &w, csvData, config.Refresh)
+			widget, err = createDonut(ctx, w, src, config.Refresh)
 		case "pie":
-			widget, err = createPieChart(ctx, &w, csvData, config.Refresh)
+			widget, err = createPieChart(ctx, w, src, config.Refresh)
 		case "text":
-			widget, err = createText(ctx, &w, csvData, config.Refresh)
+			widget, err = createText(ctx, w, src, config.Refresh)
 		case "radar":
-			widget, err = createRadarChart(ctx, &w, csvData, config.Refresh)
+			widget, err = createRadarChart(ctx, w, src, config.Refresh)
 		case "table":
-			widget, err = createTable(ctx, &w, csvData, config.Refresh)
+			widget, err = createTable(ctx, w, src, config.Refresh)
 		case "funnel":
-			widget, err = createFunnel(ctx, &w, csvData, config.Refresh)
-        case "scatter":
-            widget, err = createScatterPlot(ctx, &w, csvData, config.Refresh)
-        case "histogram":
-            widget, err = createHistogram(ctx, &w, csvData, config.Refresh)
+			widget, err = createFunnel(ctx, w, src, config.Refresh)
+		case "scatter":
+			widget, err = createScatterPlot(ctx, w, src, config.Refresh)
+		case "histogram":
+			widget, err = createHistogram(ctx, w, src, config.Refresh)
+		case "heatmap":
+			widget, err = createHeatmap(ctx, w, src, config.Refresh)
+		case "calendar":
+			widget, err = createCalendar(ctx, w, src, config.Refresh)
+		case "candlestick":
+			widget, err = createCandlestick(ctx, w, src, config.Refresh)
+		case "stat":
+			widget, err = createStat(ctx, w, src, config.Refresh)
+		case "treemap":
+			widget, err = createTreemap(ctx, w, src, config.Refresh)
+		case "boxplot":
+			widget, err = createBoxplot(ctx, w, src, config.Refresh)
+		case "log":
+			widget, err = createLog(ctx, w, src, config.Refresh)
 		default:
 			textWidget, err := text.New()
 			if err == nil {
@@ -195,6 +251,12 @@ func createWidgets(ctx context.Context, config *loader.Config, csvData *loader.D
 	titleText.Write(config.Title, text.WriteCellOpts(cell.FgColor(cell.ColorGreen)))
 	widgets["title"] = titleText
 
+	statusText, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	widgets["status"] = statusText
+
 	return widgets, nil
 }
 
@@ -212,8 +274,17 @@ func dynamicGridLayout(widgets map[string]interface{}, config *loader.Config) ([
 	// Rimuovi il widget del titolo dalla mappa per evitare di processarlo di nuovo
 	delete(widgets, "title")
 
+	// The status line goes at the bottom, after the rows of widgets.
+	statusWidget, ok := widgets["status"].(widgetapi.Widget)
+	if !ok {
+		return nil, fmt.Errorf("the status widget is not a valid widget")
+	}
+	delete(widgets, "status")
+	statusRow := grid.RowHeightPerc(5, grid.Widget(statusWidget, container.Border(linestyle.Light)))
+
 	numWidgets := len(widgets)
 	if numWidgets == 0 {
+		builder.Add(statusRow)
 		gridOpts, err := builder.Build()
 		if err != nil {
 			return nil, err
@@ -232,13 +303,14 @@ func dynamicGridLayout(widgets map[string]interface{}, config *loader.Config) ([
 	// Ensure the order of widgets is consistent with the config.
 	widgetConfigs := config.Widgets
 
-	for _, conf := range widgetConfigs {
+	for i, conf := range widgetConfigs {
 		widget, ok := widgets[conf.Title].(widgetapi.Widget)
 		if !ok {
 			return nil, fmt.Errorf("the widget '%s' is not a valid widget", conf.Title)
 		}
 
 		opts := []container.Option{
+			container.ID(widgetID(i)),
 			container.Border(linestyle.Light),
 			container.BorderTitle(conf.Title),
 		}
@@ -285,12 +357,13 @@ func dynamicGridLayout(widgets map[string]interface{}, config *loader.Config) ([
 	}
 
 	// Calculate the height for each row.
-	rowHeightPerc := (100 - 5) / len(rows)
+	rowHeightPerc := (100 - 10) / len(rows)
 
 	// Add the dynamically created rows to the grid builder.
 	for _, row := range rows {
 		builder.Add(grid.RowHeightPerc(rowHeightPerc, row...))
 	}
+	builder.Add(statusRow)
 
 	gridOpts, err := builder.Build()
 	if err != nil {
@@ -299,34 +372,533 @@ func dynamicGridLayout(widgets map[string]interface{}, config *loader.Config) ([
 	return gridOpts, nil
 }
 
-// periodic executes the provided closure periodically every interval.
+// widgetID returns the ID of the container of the i-th widget.
+func widgetID(i int) string {
+	return fmt.Sprintf("widget-%d", i)
+}
+
+// numericColumns returns the columns a widget reads as numbers, leaving out
+// the rows whose cells are empty or invalid.
+func numericColumns(w *loader.WidgetConfig, data *loader.DataDataSource) []int {
+	switch w.Type {
+	case "radar":
+		if radarValueCol < len(data.Header) {
+			return []int{radarValueCol}
+		}
+		return nil
+	case "funnel":
+		counted := w.GroupBy != "" && (w.Aggregation == "count" || w.Aggregation == "count_distinct")
+		if col, err := funnelValueColumn(w, data); err == nil && col < len(data.Header) && !counted {
+			return []int{col}
+		}
+		return nil
+	}
+	var names []string
+	if w.Aggregation != "count" && w.Aggregation != "count_distinct" {
+		names = append(names, w.ValueCol)
+	}
+	// The y_col of a heatmap labels its rows.
+	if w.Type != "heatmap" {
+		names = append(names, w.YCol)
+	}
+	names = append(names, w.YCols...)
+	if w.Type == "scatter" {
+		names = append(names, w.XCol)
+	}
+	// Candles without a volume are still drawn.
+	if w.Type == "candlestick" {
+		names = append(names, w.OpenCol, w.HighCol, w.LowCol, w.CloseCol)
+	}
+	var cols []int
+	for _, name := range names {
+		if col := data.ColumnIndex(name); name != "" && col != -1 {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// timeColumn returns the time column of a widget drawing its rows on a
+// time axis, leaving out those whose timestamp doesn't parse, or -1.
+func timeColumn(w *loader.WidgetConfig, data *loader.DataDataSource) int {
+	col := data.ColumnIndex(w.XCol)
+	switch w.Type {
+	case "candlestick":
+		return data.ColumnIndex(w.TimeCol)
+	case "calendar", "sparkline", "stat":
+		return col
+	case "line":
+		if data.IsTimeColumn(col) {
+			return col
+		}
+	case "bar":
+		// Time buckets are only used when explicitly requested; otherwise
+		// every row is its own bar.
+		if w.Bucket != "" && data.IsTimeColumn(col) {
+			return col
+		}
+	}
+	return -1
+}
+
+// showDataQuality adds to the title of each widget the number of rows it
+// leaves out because of missing or invalid numbers, of the values imputed
+// when loading them and the age of stale data, and summarizes them in the
+// dashboard title.
+func showDataQuality(c *container.Container, config *loader.Config, catalog *loader.Catalog, title *text.Text) error {
+	var dropped, imputed, affected, stale int
+	var oldest time.Duration
+	for i := range config.Widgets {
+		w := &config.Widgets[i]
+		src := catalog.Widget(w)
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		var times []int
+		if col := timeColumn(w, data); col != -1 {
+			times = append(times, col)
+		}
+		d, m := transform.Quality(data, numericColumns(w, data), times)
+		counts := qualityCounts(d, m)
+		if age, ok := src.Age(); ok {
+			counts = strings.TrimPrefix(counts+", stale "+formatAge(age), ", ")
+			stale++
+			oldest = max(oldest, age)
+		}
+		label, color := w.Title, cell.ColorDefault
+		if counts != "" {
+			label = fmt.Sprintf("%s [%s]", w.Title, counts)
+			color = cell.ColorYellow
+		}
+		if d > 0 || m > 0 {
+			affected++
+		}
+		dropped += d
+		imputed += m
+		if err := c.Update(widgetID(i), container.BorderTitle(label), container.TitleColor(color)); err != nil {
+			return err
+		}
+	}
+
+	summary, color := "data quality: ok", cell.ColorGreen
+	if affected > 0 {
+		summary = fmt.Sprintf("data quality: %s in %d widgets", qualityCounts(dropped, imputed), affected)
+		color = cell.ColorYellow
+	}
+	if stale > 0 {
+		summary += fmt.Sprintf(", stale data in %d widgets, up to %s old", stale, formatAge(oldest))
+		color = cell.ColorYellow
+	}
+	title.Reset()
+	if err := title.Write(config.Title, text.WriteCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+		return err
+	}
+	return title.Write("  "+summary, text.WriteCellOpts(cell.FgColor(color)))
+}
+
+// qualityCounts describes the rows dropped and the values imputed.
+func qualityCounts(dropped, imputed int) string {
+	var parts []string
+	if dropped > 0 {
+		parts = append(parts, fmt.Sprintf("%d dropped", dropped))
+	}
+	if imputed > 0 {
+		parts = append(parts, fmt.Sprintf("%d imputed", imputed))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge writes the age of data in its largest unit, such as "45s",
+// "12m", "3h" or "2d".
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(age.Hours()/24))
+}
+
+// parseYAMLFlag parses the YAML value of the --pivot, --melt or
+// --pagination flag, written like the settings of a source.
+func parseYAMLFlag(value string, out interface{}) error {
+	if value == "" {
+		return nil
+	}
+	return yaml.UnmarshalStrict([]byte(value), out)
+}
+
+// periodic executes the provided closure periodically every interval,
+// reporting its errors in the status line.
 func periodic(ctx context.Context, interval time.Duration, fn func() error) {
+	repeat(ctx, interval, fn, false)
+}
+
+// periodicNow is periodic, also executing the closure right away.
+func periodicNow(ctx context.Context, interval time.Duration, fn func() error) {
+	repeat(ctx, interval, fn, true)
+}
+
+func repeat(ctx context.Context, interval time.Duration, fn func() error, now bool) {
+	if now {
+		status.report(&fn, fn())
+	}
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			if err := fn(); err != nil {
-				panic(err)
-			}
+			status.report(&fn, fn())
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func createTable(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*widgets.Table, error) {
-	headers := make([]*widgets.Cell, len(csvData.Header))
-	for i, header := range csvData.Header {
-		headers[i] = widgets.NewCell(header)
+// status is the status line of the dashboard.
+var status = &statusLine{failing: make(map[interface{}]failure)}
+
+// statusLine shows the updates that are failing, or when all of them last
+// succeeded. Each update is identified by a key, such as the address of its
+// closure, so that its error is cleared once it succeeds again.
+type statusLine struct {
+	mu      sync.Mutex
+	text    *text.Text
+	failing map[interface{}]failure
+	ok      time.Time
+}
+
+// failure is the latest error of an update and when it started failing.
+type failure struct {
+	err   error
+	since time.Time
+}
+
+// show sets the widget the status line is written to.
+func (s *statusLine) show(t *text.Text) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.text = t
+	s.ok = time.Now()
+	s.draw()
+}
+
+// report records the outcome of an update, where a nil err is a success.
+func (s *statusLine) report(key interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failing, key)
+		if len(s.failing) == 0 {
+			s.ok = time.Now()
+		}
+	} else {
+		f, ok := s.failing[key]
+		if !ok {
+			f.since = time.Now()
+		}
+		f.err = err
+		s.failing[key] = f
 	}
+	s.draw()
+}
 
-	rows := make([][]*widgets.Cell, len(csvData.Records))
-	for i, record := range csvData.Records {
-		rows[i] = make([]*widgets.Cell, len(record))
-		for j, col := range record {
-			rows[i][j] = widgets.NewCell(col)
+// draw writes the status line, listing the oldest failures first.
+func (s *statusLine) draw() {
+	if s.text == nil {
+		return
+	}
+	s.text.Reset()
+	if len(s.failing) == 0 {
+		s.text.Write("ok, updated at "+s.ok.Format("15:04:05"), text.WriteCellOpts(cell.FgColor(cell.ColorGreen)))
+		return
+	}
+	failures := make([]failure, 0, len(s.failing))
+	for _, f := range s.failing {
+		failures = append(failures, f)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if !failures[i].since.Equal(failures[j].since) {
+			return failures[i].since.Before(failures[j].since)
+		}
+		return failures[i].err.Error() < failures[j].err.Error()
+	})
+	parts := make([]string, len(failures))
+	for i, f := range failures {
+		msg := strings.ReplaceAll(f.err.Error(), "\n", "; ")
+		parts[i] = fmt.Sprintf("%s (since %s)", msg, f.since.Format("15:04:05"))
+	}
+	s.text.Write(fmt.Sprintf("%d failing: %s", len(failures), strings.Join(parts, "; ")), text.WriteCellOpts(cell.FgColor(cell.ColorRed)))
+}
+
+// widgetData returns the rows a widget draws: the latest source data
+// narrowed by the widget's filter, then sorted and limited unless the
+// widget groups them.
+func widgetData(w *loader.WidgetConfig, src *loader.Dataset) (*loader.DataDataSource, error) {
+	data, err := transform.Filter(src.Data(), w.FilterExpr())
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	// Grouped and stacked bars are sorted and limited by label, see
+	// pivotBars.
+	if w.GroupBy != "" || (w.Type == "bar" && (w.SeriesBy != "" || w.StackBy != "")) {
+		return data, nil
+	}
+	if sortBy := sortColumn(w); sortBy != "" {
+		data = transform.SortRows(data, data.ColumnIndex(sortBy), w.Order == "desc")
+	}
+	return transform.LimitRows(data, w.Limit), nil
+}
+
+// sortColumn returns the column a widget is sorted by: sort_by, or its
+// value column when only the order is given.
+func sortColumn(w *loader.WidgetConfig) string {
+	switch {
+	case w.SortBy != "":
+		return w.SortBy
+	case w.Order == "":
+		return ""
+	case w.ValueCol != "":
+		return w.ValueCol
+	}
+	return w.YCol
+}
+
+// maxTimePoints caps the number of points of a time series without a bucket.
+const maxTimePoints = 240
+
+// validateBucket reports an invalid bucket size or aggregation when the
+// widget is created.
+func validateBucket(w *loader.WidgetConfig) error {
+	if w.Bucket == "" {
+		return nil
+	}
+	if _, err := series.ParseBucket(w.Bucket); err != nil {
+		return fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	if err := aggregate.ValidateStateless(w.Aggregation); err != nil {
+		return fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	return nil
+}
+
+// newAggregator returns the aggregator of a widget that summarizes its rows
+// in a single value, which by default is the last one.
+func newAggregator(w *loader.WidgetConfig) (*aggregate.Aggregator, error) {
+	name := w.Aggregation
+	if name == "" {
+		name = "last"
+	}
+	a, err := aggregate.New(name)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	return a, nil
+}
+
+// aggregateLabel formats an aggregated value after the name of its
+// aggregation, e.g. "P95: 120.50".
+func aggregateLabel(w *loader.WidgetConfig, v float64) string {
+	name := aggregate.Label(w.Aggregation)
+	if math.IsNaN(v) {
+		return fmt.Sprintf("%s: n/a", name)
+	}
+	unit := ""
+	if w.Aggregation == "rate" {
+		unit = "/s"
+	}
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%s: %.0f%s", name, v, unit)
+	}
+	return fmt.Sprintf("%s: %.2f%s", name, v, unit)
+}
+
+// columnCells returns the cells of a column.
+func columnCells(data *loader.DataDataSource, col int) []string {
+	cells := make([]string, len(data.Records))
+	for i, record := range data.Records {
+		cells[i] = record[col]
+	}
+	return cells
+}
+
+// timeSeries reads the value column against the time column and returns an
+// evenly spaced series with its time axis labels. With a bucket the values
+// are aggregated per bucket, otherwise they are interpolated on a round grid
+// so that irregular samples keep their real spacing.
+func timeSeries(w *loader.WidgetConfig, csvData *loader.DataDataSource, timeCol, valueCol int) ([]float64, map[int]string, error) {
+	var points []series.Point
+	for _, record := range csvData.Records {
+		t, err := csvData.ParseTime(timeCol, record[timeCol])
+		if err != nil {
+			continue
 		}
+		v, err := csvData.ParseNumber(valueCol, record[valueCol])
+		if err != nil {
+			continue
+		}
+		points = append(points, series.Point{Time: t, Value: v})
+	}
+	if len(points) == 0 {
+		return nil, nil, nil
+	}
+	series.Sort(points)
+
+	loc := csvData.Location(timeCol)
+	var grid series.Grid
+	var values []float64
+	if w.Bucket != "" {
+		step, err := series.ParseBucket(w.Bucket)
+		if err != nil {
+			return nil, nil, err
+		}
+		grid, values, err = series.Resample(points, step, loc, w.Aggregation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+	} else {
+		grid, values = series.Interpolate(points, loc, maxTimePoints)
+	}
+	return values, series.Labels(grid, len(values)), nil
+}
+
+// groupColumn returns the index of the widget's group_by column, or -1 when
+// the widget plots one value per row.
+func groupColumn(w *loader.WidgetConfig, csvData *loader.DataDataSource) (int, error) {
+	if w.GroupBy == "" {
+		return -1, nil
+	}
+	groupColIndex := csvData.ColumnIndex(w.GroupBy)
+	if groupColIndex == -1 {
+		return -1, fmt.Errorf("column '%s' not found for widget '%s'", w.GroupBy, w.Title)
+	}
+	if err := transform.ValidateAggregation(w.Aggregation); err != nil {
+		return -1, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	return groupColIndex, nil
+}
+
+// transformed applies the widget's transform pipeline to a series.
+func transformed(w *loader.WidgetConfig, values []float64) []float64 {
+	for _, t := range w.Transform {
+		values = series.Transform(t.Name, t.Param, values)
+	}
+	return values
+}
+
+// barValues rounds a series for the sparkline widget, which can't draw gaps
+// or negative values: both are drawn as zero. It also returns the largest
+// value, at least one.
+func barValues(values []float64) ([]int, int) {
+	rounded := make([]int, len(values))
+	max := 1
+	for i, v := range values {
+		if !math.IsNaN(v) && v > 0 {
+			rounded[i] = int(math.Round(v))
+		}
+		if rounded[i] > max {
+			max = rounded[i]
+		}
+	}
+	return rounded, max
+}
+
+// groupedValues aggregates the rows of a categorical widget per group,
+// keeps the top_n largest groups, then sorts and limits them.
+func groupedValues(w *loader.WidgetConfig, csvData *loader.DataDataSource, groupCol, labelCol, valueCol int) ([]transform.Group, error) {
+	groups, err := transform.GroupBy(csvData, groupCol, labelCol, valueCol, w.Aggregation)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	if groups, err = transform.TopN(csvData, groups, w.TopN, valueCol, w.Aggregation); err != nil {
+		return nil, err
+	}
+	return orderedGroups(w, csvData, groups, labelCol), nil
+}
+
+// orderedGroups sorts groups by sort_by and order, then keeps the first
+// limit of them.
+func orderedGroups(w *loader.WidgetConfig, csvData *loader.DataDataSource, groups []transform.Group, labelCol int) []transform.Group {
+	if w.SortBy != "" || w.Order != "" {
+		by := transform.ByValue
+		switch {
+		case w.SortBy != "" && w.SortBy == w.GroupBy:
+			by = transform.ByKey
+		case labelCol >= 0 && w.SortBy == csvData.Header[labelCol]:
+			by = transform.ByLabel
+		}
+		groups = transform.SortGroups(groups, by, w.Order == "desc")
+	}
+	return transform.LimitGroups(groups, w.Limit)
+}
+
+// pivotBars aggregates the rows of a grouped or stacked bar chart into a bar
+// for each label and a series for each value of splitCol. The labels are
+// kept, merged, sorted and limited by their totals like the groups of the
+// other charts, with top_n, sort_by, order and limit.
+func pivotBars(w *loader.WidgetConfig, data *loader.DataDataSource, labelCol, splitCol, valueCol int) ([]string, []string, [][]float64, error) {
+	groups, err := transform.GroupBy(data, labelCol, labelCol, valueCol, w.Aggregation)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	top, err := transform.TopN(data, groups, w.TopN, valueCol, w.Aggregation)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	if w.TopN > 0 && len(groups) > w.TopN {
+		// The rows of the labels left out of the top ones make the bar of
+		// the other labels.
+		kept := make(map[string]bool, len(top))
+		for _, g := range top[:len(top)-1] {
+			kept[g.Key] = true
+		}
+		records := make([][]string, len(data.Records))
+		for i, record := range data.Records {
+			records[i] = record
+			if !kept[record[labelCol]] {
+				records[i] = slices.Clone(record)
+				records[i][labelCol] = transform.OtherLabel
+			}
+		}
+		data = data.WithRecords(records)
+	}
+	labels, names, values, err := transform.Pivot(data, labelCol, splitCol, valueCol, w.Aggregation)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+
+	index := make(map[string]int, len(labels))
+	for i, label := range labels {
+		index[label] = i
+	}
+	ordered := orderedGroups(w, data, top, labelCol)
+	shown := make([]string, len(ordered))
+	for i, g := range ordered {
+		shown[i] = g.Key
+	}
+	for s := range values {
+		row := make([]float64, len(ordered))
+		for i, g := range ordered {
+			row[i] = values[s][index[g.Key]]
+		}
+		values[s] = row
+	}
+	return shown, names, values, nil
+}
+
+func createTable(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Table, error) {
+	data, err := widgetData(w, src)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]*widgets.Cell, len(data.Header))
+	for i, header := range data.Header {
+		headers[i] = widgets.NewCell(header)
 	}
 
 	opts := []widgets.TableOption{
@@ -334,15 +906,37 @@ func createTable(ctx context.Context, w *loader.WidgetConfig, csvData *loader.Da
 		widgets.HeaderFillColor(cell.ColorBlack),
 	}
 
-	table, err := widgets.NewTable(headers, rows, opts...)
+	table, err := widgets.NewTable(headers, tableRows(data), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("error creating table: %w", err)
 	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		return table.SetRows(tableRows(data))
+	})
+
 	return table, nil
 }
 
+// tableRows converts the records of the data to table cells.
+func tableRows(data *loader.DataDataSource) [][]*widgets.Cell {
+	rows := make([][]*widgets.Cell, len(data.Records))
+	for i, record := range data.Records {
+		rows[i] = make([]*widgets.Cell, len(record))
+		for j, col := range record {
+			rows[i][j] = widgets.NewCell(col)
+		}
+	}
+	return rows
+}
+
 // createHistogram creates and starts a new histogram widget with alerting.
-func createHistogram(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*widgets.Histogram, error) {
+func createHistogram(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Histogram, error) {
+	csvData := src.Data()
 	valueColIndex := -1
 	for i, header := range csvData.Header {
 		if header == w.ValueCol {
@@ -377,10 +971,14 @@ func createHistogram(ctx context.Context, w *loader.WidgetConfig, csvData *loade
 	h.SetAlertColor(alertColor)
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
 		var values []float64
 		min, max := 0.0, 0.0
-		for i, record := range csvData.Records {
-			v, err := strconv.ParseFloat(record[valueColIndex], 64)
+		for i, record := range data.Records {
+			v, err := data.ParseNumber(valueColIndex, record[valueColIndex])
 			if err != nil {
 				continue
 			}
@@ -416,8 +1014,10 @@ func createHistogram(ctx context.Context, w *loader.WidgetConfig, csvData *loade
 	})
 	return h, nil
 }
+
 // createScatterPlot creates and starts a new scatter plot widget.
-func createScatterPlot(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*widgets.ScatterPlot, error) {
+func createScatterPlot(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.ScatterPlot, error) {
+	csvData := src.Data()
 	xColIndex, yColIndex := -1, -1
 	for i, header := range csvData.Header {
 		if header == w.XCol {
@@ -437,10 +1037,14 @@ func createScatterPlot(ctx context.Context, w *loader.WidgetConfig, csvData *loa
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
 		var points []widgets.ScatterPoint
-		for _, record := range csvData.Records {
-			x, err1 := strconv.ParseFloat(record[xColIndex], 64)
-			y, err2 := strconv.ParseFloat(record[yColIndex], 64)
+		for _, record := range data.Records {
+			x, err1 := data.ParseNumber(xColIndex, record[xColIndex])
+			y, err2 := data.ParseNumber(yColIndex, record[yColIndex])
 			if err1 != nil || err2 != nil {
 				continue
 			}
@@ -452,7 +1056,8 @@ func createScatterPlot(ctx context.Context, w *loader.WidgetConfig, csvData *loa
 }
 
 // createSparkline creates and starts a new sparkline widget.
-func createSparkline(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*sparkline.SparkLine, error) {
+func createSparkline(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*sparkline.SparkLine, error) {
+	csvData := src.Data()
 	valueColIndex := -1
 	for i, header := range csvData.Header {
 		if header == w.ValueCol {
@@ -464,27 +1069,54 @@ func createSparkline(ctx context.Context, w *loader.WidgetConfig, csvData *loade
 		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
 	}
 
+	// An optional time column in x_col resamples the values on a time axis.
+	timeColIndex := csvData.ColumnIndex(w.XCol)
+	if w.XCol != "" && !csvData.IsTimeColumn(timeColIndex) {
+		return nil, fmt.Errorf("column '%s' is not a time column for widget '%s'", w.XCol, w.Title)
+	}
+	if err := validateBucket(w); err != nil {
+		return nil, err
+	}
+	for _, t := range w.Transform {
+		if series.Signed(t.Name) {
+			return nil, fmt.Errorf("widget '%s': sparklines can't draw the negative values of the '%s' transform", w.Title, t.Name)
+		}
+	}
+
 	sp, err := sparkline.New(sparkline.Color(cell.ColorGreen))
 	if err != nil {
 		return nil, err
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		var values []int
-		for _, record := range csvData.Records {
-			val, err := strconv.Atoi(record[valueColIndex])
-			if err != nil {
-				continue
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		var values []float64
+		if timeColIndex != -1 {
+			if values, _, err = timeSeries(w, data, timeColIndex, valueColIndex); err != nil {
+				return err
+			}
+		} else {
+			for _, record := range data.Records {
+				val, err := data.ParseNumber(valueColIndex, record[valueColIndex])
+				if err != nil {
+					continue
+				}
+				values = append(values, val)
 			}
-			values = append(values, val)
 		}
-		return sp.Add(values)
+		bars, _ := barValues(transformed(w, values))
+		sp.Clear()
+		return sp.Add(bars)
 	})
 	return sp, nil
 }
 
 // createGauge creates and starts a new gauge widget.
-func createGauge(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*gauge.Gauge, error) {
+func createGauge(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*gauge.Gauge, error) {
+	csvData := src.Data()
 	valueColIndex := -1
 	for i, header := range csvData.Header {
 		if header == w.ValueCol {
@@ -496,96 +1128,41 @@ func createGauge(ctx context.Context, w *loader.WidgetConfig, csvData *loader.Da
 		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
 	}
 
+	aggregator, err := newAggregator(w)
+	if err != nil {
+		return nil, err
+	}
+
 	g, err := gauge.New()
 	if err != nil {
 		return nil, err
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		var values []int
-		for _, record := range csvData.Records {
-			val, err := strconv.Atoi(record[valueColIndex])
-			if err == nil {
-				values = append(values, val)
-			}
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
 		}
-
+		parse := func(s string) (float64, error) {
+			return data.ParseNumber(valueColIndex, s)
+		}
+		cells := columnCells(data, valueColIndex)
+		values := aggregate.Numbers(cells, parse)
 		if len(values) == 0 {
 			return nil // No data to display
 		}
+		value := aggregator.Cells(cells, parse, time.Now())
 
-		var finalValue int
-		var label string
-
-		// if aggregation is defined, use it
-		if w.Aggregation != "" {
-			switch w.Aggregation {
-			case "sum":
-				sum := 0
-				for _, v := range values {
-					sum += v
-				}
-				finalValue = sum
-				label = fmt.Sprintf("Sum: %d", finalValue)
-			case "avg":
-				sum := 0
-				for _, v := range values {
-					sum += v
-				}
-				avg := float64(sum) / float64(len(values))
-				finalValue = int(avg)
-				label = fmt.Sprintf("Avg: %.2f", avg)
-			case "median":
-				sort.Ints(values)
-				if len(values)%2 == 0 {
-					finalValue = (values[len(values)/2-1] + values[len(values)/2]) / 2
-				} else {
-					finalValue = values[len(values)/2]
-				}
-				label = fmt.Sprintf("Median: %d", finalValue)
-			case "max":
-				max := values[0]
-				for _, v := range values {
-					if v > max {
-						max = v
-					}
-				}
-				finalValue = max
-				label = fmt.Sprintf("Max: %d", finalValue)
-			case "min":
-				min := values[0]
-				for _, v := range values {
-					if v < min {
-						min = v
-					}
-				}
-				finalValue = min
-				label = fmt.Sprintf("Min: %d", finalValue)
-			default:
-				finalValue = 0 // Or handle as an error
-				label = "Invalid aggregation"
-			}
-		} else {
-			// Fallback to original behavior: show the last value
-			finalValue = values[len(values)-1]
-			label = fmt.Sprintf("Value: %d", finalValue)
-		}
-
-		maxValue := w.MaxValue
+		maxValue := float64(w.MaxValue)
 		if maxValue == 0 {
 			// If MaxValue is not set in config, use the max value from the dataset
 			// as a sensible default for percentage calculation.
-			maxValue = 0
-			for _, v := range values {
-				if v > maxValue {
-					maxValue = v
-				}
-			}
+			maxValue, _ = aggregate.Reduce("max", values)
 		}
 
 		var percent int
-		if maxValue > 0 {
-			percent = (finalValue * 100) / maxValue
+		if maxValue > 0 && !math.IsNaN(value) {
+			percent = int(value * 100 / maxValue)
 		}
 
 		// Ensure percent is within 0-100 range
@@ -595,57 +1172,222 @@ func createGauge(ctx context.Context, w *loader.WidgetConfig, csvData *loader.Da
 			percent = 0
 		}
 
-		return g.Percent(percent, gauge.Label(label, cell.FgColor(cell.ColorGreen)))
+		return g.Percent(percent, gauge.TextLabel(aggregateLabel(w, value)), gauge.FilledTextColor(cell.ColorBlack), gauge.EmptyTextColor(cell.ColorGreen))
 	})
 
 	return g, nil
 }
 
-// createLineChart creates and starts a new line chart widget.
-func createLineChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*linechart.LineChart, error) {
-	xColIndex, yColIndex := -1, -1
-	for i, header := range csvData.Header {
-		if header == w.XCol {
-			xColIndex = i
+// createLineChart creates and starts a new line chart widget. It draws
+// y_col, each of y_cols, or y_col split by the values of series_by.
+func createLineChart(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.LineChart, error) {
+	csvData := src.Data()
+	xColIndex := csvData.ColumnIndex(w.XCol)
+	if xColIndex == -1 {
+		return nil, fmt.Errorf("column 'x_col' or 'y_col' not found for widget '%s'", w.Title)
+	}
+	yNames := w.YCols
+	switch {
+	case len(yNames) == 0:
+		yNames = []string{w.YCol}
+	case w.YCol != "":
+		return nil, fmt.Errorf("widget '%s' can't set both y_col and y_cols", w.Title)
+	}
+	yCols := make([]int, len(yNames))
+	for i, name := range yNames {
+		if yCols[i] = csvData.ColumnIndex(name); yCols[i] == -1 {
+			return nil, fmt.Errorf("column 'x_col' or 'y_col' not found for widget '%s'", w.Title)
 		}
-		if header == w.YCol {
-			yColIndex = i
+	}
+	seriesCol := -1
+	if w.SeriesBy != "" {
+		if len(yCols) > 1 {
+			return nil, fmt.Errorf("widget '%s' can't split several y_cols by series_by", w.Title)
+		}
+		if seriesCol = csvData.ColumnIndex(w.SeriesBy); seriesCol == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.SeriesBy, w.Title)
 		}
 	}
-	if xColIndex == -1 || yColIndex == -1 {
-		return nil, fmt.Errorf("column 'x_col' or 'y_col' not found for widget '%s'", w.Title)
+	for _, name := range w.SecondaryY {
+		if seriesCol == -1 && !slices.Contains(yNames, name) {
+			return nil, fmt.Errorf("secondary_y series '%s' is not in y_cols for widget '%s'", name, w.Title)
+		}
+	}
+
+	if err := validateBucket(w); err != nil {
+		return nil, err
 	}
+	isTime := timeColumn(w, csvData) != -1
 
-	lc, err := linechart.New(
-		linechart.AxesCellOpts(cell.FgColor(cell.ColorRed)),
-		linechart.YLabelCellOpts(cell.FgColor(cell.ColorGreen)),
-		linechart.XLabelCellOpts(cell.FgColor(cell.ColorGreen)),
-	)
+	var opts []widgets.LineChartOption
+	if w.Legend != "" {
+		legend, err := widgets.ParseLegendPlacement(w.Legend)
+		if err != nil {
+			return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		opts = append(opts, widgets.LineLegend(legend))
+	}
+	lc, err := widgets.NewLineChart(opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		var inputs []float64
-		xLabels := make(map[int]string)
-		for i, record := range csvData.Records {
-			val, err := strconv.ParseFloat(record[yColIndex], 64)
-			if err != nil {
-				continue
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		names, values, xLabels, err := lineSeries(w, data, isTime, xColIndex, yCols, seriesCol)
+		if err != nil || len(names) == 0 {
+			return err
+		}
+		lines := make([]widgets.LineSeries, len(names))
+		for i, name := range names {
+			lines[i] = widgets.LineSeries{
+				Name:      name,
+				Values:    transformed(w, values[i]),
+				Color:     widgets.Palette[i%len(widgets.Palette)],
+				Secondary: slices.Contains(w.SecondaryY, name),
 			}
-			inputs = append(inputs, val)
-			xLabels[i] = record[xColIndex]
 		}
-		return lc.Series(w.Title, inputs,
-			linechart.SeriesCellOpts(cell.FgColor(cell.ColorNumber(42))),
-			linechart.SeriesXLabels(xLabels),
-		)
+		// A single column keeps the name of the widget, as it has no legend.
+		if len(lines) == 1 && seriesCol == -1 {
+			lines[0].Name = w.Title
+		}
+		return lc.SetSeries(lines, xLabels)
 	})
 	return lc, nil
 }
 
-// createBarChart creates and starts a new bar chart widget.
-func createBarChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*barchart.BarChart, error) {
+// lineSeries returns the names and values of the series of a line widget
+// and their X axis labels. On a time axis the series share a common grid;
+// otherwise they share the rows, or the X values when split by series_by.
+// Missing values are NaN.
+func lineSeries(w *loader.WidgetConfig, data *loader.DataDataSource, isTime bool, xCol int, yCols []int, seriesCol int) ([]string, [][]float64, map[int]string, error) {
+	type set struct {
+		name    string
+		yCol    int
+		records [][]string
+	}
+	var sets []set
+	if seriesCol != -1 {
+		index := make(map[string]int)
+		for _, record := range data.Records {
+			// Rows without a series name belong to no series.
+			key := strings.TrimSpace(record[seriesCol])
+			if key == "" {
+				continue
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(sets)
+				index[key] = i
+				sets = append(sets, set{name: key, yCol: yCols[0]})
+			}
+			sets[i].records = append(sets[i].records, record)
+		}
+	} else {
+		for _, col := range yCols {
+			sets = append(sets, set{name: data.Header[col], yCol: col, records: data.Records})
+		}
+	}
+	names := make([]string, len(sets))
+	for i, s := range sets {
+		names[i] = s.name
+	}
+
+	if isTime {
+		points := make([][]series.Point, len(sets))
+		for i, s := range sets {
+			for _, record := range s.records {
+				t, err := data.ParseTime(xCol, record[xCol])
+				if err != nil {
+					continue
+				}
+				v, err := data.ParseNumber(s.yCol, record[s.yCol])
+				if err != nil {
+					continue
+				}
+				points[i] = append(points[i], series.Point{Time: t, Value: v})
+			}
+			series.Sort(points[i])
+		}
+		loc := data.Location(xCol)
+		var grid series.Grid
+		var values [][]float64
+		if w.Bucket != "" {
+			step, err := series.ParseBucket(w.Bucket)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if grid, values, err = series.ResampleAll(points, step, loc, w.Aggregation); err != nil {
+				return nil, nil, nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+			}
+		} else {
+			grid, values = series.InterpolateAll(points, loc, maxTimePoints)
+		}
+		if len(values) == 0 || len(values[0]) == 0 {
+			return nil, nil, nil, nil
+		}
+		return names, values, series.Labels(grid, len(values[0])), nil
+	}
+
+	values := make([][]float64, len(sets))
+	xLabels := make(map[int]string)
+	if seriesCol == -1 {
+		// Each row is a point of every series, and rows without any valid
+		// value are left out.
+		for _, record := range data.Records {
+			row := make([]float64, len(sets))
+			valid := false
+			for i, s := range sets {
+				v, err := data.ParseNumber(s.yCol, record[s.yCol])
+				if err != nil {
+					v = math.NaN()
+				} else {
+					valid = true
+				}
+				row[i] = v
+			}
+			if !valid {
+				continue
+			}
+			xLabels[len(values[0])] = record[xCol]
+			for i, v := range row {
+				values[i] = append(values[i], v)
+			}
+		}
+		return names, values, xLabels, nil
+	}
+
+	// Split series are aligned on the X values, in order of appearance.
+	xIndex := make(map[string]int)
+	for _, record := range data.Records {
+		x := record[xCol]
+		if _, ok := xIndex[x]; !ok {
+			xLabels[len(xIndex)] = x
+			xIndex[x] = len(xIndex)
+		}
+	}
+	for i, s := range sets {
+		values[i] = make([]float64, len(xIndex))
+		for j := range values[i] {
+			values[i][j] = math.NaN()
+		}
+		for _, record := range s.records {
+			if v, err := data.ParseNumber(s.yCol, record[s.yCol]); err == nil {
+				values[i][xIndex[record[xCol]]] = v
+			}
+		}
+	}
+	return names, values, xLabels, nil
+}
+
+// createBarChart creates and starts a new bar chart widget. With series_by
+// or stack_by, each label of x_col, or group of group_by, gets a bar for each
+// value of that column, side by side or stacked.
+func createBarChart(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.BarChart, error) {
+	csvData := src.Data()
 	xColIndex, yColIndex := -1, -1
 	for i, header := range csvData.Header {
 		if header == w.XCol {
@@ -659,32 +1401,618 @@ func createBarChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader
 		return nil, fmt.Errorf("column 'x_col' or 'y_col' not found for widget '%s'", w.Title)
 	}
 
-	bc, err := barchart.New(
-		barchart.ShowValues(),
-		// Questa è la riga corretta che fornisce un slice di colori.
-		barchart.BarColors([]cell.Color{cell.ColorNumber(42)}),
-	)
+	if err := validateBucket(w); err != nil {
+		return nil, err
+	}
+	isTime := timeColumn(w, csvData) != -1
+	groupColIndex, err := groupColumn(w, csvData)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []widgets.BarChartOption
+	splitBy := w.SeriesBy
+	if w.StackBy != "" {
+		if splitBy != "" {
+			return nil, fmt.Errorf("widget '%s' can't set both series_by and stack_by", w.Title)
+		}
+		splitBy = w.StackBy
+		opts = append(opts, widgets.BarStacked())
+	}
+	splitColIndex := -1
+	if splitBy != "" {
+		if splitColIndex = csvData.ColumnIndex(splitBy); splitColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", splitBy, w.Title)
+		}
+		if isTime {
+			return nil, fmt.Errorf("widget '%s' can't split time buckets into series", w.Title)
+		}
+		if err := transform.ValidateAggregation(w.Aggregation); err != nil {
+			return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+	}
+	switch w.Orientation {
+	case "", "vertical":
+	case "horizontal":
+		opts = append(opts, widgets.BarHorizontal())
+	default:
+		return nil, fmt.Errorf("unsupported orientation '%s' for widget '%s', use vertical or horizontal", w.Orientation, w.Title)
+	}
+	if w.MaxValue > 0 {
+		opts = append(opts, widgets.BarMax(float64(w.MaxValue)))
+	}
+	if w.Legend != "" {
+		legend, err := widgets.ParseLegendPlacement(w.Legend)
+		if err != nil {
+			return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		opts = append(opts, widgets.BarLegend(legend))
+	}
+
+	bc, err := widgets.NewBarChart(opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		var values []int
-		for _, record := range csvData.Records {
-			val, err := strconv.Atoi(record[yColIndex])
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		single := func(values []float64, labels []string) error {
+			return bc.Values(labels, []widgets.BarSeries{
+				{Name: w.Title, Values: transformed(w, values), Color: widgets.Palette[0]},
+			})
+		}
+
+		if splitColIndex != -1 {
+			labelCol := xColIndex
+			if groupColIndex != -1 {
+				labelCol = groupColIndex
+			}
+			labels, names, values, err := pivotBars(w, data, labelCol, splitColIndex, yColIndex)
+			if err != nil {
+				return err
+			}
+			bars := make([]widgets.BarSeries, len(names))
+			for i, name := range names {
+				bars[i] = widgets.BarSeries{
+					Name:   name,
+					Values: transformed(w, values[i]),
+					Color:  widgets.Palette[i%len(widgets.Palette)],
+				}
+			}
+			return bc.Values(labels, bars)
+		}
+
+		if isTime {
+			values, xLabels, err := timeSeries(w, data, xColIndex, yColIndex)
+			if err != nil || len(values) == 0 {
+				return err
+			}
+			labels := make([]string, len(values))
+			for i := range labels {
+				labels[i] = xLabels[i]
+			}
+			return single(values, labels)
+		}
+
+		if groupColIndex != -1 {
+			groups, err := groupedValues(w, data, groupColIndex, xColIndex, yColIndex)
+			if err != nil || len(groups) == 0 {
+				return err
+			}
+			values := make([]float64, len(groups))
+			labels := make([]string, len(groups))
+			for i, g := range groups {
+				values[i] = g.Value
+				labels[i] = g.Label
+			}
+			return single(values, labels)
+		}
+
+		var values []float64
+		var labels []string
+		for _, record := range data.Records {
+			val, err := data.ParseNumber(yColIndex, record[yColIndex])
 			if err != nil {
 				continue
 			}
 			values = append(values, val)
+			labels = append(labels, record[xColIndex])
 		}
-		return bc.Values(values, 100)
+		return single(values, labels)
 	})
 
 	return bc, nil
 }
 
+// createHeatmap creates and starts a new heatmap widget, with a column for
+// each value of x_col and a row for each value of y_col, colored by the
+// aggregation of value_col over their rows.
+func createHeatmap(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Heatmap, error) {
+	csvData := src.Data()
+	xColIndex, yColIndex := csvData.ColumnIndex(w.XCol), csvData.ColumnIndex(w.YCol)
+	if xColIndex == -1 || yColIndex == -1 {
+		return nil, fmt.Errorf("column 'x_col' or 'y_col' not found for widget '%s'", w.Title)
+	}
+	valueColIndex := -1
+	if w.ValueCol != "" || w.Aggregation != "count" {
+		if valueColIndex = csvData.ColumnIndex(w.ValueCol); valueColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
+		}
+	}
+	if err := transform.ValidateAggregation(w.Aggregation); err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	gradient, err := widgets.ParseGradient(w.Colors)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+
+	hm, err := widgets.NewHeatmap(widgets.HeatmapGradient(gradient))
+	if err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		columns, rows, values, err := transform.Pivot(data, xColIndex, yColIndex, valueColIndex, w.Aggregation)
+		if err != nil {
+			return fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		colOrder, rowOrder := numericOrder(columns), numericOrder(rows)
+		xLabels := make([]string, len(columns))
+		for i, c := range colOrder {
+			xLabels[i] = columns[c]
+		}
+		yLabels := make([]string, len(rows))
+		grid := make([][]float64, len(rows))
+		for i, r := range rowOrder {
+			yLabels[i] = rows[r]
+			grid[i] = make([]float64, len(columns))
+			for j, c := range colOrder {
+				grid[i][j] = values[r][c]
+			}
+		}
+		return hm.Values(xLabels, yLabels, grid)
+	})
+	return hm, nil
+}
+
+// createCalendar creates and starts a new calendar widget, drawing the
+// aggregation of value_col over the rows of each day of x_col, a sum by
+// default. Without a value column it counts the rows.
+func createCalendar(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Calendar, error) {
+	csvData := src.Data()
+	timeColIndex := csvData.ColumnIndex(w.XCol)
+	if timeColIndex == -1 || !csvData.IsTimeColumn(timeColIndex) {
+		return nil, fmt.Errorf("column '%s' is not a time column for widget '%s'", w.XCol, w.Title)
+	}
+	valueColIndex := -1
+	if w.ValueCol != "" {
+		if valueColIndex = csvData.ColumnIndex(w.ValueCol); valueColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
+		}
+	}
+	agg := w.Aggregation
+	if agg == "" {
+		agg = "sum"
+	}
+	if err := aggregate.ValidateStateless(agg); err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+
+	var opts []widgets.CalendarOption
+	if len(w.Colors) > 0 {
+		gradient, err := widgets.ParseGradient(w.Colors)
+		if err != nil {
+			return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		opts = append(opts, widgets.CalendarGradient(gradient))
+	}
+	cal, err := widgets.NewCalendar(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		var points []series.Point
+		for _, record := range data.Records {
+			t, err := data.ParseTime(timeColIndex, record[timeColIndex])
+			if err != nil {
+				continue
+			}
+			v := 1.0
+			if valueColIndex != -1 {
+				if v, err = data.ParseNumber(valueColIndex, record[valueColIndex]); err != nil {
+					continue
+				}
+			}
+			points = append(points, series.Point{Time: t, Value: v})
+		}
+		series.Sort(points)
+		grid, values, err := series.Resample(points, 24*time.Hour, data.Location(timeColIndex), agg)
+		if err != nil {
+			return fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		return cal.Values(grid.Start, values)
+	})
+	return cal, nil
+}
+
+// createCandlestick creates and starts a new candlestick widget, drawing a
+// candle per row of time_col or, with a bucket, per bucket: the first open,
+// the highest high, the lowest low, the last close and the total volume.
+// Rows with a high below their low are skipped.
+func createCandlestick(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Candlestick, error) {
+	csvData := src.Data()
+	timeColIndex := csvData.ColumnIndex(w.TimeCol)
+	if timeColIndex == -1 || !csvData.IsTimeColumn(timeColIndex) {
+		return nil, fmt.Errorf("column '%s' is not a time column for widget '%s'", w.TimeCol, w.Title)
+	}
+	var priceCols [4]int
+	for i, name := range []string{w.OpenCol, w.HighCol, w.LowCol, w.CloseCol} {
+		if priceCols[i] = csvData.ColumnIndex(name); priceCols[i] == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", name, w.Title)
+		}
+	}
+	volumeColIndex := -1
+	if w.VolumeCol != "" {
+		if volumeColIndex = csvData.ColumnIndex(w.VolumeCol); volumeColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.VolumeCol, w.Title)
+		}
+	}
+	var step time.Duration
+	if w.Bucket != "" {
+		var err error
+		if step, err = series.ParseBucket(w.Bucket); err != nil {
+			return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+	}
+
+	var opts []widgets.CandlestickOption
+	if volumeColIndex != -1 {
+		opts = append(opts, widgets.CandleVolume())
+	}
+	if w.UpColor != 0 || w.DownColor != 0 {
+		up, down := cell.ColorNumber(42), cell.ColorNumber(196)
+		if w.UpColor != 0 {
+			up = cell.ColorNumber(w.UpColor)
+		}
+		if w.DownColor != 0 {
+			down = cell.ColorNumber(w.DownColor)
+		}
+		opts = append(opts, widgets.CandleColors(up, down))
+	}
+	cs, err := widgets.NewCandlestick(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		var candles []series.Candle
+	rows:
+		for _, record := range data.Records {
+			t, err := data.ParseTime(timeColIndex, record[timeColIndex])
+			if err != nil {
+				continue
+			}
+			var prices [4]float64
+			for i, col := range priceCols {
+				if prices[i], err = data.ParseNumber(col, record[col]); err != nil {
+					continue rows
+				}
+			}
+			// A row with its high below its low is a bad export, left out
+			// rather than failing the whole update.
+			if prices[1] < prices[2] {
+				continue
+			}
+			volume := math.NaN()
+			if volumeColIndex != -1 {
+				if v, err := data.ParseNumber(volumeColIndex, record[volumeColIndex]); err == nil {
+					volume = v
+				}
+			}
+			candles = append(candles, series.Candle{
+				Time: t, Open: prices[0], High: prices[1], Low: prices[2], Close: prices[3], Volume: volume,
+			})
+		}
+		series.SortCandles(candles)
+
+		loc := data.Location(timeColIndex)
+		var labels []string
+		if step > 0 {
+			var grid series.Grid
+			if grid, candles, err = series.ResampleCandles(candles, step, loc); err != nil {
+				return fmt.Errorf("widget '%s': %w", w.Title, err)
+			}
+			labels = make([]string, len(candles))
+			for i, label := range series.Labels(grid, len(candles)) {
+				labels[i] = label
+			}
+		} else {
+			times := make([]time.Time, len(candles))
+			for i, c := range candles {
+				times[i] = c.Time
+			}
+			labels = series.TimeLabels(times, loc)
+		}
+		drawn := make([]widgets.Candle, len(candles))
+		for i, c := range candles {
+			drawn[i] = widgets.Candle{
+				Label: labels[i], Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Volume: c.Volume,
+			}
+		}
+		if err := cs.Candles(drawn); err != nil {
+			return fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		return nil
+	})
+	return cs, nil
+}
+
+// createTreemap creates and starts a new treemap widget, nesting the rows by
+// the values of path_cols and sizing each leaf by the aggregation of
+// value_col over its rows, a sum by default. Without a value column it
+// counts the rows.
+func createTreemap(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Treemap, error) {
+	csvData := src.Data()
+	if len(w.PathCols) == 0 {
+		return nil, fmt.Errorf("widget '%s' needs at least one column in path_cols", w.Title)
+	}
+	pathCols := make([]int, len(w.PathCols))
+	for i, name := range w.PathCols {
+		if pathCols[i] = csvData.ColumnIndex(name); pathCols[i] == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", name, w.Title)
+		}
+	}
+	valueColIndex := -1
+	if w.ValueCol != "" {
+		if valueColIndex = csvData.ColumnIndex(w.ValueCol); valueColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
+		}
+	}
+	agg := w.Aggregation
+	if agg == "" {
+		agg = "sum"
+	}
+	// The value of a parent is the sum of its children, which only makes
+	// sense for aggregations that add up.
+	if agg != "sum" && agg != "count" {
+		return nil, fmt.Errorf("widget '%s': treemaps need a sum or a count, not '%s', since parents add up their children", w.Title, agg)
+	}
+
+	tm, err := widgets.NewTreemap()
+	if err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		root, err := treemapTree(data, pathCols, valueColIndex, agg)
+		if err != nil {
+			return fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		return tm.Root(root)
+	})
+	return tm, nil
+}
+
+// treemapTree nests the rows by the values of the path columns and sets the
+// value of each leaf to the aggregation of the value column over its rows,
+// or of ones without a value column. Rows with an invalid value are left
+// out, and empty path values are named "(empty)".
+func treemapTree(data *loader.DataDataSource, pathCols []int, valueCol int, agg string) (*widgets.TreemapNode, error) {
+	root := &widgets.TreemapNode{}
+	children := map[*widgets.TreemapNode]map[string]*widgets.TreemapNode{}
+	var leaves []*widgets.TreemapNode
+	values := map[*widgets.TreemapNode][]float64{}
+	for _, record := range data.Records {
+		v := 1.0
+		if valueCol != -1 {
+			var err error
+			if v, err = data.ParseNumber(valueCol, record[valueCol]); err != nil {
+				continue
+			}
+		}
+		node := root
+		for depth, col := range pathCols {
+			name := strings.TrimSpace(record[col])
+			if name == "" {
+				name = "(empty)"
+			}
+			if children[node] == nil {
+				children[node] = map[string]*widgets.TreemapNode{}
+			}
+			child, ok := children[node][name]
+			if !ok {
+				child = &widgets.TreemapNode{Name: name}
+				children[node][name] = child
+				node.Children = append(node.Children, child)
+				if depth == len(pathCols)-1 {
+					leaves = append(leaves, child)
+				}
+			}
+			node = child
+		}
+		values[node] = append(values[node], v)
+	}
+	for _, leaf := range leaves {
+		v, err := aggregate.Reduce(agg, values[leaf])
+		if err != nil {
+			return nil, err
+		}
+		leaf.Value = v
+	}
+	return root, nil
+}
+
+// createBoxplot creates and starts a new boxplot widget, drawing the
+// distribution of value_col for each value of cat_col, in the order they
+// first appear, or of the whole column without a category column.
+func createBoxplot(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Boxplot, error) {
+	csvData := src.Data()
+	valueColIndex := csvData.ColumnIndex(w.ValueCol)
+	if valueColIndex == -1 {
+		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
+	}
+	catColIndex := -1
+	if w.CatCol != "" {
+		if catColIndex = csvData.ColumnIndex(w.CatCol); catColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.CatCol, w.Title)
+		}
+	}
+	var opts []widgets.BoxplotOption
+	switch w.Orientation {
+	case "", "horizontal":
+	case "vertical":
+		opts = append(opts, widgets.BoxplotVertical())
+	default:
+		return nil, fmt.Errorf("unsupported orientation '%s' for widget '%s', use horizontal or vertical", w.Orientation, w.Title)
+	}
+	bp, err := widgets.NewBoxplot(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		var categories []string
+		values := map[string][]float64{}
+		for _, record := range data.Records {
+			v, err := data.ParseNumber(valueColIndex, record[valueColIndex])
+			if err != nil {
+				continue
+			}
+			category := w.ValueCol
+			if catColIndex != -1 {
+				category = record[catColIndex]
+			}
+			if _, ok := values[category]; !ok {
+				categories = append(categories, category)
+			}
+			values[category] = append(values[category], v)
+		}
+		var boxes []widgets.BoxplotBox
+		for _, i := range numericOrder(categories) {
+			b, ok := aggregate.Boxplot(values[categories[i]])
+			if !ok {
+				continue
+			}
+			boxes = append(boxes, widgets.BoxplotBox{
+				Label: categories[i],
+				Q1:    b.Q1, Median: b.Median, Q3: b.Q3,
+				Low: b.Low, High: b.High,
+				Outliers: b.Outliers,
+			})
+		}
+		return bp.Boxes(boxes)
+	})
+	return bp, nil
+}
+
+// createLog creates and starts a new log widget, writing the newest rows
+// with the template, by default their cells separated by spaces, and
+// coloring them by level_col.
+func createLog(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Log, error) {
+	csvData := src.Data()
+	text := w.Template
+	if text == "" {
+		names := make([]string, len(csvData.Header))
+		for i, name := range csvData.Header {
+			names[i] = "{" + name + "}"
+		}
+		text = strings.Join(names, "  ")
+	}
+	tmpl, err := transform.ParseTemplate(text, csvData)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	levelColIndex := -1
+	if w.LevelCol != "" {
+		if levelColIndex = csvData.ColumnIndex(w.LevelCol); levelColIndex == -1 {
+			return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.LevelCol, w.Title)
+		}
+	}
+	var opts []widgets.LogOption
+	if w.MaxLines != 0 {
+		opts = append(opts, widgets.LogMaxLines(w.MaxLines))
+	}
+	lg, err := widgets.NewLog(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	maxLines := w.MaxLines
+	if maxLines == 0 {
+		maxLines = widgets.DefaultLogMaxLines
+	}
+
+	update := func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		records := data.Records[max(0, len(data.Records)-maxLines):]
+		lines := make([]widgets.LogLine, len(records))
+		for i, record := range records {
+			lines[i].Text = tmpl.Format(record)
+			if levelColIndex != -1 {
+				lines[i].Level = record[levelColIndex]
+			}
+		}
+		return lg.Lines(lines)
+	}
+	if err := update(); err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, update)
+
+	return lg, nil
+}
+
+// numericOrder returns the indexes of labels sorted by value when they are
+// all numbers, such as hours, and in their order otherwise.
+func numericOrder(labels []string) []int {
+	order := make([]int, len(labels))
+	numbers := make([]float64, len(labels))
+	numeric := true
+	for i, label := range labels {
+		order[i] = i
+		v, err := loader.ParseNumber(label, "")
+		if err != nil {
+			numeric = false
+		}
+		numbers[i] = v
+	}
+	if numeric {
+		sort.SliceStable(order, func(i, j int) bool { return numbers[order[i]] < numbers[order[j]] })
+	}
+	return order
+}
+
 // createDonut creates and starts a new donut widget.
-func createDonut(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*donut.Donut, error) {
+func createDonut(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*donut.Donut, error) {
+	csvData := src.Data()
 	valueColIndex := -1
 	for i, header := range csvData.Header {
 		if header == w.ValueCol {
@@ -702,8 +2030,12 @@ func createDonut(ctx context.Context, w *loader.WidgetConfig, csvData *loader.Da
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		if len(csvData.Records) > 0 {
-			val, err := strconv.Atoi(csvData.Records[len(csvData.Records)-1][valueColIndex])
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		if len(data.Records) > 0 {
+			val, err := data.ParseInt(valueColIndex, data.Records[len(data.Records)-1][valueColIndex])
 			if err == nil {
 				return d.Percent(val)
 			}
@@ -713,7 +2045,8 @@ func createDonut(ctx context.Context, w *loader.WidgetConfig, csvData *loader.Da
 	return d, nil
 }
 
-func createPieChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*widgets.PieChart, error) {
+func createPieChart(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.PieChart, error) {
+	csvData := src.Data()
 	valueColIndex := -1
 	for i, header := range csvData.Header {
 		if header == w.ValueCol {
@@ -721,23 +2054,32 @@ func createPieChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader
 			break
 		}
 	}
-	if valueColIndex == -1 {
+	groupColIndex, err := groupColumn(w, csvData)
+	if err != nil {
+		return nil, err
+	}
+	if valueColIndex == -1 && !(groupColIndex != -1 && w.Aggregation == "count") {
 		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
 	}
-
-	pc, err := widgets.NewPieChart()
+	labelColIndex := csvData.ColumnIndex(w.LabelCol)
+	if w.LabelCol != "" && labelColIndex == -1 {
+		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.LabelCol, w.Title)
+	}
+	legend, err := widgets.ParseLegendPlacement(w.Legend)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+	opts := []widgets.PieChartOption{widgets.PieLegend(legend)}
+	if w.InnerRadius != nil {
+		opts = append(opts, widgets.PieInnerRadius(*w.InnerRadius))
+	}
+	if w.SliceLabels {
+		opts = append(opts, widgets.PieSliceLabels(pieSliceLabelMin))
 	}
 
-	// Leggi i dati per le fette della torta
-	var values []int
-	for _, record := range csvData.Records {
-		val, err := strconv.Atoi(record[valueColIndex])
-		if err != nil {
-			continue
-		}
-		values = append(values, val)
+	pc, err := widgets.NewPieChart(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
 	}
 
 	// Definisci i colori per le fette. Devi specificarne uno per ogni fetta.
@@ -754,18 +2096,52 @@ func createPieChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader
 		cell.ColorNumber(63),
 	}
 
-	if err := pc.Values(values, colors); err != nil {
+	update := func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		// Leggi i dati per le fette della torta
+		var values []int
+		var labels []string
+		if groupColIndex != -1 {
+			groups, err := groupedValues(w, data, groupColIndex, labelColIndex, valueColIndex)
+			if err != nil {
+				return err
+			}
+			for _, g := range groups {
+				// Slices can't be negative, e.g. the min of a group.
+				values = append(values, int(math.Round(math.Max(g.Value, 0))))
+				labels = append(labels, g.Label)
+			}
+		} else {
+			for _, record := range data.Records {
+				val, err := data.ParseInt(valueColIndex, record[valueColIndex])
+				if err != nil {
+					continue
+				}
+				values = append(values, val)
+				if labelColIndex != -1 {
+					labels = append(labels, record[labelColIndex])
+				}
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		return pc.LabeledValues(values, labels, colors)
+	}
+	if err := update(); err != nil {
 		return nil, err
 	}
 
-	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		return nil
-	})
+	go periodic(ctx, time.Duration(refresh)*time.Second, update)
 
 	return pc, nil
 }
 
-func createText(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*segmentdisplay.SegmentDisplay, error) {
+func createText(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*segmentdisplay.SegmentDisplay, error) {
+	csvData := src.Data()
 
 	t, err := segmentdisplay.New()
 	if err != nil {
@@ -782,67 +2158,170 @@ func createText(ctx context.Context, w *loader.WidgetConfig, csvData *loader.Dat
 	if valueColIndex == -1 {
 		return nil, fmt.Errorf("colonna '%s' non trovata per il widget '%s'", w.ValueCol, w.Title)
 	}
-	if len(csvData.Records) > 0 {
-		var values []int
-		for _, record := range csvData.Records {
-			val, err := strconv.Atoi(record[valueColIndex])
-			if err == nil {
-				values = append(values, val)
-			}
+	aggregator, err := newAggregator(w)
+	if err != nil {
+		return nil, err
+	}
+	update := func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		if len(data.Records) == 0 {
+			return nil
 		}
 
-		if len(values) == 0 {
+		parse := func(s string) (float64, error) {
+			return data.ParseNumber(valueColIndex, s)
+		}
+		cells := columnCells(data, valueColIndex)
+		if len(aggregate.Numbers(cells, parse)) == 0 && w.Aggregation != "count" && w.Aggregation != "count_distinct" {
 			rollText(ctx, t, fmt.Sprintf("%s: No valid data", w.Title))
-			return t, nil
-		}
-
-		var result string
-		switch w.Aggregation {
-		case "sum":
-			sum := 0
-			for _, v := range values {
-				sum += v
-			}
-			result = fmt.Sprintf("Sum: %d", sum)
-		case "avg":
-			sum := 0
-			for _, v := range values {
-				sum += v
-			}
-			avg := float64(sum) / float64(len(values))
-			result = fmt.Sprintf("Avg: %.2f", avg)
-		case "median":
-			sort.Ints(values)
-			median := 0
-			if len(values)%2 == 0 {
-				median = (values[len(values)/2-1] + values[len(values)/2]) / 2
-			} else {
-				median = values[len(values)/2]
-			}
-			result = fmt.Sprintf("Median: %d", median)
-		case "max":
-			max := values[0]
-			for _, v := range values {
-				if v > max {
-					max = v
-				}
+			return nil
+		}
+		rollText(ctx, t, aggregateLabel(w, aggregator.Cells(cells, parse, time.Now())))
+		return nil
+	}
+	if err := update(); err != nil {
+		return nil, err
+	}
+
+	go periodic(ctx, time.Duration(refresh)*time.Second, update)
+
+	return t, nil
+}
+
+// createStat creates and starts a new stat widget, drawing the aggregation
+// of value_col, the last value by default, its change and a sparkline of the
+// column, or of its time series when x_col is a time column. The change is
+// against the previous refresh or, with a compare window, against the
+// window of x_col before the last one.
+func createStat(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Stat, error) {
+	csvData := src.Data()
+	valueColIndex := csvData.ColumnIndex(w.ValueCol)
+	if valueColIndex == -1 {
+		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
+	}
+	timeColIndex := csvData.ColumnIndex(w.XCol)
+	if w.XCol != "" && !csvData.IsTimeColumn(timeColIndex) {
+		return nil, fmt.Errorf("column '%s' is not a time column for widget '%s'", w.XCol, w.Title)
+	}
+	if err := validateBucket(w); err != nil {
+		return nil, err
+	}
+	aggregator, err := newAggregator(w)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []widgets.StatOption{widgets.StatComparison("vs last refresh")}
+	var window time.Duration
+	if w.Compare != "" && w.Compare != "previous" {
+		if window, err = series.ParseBucket(w.Compare); err != nil {
+			return nil, fmt.Errorf("widget '%s': compare must be 'previous' or a duration: %w", w.Title, err)
+		}
+		if timeColIndex == -1 {
+			return nil, fmt.Errorf("widget '%s': comparing with a window needs a time column in x_col", w.Title)
+		}
+		if err := aggregate.ValidateStateless(aggregator.Name()); err != nil {
+			return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+		}
+		opts = append(opts, widgets.StatComparison("vs previous "+w.Compare))
+	}
+	switch w.Good {
+	case "", "up":
+	case "down":
+		opts = append(opts, widgets.StatDownIsGood())
+	default:
+		return nil, fmt.Errorf("widget '%s': good must be 'up' or 'down', not '%s'", w.Title, w.Good)
+	}
+	if w.Unit != "" {
+		opts = append(opts, widgets.StatUnit(w.Unit))
+	}
+	if w.Format != "" {
+		opts = append(opts, widgets.StatFormat(w.Format))
+	}
+	st, err := widgets.NewStat(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("widget '%s': %w", w.Title, err)
+	}
+
+	// The value of the data before the last reload, and the latest value
+	// of the data as of fetched. The widget ticks more often than the data
+	// reloads, so the change is against the data, not the previous tick.
+	previous, current := math.NaN(), math.NaN()
+	var fetched time.Time
+	update := func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		parse := func(s string) (float64, error) {
+			return data.ParseNumber(valueColIndex, s)
+		}
+		var value, compared float64
+		if window > 0 {
+			value, compared = windowValues(aggregator.Name(), data, timeColIndex, valueColIndex, window)
+		} else {
+			value = aggregator.Cells(columnCells(data, valueColIndex), parse, time.Now())
+			if f := src.Fetched(); !f.Equal(fetched) {
+				previous, fetched = current, f
 			}
-			result = fmt.Sprintf("Max: %d", max)
-		case "min":
-			min := values[0]
-			for _, v := range values {
-				if v < min {
-					min = v
-				}
+			compared, current = previous, value
+		}
+
+		var history []float64
+		if timeColIndex != -1 {
+			if history, _, err = timeSeries(w, data, timeColIndex, valueColIndex); err != nil {
+				return err
 			}
-			result = fmt.Sprintf("Min: %d", min)
-		default:
-			result = "Invalid aggregation type"
+		} else {
+			history = aggregate.Numbers(columnCells(data, valueColIndex), parse)
 		}
+		return st.Value(value, compared, transformed(w, history))
+	}
+	if err := update(); err != nil {
+		return nil, err
+	}
 
-		rollText(ctx, t, result)
+	go periodic(ctx, time.Duration(refresh)*time.Second, update)
+
+	return st, nil
+}
+
+// windowValues aggregates the values of the rows of the last window of the
+// time column, ending with the newest row, and of the window before it.
+func windowValues(agg string, data *loader.DataDataSource, timeCol, valueCol int, window time.Duration) (float64, float64) {
+	var points []series.Point
+	for _, record := range data.Records {
+		t, err := data.ParseTime(timeCol, record[timeCol])
+		if err != nil {
+			continue
+		}
+		v, err := data.ParseNumber(valueCol, record[valueCol])
+		if err != nil {
+			continue
+		}
+		points = append(points, series.Point{Time: t, Value: v})
 	}
-	return t, nil
+	if len(points) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	series.Sort(points)
+
+	newest := points[len(points)-1].Time
+	var current, before []float64
+	for _, p := range points {
+		switch age := newest.Sub(p.Time); {
+		case age < window:
+			current = append(current, p.Value)
+		case age < 2*window:
+			before = append(before, p.Value)
+		}
+	}
+	value, _ := aggregate.Reduce(agg, current)
+	compared, _ := aggregate.Reduce(agg, before)
+	return value, compared
 }
 
 func rollText(ctx context.Context, sd *segmentdisplay.SegmentDisplay, text string) {
@@ -856,33 +2335,17 @@ func rollText(ctx context.Context, sd *segmentdisplay.SegmentDisplay, text strin
 	}
 }
 
-func createRadarChart(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*widgets.Radar, error) {
-	data := make(map[string]float64)
-	for _, record := range csvData.Records {
-		label := record[0]
-		value, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			continue
-		}
-		data[label] = value
-	}
-
-	max := 0.0
-	for _, value := range data {
-		if value > max {
-			max = value
-		}
+func createRadarChart(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Radar, error) {
+	rows, err := widgetData(w, src)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(data) == 0 {
+	values := radarValues(rows)
+	if len(values.Data) == 0 {
 		return nil, fmt.Errorf("no valid data found for radar chart")
 	}
 
-	values := &widgets.Values{
-		Data: data,
-		Max:  max,
-	}
-
 	r, err := widgets.NewRadar()
 	if err != nil {
 		return nil, err
@@ -893,23 +2356,75 @@ func createRadarChart(ctx context.Context, w *loader.WidgetConfig, csvData *load
 	}
 
 	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		return nil
+		rows, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		values := radarValues(rows)
+		if len(values.Data) == 0 {
+			return nil
+		}
+		return r.SetValues(values)
 	})
 
 	return r, nil
 }
 
-func createFunnel(ctx context.Context, w *loader.WidgetConfig, csvData *loader.DataDataSource, refresh int) (*widgets.Funnel, error) {
-	data := make([]int, 0)
-	colors := make([]cell.Color, 0)
+// The radar chart reads its labels in the first column and its values in
+// the second one.
+const radarLabelCol, radarValueCol = 0, 1
 
-	for _, record := range csvData.Records {
-		value, err := strconv.Atoi(record[1])
+// radarValues reads the labels and values of a radar chart.
+func radarValues(rows *loader.DataDataSource) *widgets.Values {
+	data := make(map[string]float64)
+	for _, record := range rows.Records {
+		label := record[radarLabelCol]
+		value, err := rows.ParseNumber(radarValueCol, record[radarValueCol])
 		if err != nil {
 			continue
 		}
-		data = append(data, value)
-		colors = append(colors, cell.ColorNumber(len(colors)+1))
+		data[label] = value
+	}
+
+	max := 0.0
+	for _, value := range data {
+		if value > max {
+			max = value
+		}
+	}
+
+	return &widgets.Values{
+		Data: data,
+		Max:  max,
+	}
+}
+
+// funnelValueColumn returns the column holding the stage values of a
+// funnel: value_col, or the second column without one.
+func funnelValueColumn(w *loader.WidgetConfig, data *loader.DataDataSource) (int, error) {
+	if w.ValueCol == "" {
+		return 1, nil
+	}
+	col := data.ColumnIndex(w.ValueCol)
+	if col == -1 {
+		return -1, fmt.Errorf("column '%s' not found for widget '%s'", w.ValueCol, w.Title)
+	}
+	return col, nil
+}
+
+func createFunnel(ctx context.Context, w *loader.WidgetConfig, src *loader.Dataset, refresh int) (*widgets.Funnel, error) {
+	csvData := src.Data()
+	valueColIndex, err := funnelValueColumn(w, csvData)
+	if err != nil {
+		return nil, err
+	}
+	groupColIndex, err := groupColumn(w, csvData)
+	if err != nil {
+		return nil, err
+	}
+	labelColIndex := csvData.ColumnIndex(w.LabelCol)
+	if w.LabelCol != "" && labelColIndex == -1 {
+		return nil, fmt.Errorf("column '%s' not found for widget '%s'", w.LabelCol, w.Title)
 	}
 
 	funnel, err := widgets.NewFunnel()
@@ -917,13 +2432,43 @@ func createFunnel(ctx context.Context, w *loader.WidgetConfig, csvData *loader.D
 		return nil, err
 	}
 
-	if err := funnel.Values(data, colors); err != nil {
+	update := func() error {
+		data, err := widgetData(w, src)
+		if err != nil {
+			return err
+		}
+		values := make([]int, 0)
+		colors := make([]cell.Color, 0)
+
+		if groupColIndex != -1 {
+			groups, err := groupedValues(w, data, groupColIndex, labelColIndex, valueColIndex)
+			if err != nil {
+				return err
+			}
+			for _, g := range groups {
+				values = append(values, int(math.Round(math.Max(g.Value, 0))))
+				colors = append(colors, cell.ColorNumber(len(colors)+1))
+			}
+		} else {
+			for _, record := range data.Records {
+				value, err := data.ParseInt(valueColIndex, record[valueColIndex])
+				if err != nil {
+					continue
+				}
+				values = append(values, value)
+				colors = append(colors, cell.ColorNumber(len(colors)+1))
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		return funnel.Values(values, colors)
+	}
+	if err := update(); err != nil {
 		return nil, err
 	}
 
-	go periodic(ctx, time.Duration(refresh)*time.Second, func() error {
-		return nil
-	})
+	go periodic(ctx, time.Duration(refresh)*time.Second, update)
 
 	return funnel, nil
 }
@@ -0,0 +1,333 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"datacmd/aggregate"
+	"datacmd/expr"
+)
+
+// reserved are the words that end an expression or a clause and so cannot
+// be used as table names or aliases without backquotes.
+var reserved = map[string]bool{
+	"select": true, "distinct": true, "from": true, "as": true, "join": true,
+	"inner": true, "left": true, "outer": true, "on": true, "where": true,
+	"group": true, "by": true, "having": true, "order": true, "asc": true,
+	"desc": true, "limit": true, "offset": true, "and": true, "or": true,
+	"in": true, "is": true, "matches": true,
+}
+
+// isAggregate reports whether a function is an aggregate: any stateless
+// aggregation of package aggregate.
+func isAggregate(name string) bool {
+	return name != "" && name != "distinct" && aggregate.ValidateStateless(name) == nil
+}
+
+// parser reads a query. Expressions are handed over to package expr, which
+// reports how much of the text they span.
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &expr.Error{Pos: utf8.RuneCountInString(p.src[:p.pos]), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += size
+	}
+}
+
+// found describes the text at the current position for error messages.
+func (p *parser) found() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "end of query"
+	}
+	if w := p.peekWord(); w != "" {
+		return fmt.Sprintf("%q", w)
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return fmt.Sprintf("%q", r)
+}
+
+// peekWord returns the identifier at the current position, if any.
+func (p *parser) peekWord() string {
+	p.skipSpace()
+	end := p.pos
+	for end < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[end:])
+		if !(unicode.IsLetter(r) || r == '_' || (end > p.pos && unicode.IsDigit(r))) {
+			break
+		}
+		end += size
+	}
+	return p.src[p.pos:end]
+}
+
+// keyword consumes the given sequence of keywords, matched
+// case-insensitively, if it comes next.
+func (p *parser) keyword(words ...string) bool {
+	start := p.pos
+	for _, w := range words {
+		if !strings.EqualFold(p.peekWord(), w) {
+			p.pos = start
+			return false
+		}
+		p.pos += len(w)
+	}
+	return true
+}
+
+func (p *parser) expectKeyword(words ...string) error {
+	if !p.keyword(words...) {
+		return p.errorf("expected %s, found %s", strings.Join(words, " "), p.found())
+	}
+	return nil
+}
+
+// accept consumes the given punctuation if it comes next.
+func (p *parser) accept(s string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// name reads a table name or an alias, plain or in backquotes.
+func (p *parser) name() (string, bool) {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], "`") {
+		end := strings.IndexByte(p.src[p.pos+1:], '`')
+		if end < 0 {
+			return "", false
+		}
+		name := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return name, true
+	}
+	w := p.peekWord()
+	if w == "" || reserved[strings.ToLower(w)] {
+		return "", false
+	}
+	p.pos += len(w)
+	return w, true
+}
+
+// alias reads an optional "[AS] name".
+func (p *parser) alias() (string, error) {
+	if p.keyword("as") {
+		name, ok := p.name()
+		if !ok {
+			return "", p.errorf("expected a name after AS, found %s", p.found())
+		}
+		return name, nil
+	}
+	name, _ := p.name()
+	return name, nil
+}
+
+// expression reads an expression. Aggregate functions are parsed everywhere
+// so that using them in the wrong clause gives a clear error.
+func (p *parser) expression(clause string, allowAggregates bool) (*expr.Expr, error) {
+	p.skipSpace()
+	if w := strings.ToLower(p.peekWord()); reserved[w] || w == "select" {
+		return nil, p.errorf("expected an expression, found %s", p.found())
+	}
+	e, n, err := expr.CompilePrefix(p.src[p.pos:], isAggregate)
+	if err != nil {
+		if ee, ok := err.(*expr.Error); ok {
+			return nil, &expr.Error{Pos: utf8.RuneCountInString(p.src[:p.pos]) + ee.Pos, Msg: ee.Msg}
+		}
+		return nil, err
+	}
+	if !allowAggregates && len(e.Aggregates()) > 0 {
+		return nil, p.errorf("aggregate functions are not allowed in %s", clause)
+	}
+	p.pos += n
+	return e, nil
+}
+
+// integer reads a non-negative integer.
+func (p *parser) integer() (int, error) {
+	p.skipSpace()
+	end := p.pos
+	for end < len(p.src) && p.src[end] >= '0' && p.src[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(p.src[p.pos:end])
+	if err != nil {
+		return 0, p.errorf("expected a number, found %s", p.found())
+	}
+	p.pos = end
+	return n, nil
+}
+
+func (p *parser) parse() (*Query, error) {
+	q := &Query{src: p.src, limit: -1}
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+	q.distinct = p.keyword("distinct")
+	for {
+		it, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		q.items = append(q.items, it)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expectKeyword("from"); err != nil {
+		return nil, err
+	}
+	from, err := p.parseTable()
+	if err != nil {
+		return nil, err
+	}
+	q.from = from
+	for {
+		var j join
+		switch {
+		case p.keyword("join"), p.keyword("inner", "join"):
+		case p.keyword("left", "join"), p.keyword("left", "outer", "join"):
+			j.left = true
+		default:
+			goto joined
+		}
+		if j.table, err = p.parseTable(); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("on"); err != nil {
+			return nil, err
+		}
+		if j.on, err = p.expression("ON", false); err != nil {
+			return nil, err
+		}
+		q.joins = append(q.joins, j)
+	}
+joined:
+
+	if p.keyword("where") {
+		if q.where, err = p.expression("WHERE", false); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("group", "by") {
+		for {
+			e, err := p.expression("GROUP BY", false)
+			if err != nil {
+				return nil, err
+			}
+			q.groupBy = append(q.groupBy, e)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("having") {
+		if q.having, err = p.expression("HAVING", true); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("order", "by") {
+		for {
+			k, err := p.parseOrderKey()
+			if err != nil {
+				return nil, err
+			}
+			q.orderBy = append(q.orderBy, k)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("limit") {
+		if q.limit, err = p.integer(); err != nil {
+			return nil, err
+		}
+		if p.keyword("offset") {
+			if q.offset, err = p.integer(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.accept(";")
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %s", p.found())
+	}
+	if q.having != nil && !q.grouped() {
+		return nil, fmt.Errorf("HAVING requires GROUP BY or an aggregate function")
+	}
+	return q, nil
+}
+
+func (p *parser) parseItem() (item, error) {
+	if p.accept("*") {
+		return item{star: true}, nil
+	}
+	start := p.pos
+	if name, ok := p.name(); ok && p.accept(".*") {
+		return item{star: true, table: name}, nil
+	}
+	p.pos = start
+
+	e, err := p.expression("SELECT", true)
+	if err != nil {
+		return item{}, err
+	}
+	alias, err := p.alias()
+	if err != nil {
+		return item{}, err
+	}
+	return item{expr: e, alias: alias}, nil
+}
+
+func (p *parser) parseTable() (tableRef, error) {
+	name, ok := p.name()
+	if !ok {
+		return tableRef{}, p.errorf("expected a source name, found %s", p.found())
+	}
+	alias, err := p.alias()
+	if err != nil {
+		return tableRef{}, err
+	}
+	if alias == "" {
+		alias = name
+	}
+	return tableRef{name: name, alias: alias}, nil
+}
+
+func (p *parser) parseOrderKey() (orderKey, error) {
+	e, err := p.expression("ORDER BY", true)
+	if err != nil {
+		return orderKey{}, err
+	}
+	k := orderKey{expr: e}
+	if n, err := strconv.Atoi(e.String()); err == nil {
+		k.position = n
+		k.expr = nil
+	}
+	if p.keyword("desc") {
+		k.desc = true
+	} else {
+		p.keyword("asc")
+	}
+	return k, nil
+}
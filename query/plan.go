@@ -0,0 +1,511 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"datacmd/aggregate"
+	"datacmd/expr"
+)
+
+// plan is a query bound to the columns of its tables.
+type plan struct {
+	key     string
+	schema  *rowSchema
+	on      []*expr.Program
+	left    []bool
+	where   *expr.Program
+	columns []output
+	grouped bool
+	groupBy []*expr.Program
+	having  *bound
+	order   []boundKey
+}
+
+// output is a column of the result.
+type output struct {
+	name   string
+	expr   *bound
+	origin Origin
+}
+
+// cell writes a value of the column. The columns copied from a source keep
+// its text, while the numbers of computed columns are written with a
+// decimal point, even when they are a cell of a source written in another
+// locale, as max(amount) or coalesce(amount, 0) are.
+func (c output) cell(v expr.Value) string {
+	if f, ok := v.Float(); ok && c.origin.Table == "" {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return v.String()
+}
+
+// bound is an expression bound to the row schema, with the arguments of
+// its aggregate functions.
+type bound struct {
+	prog *expr.Program
+	aggs []boundAggregate
+}
+
+type boundAggregate struct {
+	*expr.Aggregate
+	arg *expr.Program
+}
+
+// boundKey is an ORDER BY key: either a column of the result or an
+// expression.
+type boundKey struct {
+	column int
+	expr   *bound
+	desc   bool
+}
+
+// bindExpr resolves the columns of an expression against the schema.
+func bindExpr(s *rowSchema, e *expr.Expr) (*bound, error) {
+	prog, err := e.Bind(s)
+	if err != nil {
+		return nil, err
+	}
+	b := &bound{prog: prog}
+	for _, a := range e.Aggregates() {
+		ba := boundAggregate{Aggregate: a}
+		if a.Arg != nil {
+			if ba.arg, err = a.Arg.Bind(s); err != nil {
+				return nil, err
+			}
+		}
+		b.aggs = append(b.aggs, ba)
+	}
+	return b, nil
+}
+
+func (q *Query) bind(refs []tableRef, inputs []*Table) (*plan, error) {
+	s := newRowSchema(refs, inputs)
+	p := &plan{schema: s, grouped: q.grouped()}
+
+	for _, j := range q.joins {
+		b, err := bindExpr(s, j.on)
+		if err != nil {
+			return nil, err
+		}
+		p.on = append(p.on, b.prog)
+		p.left = append(p.left, j.left)
+	}
+	if q.where != nil {
+		b, err := bindExpr(s, q.where)
+		if err != nil {
+			return nil, err
+		}
+		p.where = b.prog
+	}
+	for _, e := range q.groupBy {
+		b, err := bindExpr(s, e)
+		if err != nil {
+			return nil, err
+		}
+		p.groupBy = append(p.groupBy, b.prog)
+	}
+	if q.having != nil {
+		b, err := bindExpr(s, q.having)
+		if err != nil {
+			return nil, err
+		}
+		p.having = b
+	}
+
+	for _, it := range q.items {
+		if !it.star {
+			b, err := bindExpr(s, it.expr)
+			if err != nil {
+				return nil, err
+			}
+			out := output{name: it.alias, expr: b}
+			if cols := it.expr.Columns(); len(cols) == 1 && isColumn(it.expr, cols[0]) {
+				col, _ := s.ResolveColumn(cols[0])
+				out.origin = s.origin(col)
+				if out.name == "" {
+					out.name = s.names[col]
+				}
+			}
+			if out.name == "" {
+				out.name = it.expr.String()
+			}
+			p.columns = append(p.columns, out)
+			continue
+		}
+
+		found := false
+		for i, ref := range refs {
+			if it.table != "" && it.table != ref.alias {
+				continue
+			}
+			found = true
+			for j, h := range inputs[i].Header {
+				e, err := expr.Compile("`" + ref.alias + "." + h + "`")
+				if err != nil {
+					return nil, err
+				}
+				b, err := bindExpr(s, e)
+				if err != nil {
+					return nil, err
+				}
+				p.columns = append(p.columns, output{name: h, expr: b, origin: Origin{Table: ref.name, Column: j}})
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown source '%s' in %s.*", it.table, it.table)
+		}
+	}
+	uniqueNames(p.columns, refs)
+
+	for _, k := range q.orderBy {
+		bk := boundKey{column: -1, desc: k.desc}
+		switch {
+		case k.expr == nil:
+			if k.position < 1 || k.position > len(p.columns) {
+				return nil, fmt.Errorf("ORDER BY position %d is out of range, the query has %d columns", k.position, len(p.columns))
+			}
+			bk.column = k.position - 1
+		default:
+			for i, c := range p.columns {
+				if cols := k.expr.Columns(); len(cols) == 1 && isColumn(k.expr, cols[0]) && c.name == cols[0] {
+					bk.column = i
+					break
+				}
+			}
+			if bk.column == -1 {
+				b, err := bindExpr(s, k.expr)
+				if err != nil {
+					return nil, err
+				}
+				bk.expr = b
+			}
+		}
+		p.order = append(p.order, bk)
+	}
+	return p, nil
+}
+
+// isColumn reports whether an expression is a plain reference to a column.
+func isColumn(e *expr.Expr, col string) bool {
+	return e.String() == col || e.String() == "`"+col+"`"
+}
+
+// uniqueNames qualifies the result columns sharing a name with the alias of
+// their source, then numbers the remaining duplicates.
+func uniqueNames(columns []output, refs []tableRef) {
+	count := make(map[string]int)
+	for _, c := range columns {
+		count[c.name]++
+	}
+	for i, c := range columns {
+		if count[c.name] > 1 && c.origin.Table != "" {
+			for _, ref := range refs {
+				if ref.name == c.origin.Table {
+					columns[i].name = ref.alias + "." + c.name
+					break
+				}
+			}
+		}
+	}
+	seen := make(map[string]int)
+	for i, c := range columns {
+		seen[c.name]++
+		if n := seen[c.name]; n > 1 {
+			columns[i].name = c.name + "_" + strconv.Itoa(n)
+		}
+	}
+}
+
+// row is a row of the result with the values of its ORDER BY keys.
+type row struct {
+	cells []string
+	keys  []expr.Value
+}
+
+func (p *plan) run(q *Query, inputs []*Table) (*Result, error) {
+	rows, err := p.join(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if p.where != nil {
+		kept := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			ok, err := p.where.Match(r)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				kept = append(kept, r)
+			}
+		}
+		rows = kept
+	}
+
+	var out []row
+	if p.grouped {
+		out, err = p.aggregate(rows)
+	} else {
+		out, err = p.project(rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if q.distinct {
+		seen := make(map[string]bool)
+		kept := out[:0]
+		for _, r := range out {
+			k := strings.Join(r.cells, "\x00")
+			if !seen[k] {
+				seen[k] = true
+				kept = append(kept, r)
+			}
+		}
+		out = kept
+	}
+	if len(p.order) > 0 {
+		sort.SliceStable(out, func(i, j int) bool {
+			for k, key := range p.order {
+				c := expr.Compare(out[i].keys[k], out[j].keys[k])
+				if key.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+	if q.offset > 0 {
+		out = out[min(q.offset, len(out)):]
+	}
+	if q.limit >= 0 && len(out) > q.limit {
+		out = out[:q.limit]
+	}
+
+	res := &Result{Records: make([][]string, len(out))}
+	for _, c := range p.columns {
+		res.Header = append(res.Header, c.name)
+		res.Origins = append(res.Origins, c.origin)
+	}
+	for i, r := range out {
+		res.Records[i] = r.cells
+	}
+	return res, nil
+}
+
+// join returns the rows of the FROM clause, joining the tables in order.
+func (p *plan) join(inputs []*Table) ([][]string, error) {
+	rows := inputs[0].Records
+	for i, t := range inputs[1:] {
+		on := p.on[i]
+		width := p.schema.width(i + 1)
+		var joined [][]string
+		for _, l := range rows {
+			matched := false
+			for _, r := range t.Records {
+				candidate := append(append(make([]string, 0, width+len(r)), l...), r...)
+				ok, err := on.Match(candidate)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					joined = append(joined, candidate)
+					matched = true
+				}
+			}
+			if !matched && p.left[i] {
+				joined = append(joined, append(append(make([]string, 0, width+len(t.Header)), l...), make([]string, len(t.Header))...))
+			}
+		}
+		rows = joined
+	}
+	return rows, nil
+}
+
+// project evaluates the result columns of a query without aggregation.
+func (p *plan) project(rows [][]string) ([]row, error) {
+	out := make([]row, 0, len(rows))
+	for _, r := range rows {
+		var res row
+		values := make([]expr.Value, len(p.columns))
+		for i, c := range p.columns {
+			v, err := c.expr.prog.Eval(r)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+			res.cells = append(res.cells, c.cell(v))
+		}
+		for _, k := range p.order {
+			if k.column >= 0 {
+				res.keys = append(res.keys, values[k.column])
+				continue
+			}
+			v, err := k.expr.prog.Eval(r)
+			if err != nil {
+				return nil, err
+			}
+			res.keys = append(res.keys, v)
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// aggregate groups the rows by the GROUP BY keys, in order of first
+// appearance, and evaluates the result columns once per group. Without
+// GROUP BY all the rows form a single group.
+func (p *plan) aggregate(rows [][]string) ([]row, error) {
+	var groups [][][]string
+	if len(p.groupBy) == 0 {
+		groups = [][][]string{rows}
+	} else {
+		index := make(map[string]int)
+		for _, r := range rows {
+			var key strings.Builder
+			for _, g := range p.groupBy {
+				v, err := g.Eval(r)
+				if err != nil {
+					return nil, err
+				}
+				if v.IsNull() {
+					key.WriteString("\x01")
+				} else {
+					key.WriteString(v.String())
+				}
+				key.WriteString("\x00")
+			}
+			i, ok := index[key.String()]
+			if !ok {
+				i = len(groups)
+				index[key.String()] = i
+				groups = append(groups, nil)
+			}
+			groups[i] = append(groups[i], r)
+		}
+	}
+
+	out := make([]row, 0, len(groups))
+	for _, g := range groups {
+		if p.having != nil {
+			v, err := p.having.eval(g)
+			if err != nil {
+				return nil, err
+			}
+			if !v.Truthy() {
+				continue
+			}
+		}
+		var res row
+		values := make([]expr.Value, len(p.columns))
+		for i, c := range p.columns {
+			v, err := c.expr.eval(g)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+			res.cells = append(res.cells, c.cell(v))
+		}
+		for _, k := range p.order {
+			if k.column >= 0 {
+				res.keys = append(res.keys, values[k.column])
+				continue
+			}
+			v, err := k.expr.eval(g)
+			if err != nil {
+				return nil, err
+			}
+			res.keys = append(res.keys, v)
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// eval evaluates a bound expression on a group of rows.
+func (b *bound) eval(rows [][]string) (expr.Value, error) {
+	aggs := make([]expr.Value, len(b.aggs))
+	for i, a := range b.aggs {
+		v, err := a.compute(rows)
+		if err != nil {
+			return expr.NullValue, err
+		}
+		aggs[i] = v
+	}
+	var first []string
+	if len(rows) > 0 {
+		first = rows[0]
+	}
+	return b.prog.EvalGroup(first, aggs)
+}
+
+// compute evaluates an aggregate function over the rows of a group. Null
+// values are ignored; min, max, first and last keep any value, the other
+// aggregations use the numbers.
+func (a boundAggregate) compute(rows [][]string) (expr.Value, error) {
+	if a.arg == nil {
+		return expr.NumberValue(float64(len(rows))), nil
+	}
+	distinct := a.Distinct || a.Name == "count_distinct"
+	var values []expr.Value
+	seen := make(map[string]bool)
+	for _, r := range rows {
+		v, err := a.arg.Eval(r)
+		if err != nil {
+			return expr.NullValue, err
+		}
+		if v.IsNull() {
+			continue
+		}
+		if distinct {
+			key := v.String()
+			if f, ok := v.Float(); ok {
+				key = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		values = append(values, v)
+	}
+
+	switch a.Name {
+	case "count", "count_distinct":
+		return expr.NumberValue(float64(len(values))), nil
+	case "first", "last", "min", "max":
+		if len(values) == 0 {
+			return expr.NullValue, nil
+		}
+		best := values[0]
+		for _, v := range values[1:] {
+			switch c := expr.Compare(v, best); {
+			case a.Name == "last",
+				a.Name == "min" && c < 0,
+				a.Name == "max" && c > 0:
+				best = v
+			}
+		}
+		return best, nil
+	}
+	var nums []float64
+	for _, v := range values {
+		if f, ok := v.Float(); ok {
+			nums = append(nums, f)
+		}
+	}
+	f, err := aggregate.Reduce(a.Name, nums)
+	if err != nil {
+		return expr.NullValue, err
+	}
+	if math.IsNaN(f) {
+		return expr.NullValue, nil
+	}
+	return expr.NumberValue(f), nil
+}
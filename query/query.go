@@ -0,0 +1,249 @@
+// Package query runs SQL queries over the data of named sources, e.g.
+//
+//	SELECT region, sum(amount) AS total FROM src GROUP BY region ORDER BY 2 DESC LIMIT 10
+//
+// It supports SELECT [DISTINCT], FROM with inner and left joins, WHERE,
+// GROUP BY, HAVING, ORDER BY and LIMIT/OFFSET. Expressions use the syntax of
+// package expr, plus the aggregations of package aggregate, such as count,
+// sum, avg, p95 or stddev, called as functions. A query is parsed once; the
+// plan resolving its columns is cached for as long as the columns of its
+// sources stay the same.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"datacmd/expr"
+)
+
+// Table is an input of a query: the rows of a source and how their cells
+// are parsed.
+type Table struct {
+	Header  []string
+	Records [][]string
+	Schema  expr.Schema
+}
+
+// Origin is the source column a result column was copied from.
+type Origin struct {
+	// Table is the name of the source, or empty for computed columns.
+	Table string
+	// Column is the index of the column in the source.
+	Column int
+}
+
+// Result is the output of a query.
+type Result struct {
+	Header  []string
+	Records [][]string
+	// Origins tells where each column of the result comes from, so that its
+	// cells can be parsed like those of the source.
+	Origins []Origin
+}
+
+// Query is a parsed query.
+type Query struct {
+	src      string
+	distinct bool
+	items    []item
+	from     tableRef
+	joins    []join
+	where    *expr.Expr
+	groupBy  []*expr.Expr
+	having   *expr.Expr
+	orderBy  []orderKey
+	limit    int
+	offset   int
+
+	mu   sync.Mutex
+	plan *plan
+}
+
+// item is an element of the SELECT list: an expression or a "*", possibly
+// restricted to one table.
+type item struct {
+	expr  *expr.Expr
+	alias string
+	star  bool
+	table string
+}
+
+type tableRef struct {
+	name, alias string
+}
+
+type join struct {
+	table tableRef
+	left  bool
+	on    *expr.Expr
+}
+
+// orderKey is an ORDER BY key: an expression or a 1-based position in the
+// SELECT list.
+type orderKey struct {
+	expr     *expr.Expr
+	position int
+	desc     bool
+}
+
+// Compile parses a query.
+func Compile(src string) (*Query, error) {
+	p := &parser{src: src}
+	q, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return q, nil
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Tables returns the names of the sources the query reads.
+func (q *Query) Tables() []string {
+	names := []string{q.from.name}
+	for _, j := range q.joins {
+		names = append(names, j.table.name)
+	}
+	return names
+}
+
+// grouped reports whether the query aggregates its rows.
+func (q *Query) grouped() bool {
+	if len(q.groupBy) > 0 || (q.having != nil && len(q.having.Aggregates()) > 0) {
+		return true
+	}
+	for _, it := range q.items {
+		if it.expr != nil && len(it.expr.Aggregates()) > 0 {
+			return true
+		}
+	}
+	for _, k := range q.orderBy {
+		if k.expr != nil && len(k.expr.Aggregates()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the query over the named tables.
+func (q *Query) Run(tables map[string]*Table) (*Result, error) {
+	refs := append([]tableRef{q.from}, make([]tableRef, 0, len(q.joins))...)
+	for _, j := range q.joins {
+		refs = append(refs, j.table)
+	}
+	inputs := make([]*Table, len(refs))
+	var key strings.Builder
+	for i, ref := range refs {
+		t, ok := tables[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source '%s'", ref.name)
+		}
+		inputs[i] = t
+		key.WriteString(ref.name)
+		for _, h := range t.Header {
+			key.WriteString("\x00" + h)
+		}
+		key.WriteString("\x01")
+	}
+
+	q.mu.Lock()
+	pl := q.plan
+	if pl == nil || pl.key != key.String() {
+		var err error
+		if pl, err = q.bind(refs, inputs); err != nil {
+			q.mu.Unlock()
+			return nil, err
+		}
+		pl.key = key.String()
+		q.plan = pl
+	}
+	q.mu.Unlock()
+	return pl.run(q, inputs)
+}
+
+// rowSchema describes the rows produced by the FROM clause: the columns of
+// each table, side by side.
+type rowSchema struct {
+	refs    []tableRef
+	inputs  []*Table
+	offsets []int
+	names   []string
+	owner   []int
+}
+
+func newRowSchema(refs []tableRef, inputs []*Table) *rowSchema {
+	s := &rowSchema{refs: refs, inputs: inputs}
+	for i, t := range inputs {
+		s.offsets = append(s.offsets, len(s.names))
+		for _, h := range t.Header {
+			s.names = append(s.names, h)
+			s.owner = append(s.owner, i)
+		}
+	}
+	return s
+}
+
+// ResolveColumn finds a column, either by its name or as "table.column".
+// A name found in several joined sources is ambiguous.
+func (s *rowSchema) ResolveColumn(name string) (int, error) {
+	found := -1
+	for i, n := range s.names {
+		if n == name {
+			if found != -1 {
+				return -1, fmt.Errorf("ambiguous column '%s', prefix it with the source name", name)
+			}
+			found = i
+		}
+	}
+	if found != -1 {
+		return found, nil
+	}
+	if table, col, ok := strings.Cut(name, "."); ok {
+		for i, ref := range s.refs {
+			if ref.alias != table {
+				continue
+			}
+			for j, h := range s.inputs[i].Header {
+				if h == col {
+					return s.offsets[i] + j, nil
+				}
+			}
+		}
+	}
+	return -1, fmt.Errorf("unknown column '%s'", name)
+}
+
+func (s *rowSchema) ColumnIndex(name string) int {
+	i, _ := s.ResolveColumn(name)
+	return i
+}
+
+func (s *rowSchema) ParseNumber(col int, v string) (float64, error) {
+	t := s.owner[col]
+	return s.inputs[t].Schema.ParseNumber(col-s.offsets[t], v)
+}
+
+func (s *rowSchema) ParseTime(col int, v string) (time.Time, error) {
+	t := s.owner[col]
+	return s.inputs[t].Schema.ParseTime(col-s.offsets[t], v)
+}
+
+// origin returns where a column of the row comes from.
+func (s *rowSchema) origin(col int) Origin {
+	t := s.owner[col]
+	return Origin{Table: s.refs[t].name, Column: col - s.offsets[t]}
+}
+
+// width returns the number of columns of the first n tables.
+func (s *rowSchema) width(n int) int {
+	if n == len(s.inputs) {
+		return len(s.names)
+	}
+	return s.offsets[n]
+}
@@ -0,0 +1,183 @@
+package query
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSchema parses numbers as plain floats.
+type testSchema struct{}
+
+func (testSchema) ColumnIndex(string) int { return -1 }
+
+func (testSchema) ParseNumber(col int, v string) (float64, error) {
+	return strconv.ParseFloat(v, 64)
+}
+
+func (testSchema) ParseTime(col int, v string) (time.Time, error) {
+	return time.Parse(time.RFC3339, v)
+}
+
+func testTables() map[string]*Table {
+	return map[string]*Table{
+		"src": {
+			Header: []string{"region", "host", "amount"},
+			Records: [][]string{
+				{"EU", "a", "10"},
+				{"US", "b", "25"},
+				{"EU", "c", "5"},
+				{"APAC", "a", "40"},
+				{"US", "d", ""},
+			},
+			Schema: testSchema{},
+		},
+		"hosts": {
+			Header:  []string{"host", "team"},
+			Records: [][]string{{"a", "core"}, {"b", "edge"}, {"c", "core"}},
+			Schema:  testSchema{},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		src    string
+		header []string
+		rows   string
+	}{
+		{
+			"SELECT region, sum(amount) FROM src GROUP BY region ORDER BY 2 DESC LIMIT 2",
+			[]string{"region", "sum(amount)"},
+			"APAC,40;US,25",
+		},
+		{
+			"select region, count(*) as n, count(amount) filled from src group by region having count(*) > 1 order by region",
+			[]string{"region", "n", "filled"},
+			"EU,2,2;US,2,1",
+		},
+		{
+			"SELECT s.host, h.team, amount * 2 AS double FROM src s LEFT JOIN hosts h ON s.host = h.host WHERE amount > 6 ORDER BY double",
+			[]string{"host", "team", "double"},
+			"a,core,20;b,edge,50;a,core,80",
+		},
+		{
+			"SELECT team, avg(amount) FROM src JOIN hosts ON src.host = hosts.host GROUP BY team ORDER BY team;",
+			[]string{"team", "avg(amount)"},
+			"core,18.333333333333332;edge,25",
+		},
+		{
+			"SELECT DISTINCT region FROM src ORDER BY region LIMIT 2 OFFSET 1",
+			[]string{"region"},
+			"EU;US",
+		},
+		{
+			"SELECT count(distinct host), max(region), min(amount) FROM src",
+			[]string{"count(distinct host)", "max(region)", "min(amount)"},
+			"4,US,5",
+		},
+		{
+			"SELECT * FROM src JOIN hosts ON src.host = hosts.host WHERE team = 'edge'",
+			[]string{"region", "src.host", "amount", "hosts.host", "team"},
+			"US,b,25,b,edge",
+		},
+	}
+	for _, tt := range tests {
+		q, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.src, err)
+			continue
+		}
+		res, err := q.Run(testTables())
+		if err != nil {
+			t.Errorf("Run(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(res.Header, tt.header) {
+			t.Errorf("Run(%q) header = %v, expected %v", tt.src, res.Header, tt.header)
+		}
+		var rows []string
+		for _, r := range res.Records {
+			rows = append(rows, strings.Join(r, ","))
+		}
+		if got := strings.Join(rows, ";"); got != tt.rows {
+			t.Errorf("Run(%q) = %q, expected %q", tt.src, got, tt.rows)
+		}
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"SELECT FROM src",
+		"SELECT region src",
+		"SELECT region FROM",
+		"SELECT region FROM src WHERE sum(amount) > 1",
+		"SELECT region FROM src GROUP BY count(*)",
+		"SELECT sum(count(*)) FROM src",
+		"SELECT region FROM src HAVING region = 'EU'",
+		"SELECT region FROM src LIMIT ten",
+		"SELECT region FROM src ORDER BY region sideways",
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) expected an error", src)
+		}
+	}
+}
+
+func TestRun_Invalid(t *testing.T) {
+	for _, src := range []string{
+		"SELECT region FROM missing",
+		"SELECT nope FROM src",
+		"SELECT host FROM src JOIN hosts ON src.host = hosts.host",
+		"SELECT region FROM src ORDER BY 3",
+	} {
+		q, err := Compile(src)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", src, err)
+			continue
+		}
+		if _, err := q.Run(testTables()); err == nil {
+			t.Errorf("Run(%q) expected an error", src)
+		}
+	}
+}
+
+func TestRun_Ambiguous(t *testing.T) {
+	q, err := Compile("SELECT host FROM src JOIN hosts ON src.host = hosts.host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.Run(testTables())
+	if err == nil || !strings.Contains(err.Error(), "ambiguous column 'host'") {
+		t.Errorf("expected an ambiguous column error, got %v", err)
+	}
+}
+
+func TestRun_CachesPlan(t *testing.T) {
+	q, err := Compile("SELECT host FROM src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := testTables()
+	if _, err := q.Run(tables); err != nil {
+		t.Fatal(err)
+	}
+	p := q.plan
+	tables["src"].Records = tables["src"].Records[:1]
+	if _, err := q.Run(tables); err != nil {
+		t.Fatal(err)
+	}
+	if q.plan != p {
+		t.Errorf("the plan should be reused while the columns stay the same")
+	}
+	tables["src"].Header = []string{"region", "host", "total"}
+	if _, err := q.Run(tables); err != nil {
+		t.Fatal(err)
+	}
+	if q.plan == p {
+		t.Errorf("the plan should be rebuilt when the columns change")
+	}
+}
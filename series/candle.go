@@ -0,0 +1,87 @@
+package series
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Candle is the open, high, low and close prices of a period, and the
+// volume traded in it.
+type Candle struct {
+	Time                   time.Time
+	Open, High, Low, Close float64
+	// Volume is NaN when unknown.
+	Volume float64
+}
+
+// SortCandles orders candles by time, keeping the original order of equal
+// times.
+func SortCandles(candles []Candle) {
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candles[i].Time.Before(candles[j].Time)
+	})
+}
+
+// ResampleCandles merges sorted candles into consecutive buckets of the
+// given size, starting at the bucket containing the first one: the open of
+// the first candle of a bucket, the highest high, the lowest low, the close
+// of the last candle and the sum of the volumes. Buckets without candles
+// have NaN prices, so charts leave a gap for them. Like Resample, it
+// returns an error for more than MaxBuckets buckets.
+func ResampleCandles(candles []Candle, step time.Duration, loc *time.Location) (Grid, []Candle, error) {
+	grid := Grid{Step: step, Loc: loc}
+	if len(candles) == 0 {
+		return grid, nil, nil
+	}
+	grid.Start = Floor(candles[0].Time, step, loc)
+	n, err := bucketCount(grid, candles[len(candles)-1].Time)
+	if err != nil {
+		return grid, nil, err
+	}
+	nan := math.NaN()
+	buckets := make([]Candle, n)
+	for i := range buckets {
+		buckets[i] = Candle{Time: grid.At(i), Open: nan, High: nan, Low: nan, Close: nan, Volume: nan}
+	}
+	for _, c := range candles {
+		b := &buckets[grid.Index(c.Time)]
+		if math.IsNaN(b.Open) {
+			b.Open, b.High, b.Low = c.Open, c.High, c.Low
+		}
+		b.High = math.Max(b.High, c.High)
+		b.Low = math.Min(b.Low, c.Low)
+		b.Close = c.Close
+		if !math.IsNaN(c.Volume) {
+			if math.IsNaN(b.Volume) {
+				b.Volume = 0
+			}
+			b.Volume += c.Volume
+		}
+	}
+	return grid, buckets, nil
+}
+
+// TimeLabels formats sorted times as X axis labels, as short as their span
+// and the smallest interval between them allow.
+func TimeLabels(times []time.Time, loc *time.Location) []string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if len(times) == 0 {
+		return nil
+	}
+	span := times[len(times)-1].Sub(times[0])
+	step := span
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap > 0 && gap < step {
+			step = gap
+		}
+	}
+	layout := labelLayout(span, step)
+	labels := make([]string, len(times))
+	for i, t := range times {
+		labels[i] = t.In(loc).Format(layout)
+	}
+	return labels
+}
@@ -0,0 +1,46 @@
+package series
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestResampleCandles(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	nan := math.NaN()
+	candles := []Candle{
+		{Time: base.Add(10 * time.Second), Open: 10, High: 12, Low: 9, Close: 11, Volume: 100},
+		{Time: base.Add(40 * time.Second), Open: 11, High: 15, Low: 10, Close: 14, Volume: nan},
+		{Time: base.Add(150 * time.Second), Open: 14, High: 14, Low: 8, Close: 9, Volume: 50},
+	}
+	grid, buckets, err := ResampleCandles(candles, time.Minute, time.UTC)
+	if err != nil {
+		t.Fatalf("ResampleCandles failed: %v", err)
+	}
+	if !grid.Start.Equal(base) || len(buckets) != 3 {
+		t.Fatalf("unexpected grid %v and buckets %v", grid, buckets)
+	}
+	first := buckets[0]
+	if first.Open != 10 || first.High != 15 || first.Low != 9 || first.Close != 14 || first.Volume != 100 {
+		t.Errorf("unexpected first bucket %+v", first)
+	}
+	if !math.IsNaN(buckets[1].Open) || !math.IsNaN(buckets[1].Volume) || !buckets[1].Time.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected an empty second bucket, got %+v", buckets[1])
+	}
+	if buckets[2].Close != 9 || buckets[2].Low != 8 {
+		t.Errorf("unexpected last bucket %+v", buckets[2])
+	}
+}
+
+func TestTimeLabels(t *testing.T) {
+	base := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(5 * time.Minute), base.Add(time.Hour)}
+	if got := TimeLabels(times, time.UTC); got[0] != "09:30" || got[2] != "10:30" {
+		t.Errorf("unexpected labels %v", got)
+	}
+	days := []time.Time{base, base.AddDate(0, 0, 1), base.AddDate(0, 0, 30)}
+	if got := TimeLabels(days, time.UTC); got[1] != "Mar 02" {
+		t.Errorf("unexpected labels %v", got)
+	}
+}
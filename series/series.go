@@ -0,0 +1,334 @@
+// Package series turns timestamped rows into evenly spaced series that the
+// chart widgets can draw on a real time axis.
+package series
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"datacmd/aggregate"
+)
+
+// Point is a single timestamped value.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Sort orders points by time, keeping the original order of equal times.
+func Sort(points []Point) {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+}
+
+// ParseBucket parses a bucket size such as "30s", "5m", "1h", "1d" or "2w".
+// Days and weeks are not supported by time.ParseDuration, so they are
+// handled here.
+func ParseBucket(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid bucket %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid bucket %q", s)
+	}
+	return d, nil
+}
+
+// niceSteps are the grid steps picked for series without a bucket.
+var niceSteps = []time.Duration{
+	time.Second, 5 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour,
+}
+
+// NiceStep returns the smallest round step that covers span in at most
+// maxPoints steps.
+func NiceStep(span time.Duration, maxPoints int) time.Duration {
+	for _, step := range niceSteps {
+		if span/step < time.Duration(maxPoints) {
+			return step
+		}
+	}
+	step := niceSteps[len(niceSteps)-1]
+	for span/step >= time.Duration(maxPoints) {
+		step *= 2
+	}
+	return step
+}
+
+// Grid is a sequence of evenly spaced bucket start times.
+type Grid struct {
+	Start time.Time
+	Step  time.Duration
+	Loc   *time.Location
+}
+
+// isCalendar reports whether the step is a whole number of days, in which
+// case buckets follow local midnights rather than fixed durations.
+func (g Grid) isCalendar() bool {
+	return g.Step >= 24*time.Hour && g.Step%(24*time.Hour) == 0
+}
+
+// Floor returns the start of the bucket of size step containing t. Buckets
+// are aligned in loc, so that 6h buckets start at local midnight and hourly
+// ones on the hour in zones offset by half an hour.
+func Floor(t time.Time, step time.Duration, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	g := Grid{Step: step, Loc: loc}
+	if !g.isCalendar() {
+		_, offset := t.In(loc).Zone()
+		shift := time.Duration(offset) * time.Second
+		return t.Add(shift).Truncate(step).Add(-shift)
+	}
+	y, m, d := t.In(loc).Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	if step%(7*24*time.Hour) == 0 {
+		// Weeks start on Monday.
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// At returns the start of the i-th bucket.
+func (g Grid) At(i int) time.Time {
+	if g.isCalendar() {
+		return g.Start.AddDate(0, 0, i*int(g.Step/(24*time.Hour)))
+	}
+	return g.Start.Add(time.Duration(i) * g.Step)
+}
+
+// Index returns the bucket index of t, which must not precede Start.
+func (g Grid) Index(t time.Time) int {
+	if !g.isCalendar() {
+		return int(t.Sub(g.Start) / g.Step)
+	}
+	i := int(t.Sub(g.Start) / g.Step)
+	// Daylight saving changes make calendar days 23 or 25 hours long.
+	for i > 0 && g.At(i).After(t) {
+		i--
+	}
+	for !g.At(i + 1).After(t) {
+		i++
+	}
+	return i
+}
+
+// MaxBuckets is the largest number of buckets points are resampled into, so
+// that an outlier timestamp or a small bucket over a long span can't
+// allocate millions of them.
+const MaxBuckets = 10000
+
+// bucketCount returns the number of buckets of grid up to the one
+// containing last, and an error when there are more than MaxBuckets.
+func bucketCount(grid Grid, last time.Time) (int, error) {
+	if last.Sub(grid.Start)/grid.Step >= MaxBuckets {
+		return 0, fmt.Errorf("the data spans more than %d buckets of %v, use a larger bucket", MaxBuckets, grid.Step)
+	}
+	return grid.Index(last) + 1, nil
+}
+
+// Resample aggregates sorted points into consecutive buckets of the given
+// size, starting at the bucket containing the first point. Buckets without
+// points are NaN, so line charts leave a gap for them.
+func Resample(points []Point, step time.Duration, loc *time.Location, agg string) (Grid, []float64, error) {
+	grid := Grid{Step: step, Loc: loc}
+	if len(points) == 0 {
+		return grid, nil, nil
+	}
+	grid.Start = Floor(points[0].Time, step, loc)
+	n, err := bucketCount(grid, points[len(points)-1].Time)
+	if err != nil {
+		return grid, nil, err
+	}
+	values, err := resampleOn(points, grid, n, agg)
+	return grid, values, err
+}
+
+// ResampleAll is Resample for several series, which share the buckets
+// spanning all of them.
+func ResampleAll(sets [][]Point, step time.Duration, loc *time.Location, agg string) (Grid, [][]float64, error) {
+	grid := Grid{Step: step, Loc: loc}
+	first, last, ok := span(sets)
+	if !ok {
+		return grid, make([][]float64, len(sets)), nil
+	}
+	grid.Start = Floor(first, step, loc)
+	n, err := bucketCount(grid, last)
+	if err != nil {
+		return grid, nil, err
+	}
+	all := make([][]float64, len(sets))
+	for i, points := range sets {
+		values, err := resampleOn(points, grid, n, agg)
+		if err != nil {
+			return grid, nil, err
+		}
+		all[i] = values
+	}
+	return grid, all, nil
+}
+
+// resampleOn aggregates sorted points into the first n buckets of grid,
+// which must start before them.
+func resampleOn(points []Point, grid Grid, n int, agg string) ([]float64, error) {
+	groups := make([][]float64, n)
+	for _, p := range points {
+		i := grid.Index(p.Time)
+		groups[i] = append(groups[i], p.Value)
+	}
+	values := make([]float64, n)
+	for i, g := range groups {
+		if len(g) == 0 {
+			values[i] = math.NaN()
+			continue
+		}
+		v, err := aggregate.Reduce(agg, g)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// span returns the earliest and latest times of sorted sets of points.
+func span(sets [][]Point) (first, last time.Time, ok bool) {
+	for _, points := range sets {
+		if len(points) == 0 {
+			continue
+		}
+		if !ok || points[0].Time.Before(first) {
+			first = points[0].Time
+		}
+		if !ok || points[len(points)-1].Time.After(last) {
+			last = points[len(points)-1].Time
+		}
+		ok = true
+	}
+	return first, last, ok
+}
+
+// Interpolate samples sorted points on a grid of round steps by linear
+// interpolation, so that irregular series keep their true time proportions.
+func Interpolate(points []Point, loc *time.Location, maxPoints int) (Grid, []float64) {
+	if len(points) == 0 {
+		return Grid{Loc: loc}, nil
+	}
+	grid, n := roundGrid(points[0].Time, points[len(points)-1].Time, loc, maxPoints)
+	return grid, interpolateOn(points, grid, n, true)
+}
+
+// InterpolateAll is Interpolate for several series, which share a grid
+// spanning all of them. Each series is NaN outside of its own time range.
+func InterpolateAll(sets [][]Point, loc *time.Location, maxPoints int) (Grid, [][]float64) {
+	first, last, ok := span(sets)
+	if !ok {
+		return Grid{Loc: loc}, make([][]float64, len(sets))
+	}
+	grid, n := roundGrid(first, last, loc, maxPoints)
+	all := make([][]float64, len(sets))
+	for i, points := range sets {
+		all[i] = interpolateOn(points, grid, n, false)
+	}
+	return grid, all
+}
+
+// roundGrid returns a grid of round steps covering first to last in at
+// most about maxPoints buckets, and its number of buckets.
+func roundGrid(first, last time.Time, loc *time.Location, maxPoints int) (Grid, int) {
+	grid := Grid{Loc: loc, Step: NiceStep(last.Sub(first), maxPoints)}
+	grid.Start = Floor(first, grid.Step, loc)
+	n := grid.Index(last) + 1
+	if grid.At(n - 1).Before(last) {
+		n++
+	}
+	return grid, n
+}
+
+// interpolateOn samples sorted points on the first n buckets of grid. The
+// buckets before the first point or after the last one take the nearest
+// value when extend is set, and are NaN otherwise.
+func interpolateOn(points []Point, grid Grid, n int, extend bool) []float64 {
+	values := make([]float64, n)
+	if len(points) == 0 {
+		for i := range values {
+			values[i] = math.NaN()
+		}
+		return values
+	}
+	first, last := points[0].Time, points[len(points)-1].Time
+	// The buckets around the first and last points, like the outer buckets
+	// of Interpolate, always take the nearest value.
+	lo, end := grid.At(grid.Index(first)), grid.Index(last)
+	if grid.At(end).Before(last) {
+		end++
+	}
+	hi := grid.At(end)
+	j := 0
+	for i := range values {
+		t := grid.At(i)
+		for j < len(points)-1 && !points[j+1].Time.After(t) {
+			j++
+		}
+		switch {
+		case !extend && (t.Before(lo) || t.After(hi)):
+			values[i] = math.NaN()
+		case t.Before(first):
+			values[i] = points[0].Value
+		case j == len(points)-1 || points[j].Time.Equal(t):
+			values[i] = points[j].Value
+		default:
+			a, b := points[j], points[j+1]
+			frac := float64(t.Sub(a.Time)) / float64(b.Time.Sub(a.Time))
+			values[i] = a.Value + (b.Value-a.Value)*frac
+		}
+	}
+	return values
+}
+
+// Labels returns an X axis label for each of the n buckets of the grid,
+// formatted according to the step and the overall span.
+func Labels(g Grid, n int) map[int]string {
+	loc := g.Loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	layout := labelLayout(g.At(n-1).Sub(g.Start), g.Step)
+	labels := make(map[int]string, n)
+	for i := 0; i < n; i++ {
+		labels[i] = g.At(i).In(loc).Format(layout)
+	}
+	return labels
+}
+
+// labelLayout picks a label format that is as short as the span allows.
+func labelLayout(span, step time.Duration) string {
+	switch {
+	case step < time.Minute && span < time.Hour:
+		return "15:04:05"
+	case span <= 24*time.Hour:
+		return "15:04"
+	case step < 24*time.Hour && span <= 7*24*time.Hour:
+		return "Mon 15:04"
+	case span <= 365*24*time.Hour:
+		return "Jan 02"
+	}
+	return "2006-01"
+}
@@ -0,0 +1,128 @@
+package series
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestResample(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Time: base.Add(10 * time.Second), Value: 1},
+		{Time: base.Add(50 * time.Second), Value: 3},
+		{Time: base.Add(150 * time.Second), Value: 5},
+	}
+	grid, values, err := Resample(points, time.Minute, time.UTC, "sum")
+	if err != nil {
+		t.Fatalf("Resample failed: %v", err)
+	}
+	if !grid.Start.Equal(base) {
+		t.Errorf("expected grid to start at %v, got %v", base, grid.Start)
+	}
+	if len(values) != 3 || values[0] != 4 || !math.IsNaN(values[1]) || values[2] != 5 {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+func TestFloor_Location(t *testing.T) {
+	rome := time.FixedZone("CET", 3600)
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	tests := []struct {
+		t    time.Time
+		step time.Duration
+		loc  *time.Location
+		want time.Time
+	}{
+		{time.Date(2024, 1, 15, 8, 20, 0, 0, rome), 6 * time.Hour, rome, time.Date(2024, 1, 15, 6, 0, 0, 0, rome)},
+		{time.Date(2024, 1, 15, 0, 20, 0, 0, rome), 6 * time.Hour, rome, time.Date(2024, 1, 15, 0, 0, 0, 0, rome)},
+		{time.Date(2024, 1, 15, 10, 45, 0, 0, kolkata), time.Hour, kolkata, time.Date(2024, 1, 15, 10, 0, 0, 0, kolkata)},
+		{time.Date(2024, 1, 15, 5, 15, 0, 0, time.UTC), time.Hour, kolkata, time.Date(2024, 1, 15, 4, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := Floor(tt.t, tt.step, tt.loc); !got.Equal(tt.want) {
+			t.Errorf("Floor(%v, %v) = %v, expected %v", tt.t, tt.step, got.In(tt.loc), tt.want.In(tt.loc))
+		}
+	}
+}
+
+func TestResample_TooManyBuckets(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Time: base, Value: 1},
+		{Time: base.AddDate(0, 3, 0), Value: 2},
+	}
+	if _, _, err := Resample(points, time.Second, time.UTC, "sum"); err == nil {
+		t.Error("expected an error for a one second bucket over three months")
+	}
+	if _, _, err := Resample(points, 24*time.Hour, time.UTC, "sum"); err != nil {
+		t.Errorf("Resample failed for daily buckets: %v", err)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Time: base, Value: 0},
+		{Time: base.Add(4 * time.Minute), Value: 8},
+	}
+	grid, values := Interpolate(points, time.UTC, 5)
+	if grid.Step != time.Minute {
+		t.Errorf("expected a one minute step, got %v", grid.Step)
+	}
+	want := []float64{0, 2, 4, 6, 8}
+	if len(values) != len(want) {
+		t.Fatalf("expected %d values, got %v", len(want), values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("value %d: expected %v, got %v", i, want[i], values[i])
+		}
+	}
+}
+
+func TestResampleAll(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	sets := [][]Point{
+		{{Time: base.Add(10 * time.Second), Value: 1}},
+		{{Time: base.Add(70 * time.Second), Value: 2}, {Time: base.Add(130 * time.Second), Value: 3}},
+	}
+	grid, all, err := ResampleAll(sets, time.Minute, time.UTC, "sum")
+	if err != nil {
+		t.Fatalf("ResampleAll failed: %v", err)
+	}
+	if !grid.Start.Equal(base) || len(all[0]) != 3 || len(all[1]) != 3 {
+		t.Fatalf("expected 3 shared buckets from %v, got %v from %v", base, all, grid.Start)
+	}
+	if all[0][0] != 1 || !math.IsNaN(all[0][1]) || !math.IsNaN(all[1][0]) || all[1][1] != 2 || all[1][2] != 3 {
+		t.Errorf("unexpected values %v", all)
+	}
+}
+
+func TestInterpolateAll(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sets := [][]Point{
+		{{Time: base, Value: 0}, {Time: base.Add(4 * time.Minute), Value: 8}},
+		{{Time: base.Add(2 * time.Minute), Value: 1}, {Time: base.Add(3 * time.Minute), Value: 2}},
+	}
+	_, all := InterpolateAll(sets, time.UTC, 5)
+	if len(all[0]) != 5 || all[0][4] != 8 {
+		t.Fatalf("unexpected first series %v", all[0])
+	}
+	second := all[1]
+	if !math.IsNaN(second[0]) || !math.IsNaN(second[1]) || second[2] != 1 || second[3] != 2 || !math.IsNaN(second[4]) {
+		t.Errorf("expected the second series only between its points, got %v", second)
+	}
+}
+
+func TestParseBucket(t *testing.T) {
+	for in, want := range map[string]time.Duration{"30s": 30 * time.Second, "1m": time.Minute, "1d": 24 * time.Hour, "2w": 14 * 24 * time.Hour} {
+		got, err := ParseBucket(in)
+		if err != nil || got != want {
+			t.Errorf("ParseBucket(%q) = %v, %v; expected %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseBucket("soon"); err == nil {
+		t.Errorf("expected an error for an invalid bucket")
+	}
+}
@@ -0,0 +1,132 @@
+package series
+
+import (
+	"fmt"
+	"math"
+
+	"datacmd/aggregate"
+)
+
+// ValidateTransform reports an unknown transform or an invalid parameter.
+// moving_avg takes a window of points and ema a smoothing factor in (0, 1];
+// cumsum, pct_change, diff and zscore take no parameter.
+func ValidateTransform(name string, param float64) error {
+	switch name {
+	case "moving_avg":
+		if param < 1 || param != math.Trunc(param) {
+			return fmt.Errorf("moving_avg needs a window of at least one point, got %v", param)
+		}
+	case "ema":
+		if param <= 0 || param > 1 {
+			return fmt.Errorf("ema needs a smoothing factor between 0 and 1, got %v", param)
+		}
+	case "cumsum", "pct_change", "diff", "zscore":
+	default:
+		return fmt.Errorf("unsupported transform '%s'", name)
+	}
+	return nil
+}
+
+// Signed reports whether a transform turns positive values into negative
+// ones, which widgets drawing from zero can't show.
+func Signed(name string) bool {
+	return name == "diff" || name == "pct_change" || name == "zscore"
+}
+
+// Transform returns a new series with the named transform applied. NaN
+// values are gaps: they stay NaN and are skipped by the others, so that
+// differences and averages bridge them.
+//
+//   - moving_avg: mean of the last param points; the first ones average
+//     the points available so far
+//   - ema: exponential moving average with smoothing factor param
+//   - cumsum: running total
+//   - pct_change: percent change from the previous point
+//   - diff: difference from the previous point
+//   - zscore: standard score against the mean and standard deviation of
+//     the series
+func Transform(name string, param float64, values []float64) []float64 {
+	out := make([]float64, len(values))
+	switch name {
+	case "moving_avg":
+		window := int(param)
+		for i, v := range values {
+			if math.IsNaN(v) {
+				out[i] = v
+				continue
+			}
+			sum, n := 0.0, 0
+			for _, w := range values[max(0, i-window+1) : i+1] {
+				if !math.IsNaN(w) {
+					sum += w
+					n++
+				}
+			}
+			out[i] = sum / float64(n)
+		}
+	case "ema":
+		ema := math.NaN()
+		for i, v := range values {
+			if !math.IsNaN(v) {
+				if math.IsNaN(ema) {
+					ema = v
+				} else {
+					ema = param*v + (1-param)*ema
+				}
+			}
+			out[i] = gap(v, ema)
+		}
+	case "cumsum":
+		sum := 0.0
+		for i, v := range values {
+			if !math.IsNaN(v) {
+				sum += v
+			}
+			out[i] = gap(v, sum)
+		}
+	case "diff", "pct_change":
+		prev := math.NaN()
+		for i, v := range values {
+			switch {
+			case math.IsNaN(v) || math.IsNaN(prev):
+				out[i] = math.NaN()
+			case name == "diff":
+				out[i] = v - prev
+			case prev == 0:
+				out[i] = math.NaN()
+			default:
+				out[i] = (v - prev) / math.Abs(prev) * 100
+			}
+			if !math.IsNaN(v) {
+				prev = v
+			}
+		}
+	case "zscore":
+		var known []float64
+		for _, v := range values {
+			if !math.IsNaN(v) {
+				known = append(known, v)
+			}
+		}
+		mean, _ := aggregate.Reduce("avg", known)
+		stddev, _ := aggregate.Reduce("stddev", known)
+		for i, v := range values {
+			if stddev == 0 {
+				out[i] = gap(v, 0)
+			} else {
+				out[i] = (v - mean) / stddev
+			}
+		}
+	default:
+		copy(out, values)
+	}
+	return out
+}
+
+// gap returns NaN where the input is a gap, and v elsewhere.
+func gap(in, v float64) float64 {
+	if math.IsNaN(in) {
+		return in
+	}
+	return v
+}
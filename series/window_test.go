@@ -0,0 +1,52 @@
+package series
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{2, 4, nan, 8, 6}
+	sd := math.Sqrt(5)
+	tests := []struct {
+		name  string
+		param float64
+		want  []float64
+	}{
+		{"moving_avg", 2, []float64{2, 3, nan, 8, 7}},
+		{"ema", 0.5, []float64{2, 3, nan, 5.5, 5.75}},
+		{"cumsum", 0, []float64{2, 6, nan, 14, 20}},
+		{"diff", 0, []float64{nan, 2, nan, 4, -2}},
+		{"pct_change", 0, []float64{nan, 100, nan, 100, -25}},
+		{"zscore", 0, []float64{-3 / sd, -1 / sd, nan, 3 / sd, 1 / sd}},
+	}
+	for _, tt := range tests {
+		if err := ValidateTransform(tt.name, tt.param); err != nil {
+			t.Errorf("ValidateTransform(%q) failed: %v", tt.name, err)
+		}
+		got := Transform(tt.name, tt.param, values)
+		if negative := slices.ContainsFunc(got, func(v float64) bool { return v < 0 }); negative != Signed(tt.name) {
+			t.Errorf("Signed(%q) = %v, but the transform of positive values gave %v", tt.name, Signed(tt.name), got)
+		}
+		for i := range tt.want {
+			if !(math.Abs(got[i]-tt.want[i]) < 1e-9 || math.IsNaN(got[i]) && math.IsNaN(tt.want[i])) {
+				t.Errorf("%s = %v, expected %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+	if math.IsNaN(values[0]) || values[1] != 4 {
+		t.Errorf("Transform should not modify its input")
+	}
+
+	for _, step := range []struct {
+		name  string
+		param float64
+	}{{"moving_avg", 0}, {"moving_avg", 2.5}, {"ema", 1.5}, {"smooth", 0}} {
+		if err := ValidateTransform(step.name, step.param); err == nil {
+			t.Errorf("ValidateTransform(%q, %v) expected an error", step.name, step.param)
+		}
+	}
+}
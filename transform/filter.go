@@ -0,0 +1,29 @@
+package transform
+
+import (
+	"datacmd/expr"
+	"datacmd/loader"
+)
+
+// Filter returns the rows of data for which the expression is true. A nil
+// expression returns data unchanged.
+func Filter(data *loader.DataDataSource, e *expr.Expr) (*loader.DataDataSource, error) {
+	if e == nil {
+		return data, nil
+	}
+	program, err := e.Bind(data)
+	if err != nil {
+		return nil, err
+	}
+	records := make([][]string, 0, len(data.Records))
+	for _, record := range data.Records {
+		ok, err := program.Match(record)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			records = append(records, record)
+		}
+	}
+	return data.WithRecords(records), nil
+}
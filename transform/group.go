@@ -0,0 +1,178 @@
+// Package transform reshapes the rows of a data source before a widget
+// draws them.
+package transform
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"datacmd/aggregate"
+	"datacmd/loader"
+)
+
+// OtherLabel is the label of the group collecting everything outside TopN.
+const OtherLabel = "Other"
+
+// Group is the set of rows sharing the same value in the group column.
+type Group struct {
+	// Key is the value of the group column.
+	Key string
+	// Label is the value of the label column in the first row of the group.
+	Label string
+	// Value is the aggregated value of the group.
+	Value float64
+
+	// cells holds the raw value cells, so that groups can be merged.
+	cells []string
+}
+
+// GroupBy groups the rows of data by groupCol and reduces the cells of
+// valueCol with the named aggregation, sum by default; count counts the rows
+// and needs no value column. The label of each group comes from labelCol, or
+// from the key when labelCol is -1. Groups keep the order of their first row.
+func GroupBy(data *loader.DataDataSource, groupCol, labelCol, valueCol int, agg string) ([]Group, error) {
+	if groupCol < 0 {
+		return nil, fmt.Errorf("group column not found")
+	}
+	if valueCol < 0 && agg != "count" {
+		return nil, fmt.Errorf("aggregation '%s' needs a value column", aggName(agg))
+	}
+
+	var groups []Group
+	index := make(map[string]int)
+	for _, record := range data.Records {
+		key := record[groupCol]
+		i, ok := index[key]
+		if !ok {
+			label := key
+			if labelCol >= 0 {
+				label = record[labelCol]
+			}
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Key: key, Label: label})
+		}
+		if valueCol >= 0 {
+			groups[i].cells = append(groups[i].cells, record[valueCol])
+		} else {
+			groups[i].cells = append(groups[i].cells, "")
+		}
+	}
+
+	for i := range groups {
+		v, err := reduce(data, valueCol, groups[i].cells, agg)
+		if err != nil {
+			return nil, err
+		}
+		groups[i].Value = v
+	}
+	return groups, nil
+}
+
+// Pivot groups the rows of data by rowCol and, within each row group, by
+// seriesCol, reducing the cells of valueCol of each pair like GroupBy. It
+// returns the row and series keys in the order of their first row, and the
+// values indexed by series then row; pairs without any row are NaN.
+func Pivot(data *loader.DataDataSource, rowCol, seriesCol, valueCol int, agg string) (rows, series []string, values [][]float64, err error) {
+	if rowCol < 0 || seriesCol < 0 {
+		return nil, nil, nil, fmt.Errorf("group column not found")
+	}
+	if valueCol < 0 && agg != "count" {
+		return nil, nil, nil, fmt.Errorf("aggregation '%s' needs a value column", aggName(agg))
+	}
+
+	rowIndex := make(map[string]int)
+	seriesIndex := make(map[string]int)
+	var cells [][][]string
+	for _, record := range data.Records {
+		r, ok := rowIndex[record[rowCol]]
+		if !ok {
+			r = len(rows)
+			rowIndex[record[rowCol]] = r
+			rows = append(rows, record[rowCol])
+		}
+		s, ok := seriesIndex[record[seriesCol]]
+		if !ok {
+			s = len(series)
+			seriesIndex[record[seriesCol]] = s
+			series = append(series, record[seriesCol])
+			cells = append(cells, nil)
+		}
+		for len(cells[s]) < len(rows) {
+			cells[s] = append(cells[s], nil)
+		}
+		cell := ""
+		if valueCol >= 0 {
+			cell = record[valueCol]
+		}
+		cells[s][r] = append(cells[s][r], cell)
+	}
+
+	values = make([][]float64, len(series))
+	for s := range series {
+		values[s] = make([]float64, len(rows))
+		for r := range rows {
+			if r >= len(cells[s]) || len(cells[s][r]) == 0 {
+				values[s][r] = math.NaN()
+				continue
+			}
+			if values[s][r], err = reduce(data, valueCol, cells[s][r], agg); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	return rows, series, values, nil
+}
+
+// TopN keeps the n groups with the largest values, in descending order, and
+// merges the remaining ones into a single OtherLabel group aggregated from
+// their original rows. A non-positive n returns the groups unchanged.
+func TopN(data *loader.DataDataSource, groups []Group, n int, valueCol int, agg string) ([]Group, error) {
+	if n <= 0 || len(groups) <= n {
+		return groups, nil
+	}
+	sorted := append([]Group(nil), groups...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Value > sorted[j].Value
+	})
+
+	other := Group{Key: OtherLabel, Label: OtherLabel}
+	for _, g := range sorted[n:] {
+		other.cells = append(other.cells, g.cells...)
+	}
+	v, err := reduce(data, valueCol, other.cells, agg)
+	if err != nil {
+		return nil, err
+	}
+	other.Value = v
+	return append(sorted[:n:n], other), nil
+}
+
+// ValidateAggregation reports an aggregation that GroupBy doesn't support.
+func ValidateAggregation(agg string) error {
+	return aggregate.ValidateStateless(agg)
+}
+
+// reduce aggregates the raw cells of a group. Groups without any number are
+// zero.
+func reduce(data *loader.DataDataSource, valueCol int, cells []string, agg string) (float64, error) {
+	v, err := aggregate.Cells(aggName(agg), cells, func(s string) (float64, error) {
+		return data.ParseNumber(valueCol, s)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) {
+		return 0, nil
+	}
+	return v, nil
+}
+
+// aggName returns the aggregation used for groups, which defaults to sum.
+func aggName(agg string) string {
+	if agg == "" {
+		return "sum"
+	}
+	return agg
+}
@@ -0,0 +1,67 @@
+package transform
+
+import (
+	"math"
+	"testing"
+
+	"datacmd/loader"
+)
+
+func TestGroupBy_TopN(t *testing.T) {
+	data := &loader.DataDataSource{
+		Header: []string{"region", "amount"},
+		Records: [][]string{
+			{"EU", "10"}, {"US", "30"}, {"EU", "5"}, {"APAC", "2"}, {"LATAM", "1"}, {"US", "x"},
+		},
+	}
+	groups, err := GroupBy(data, 0, -1, 1, "sum")
+	if err != nil {
+		t.Fatalf("GroupBy failed: %v", err)
+	}
+	if len(groups) != 4 || groups[0].Key != "EU" || groups[0].Value != 15 || groups[1].Value != 30 {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+
+	top, err := TopN(data, groups, 2, 1, "sum")
+	if err != nil {
+		t.Fatalf("TopN failed: %v", err)
+	}
+	if len(top) != 3 || top[0].Key != "US" || top[2].Label != OtherLabel || top[2].Value != 3 {
+		t.Errorf("unexpected top groups %+v", top)
+	}
+
+	counts, err := GroupBy(data, 0, -1, 1, "count")
+	if err != nil {
+		t.Fatalf("GroupBy count failed: %v", err)
+	}
+	if counts[1].Value != 2 {
+		t.Errorf("expected 2 rows for US, got %v", counts[1].Value)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	data := &loader.DataDataSource{
+		Header: []string{"month", "region", "amount"},
+		Records: [][]string{
+			{"jan", "EU", "10"}, {"jan", "US", "30"}, {"feb", "EU", "5"}, {"jan", "EU", "2"}, {"feb", "APAC", "x"},
+		},
+	}
+	rows, series, values, err := Pivot(data, 0, 1, 2, "")
+	if err != nil {
+		t.Fatalf("Pivot failed: %v", err)
+	}
+	if len(rows) != 2 || rows[1] != "feb" || len(series) != 3 || series[2] != "APAC" {
+		t.Fatalf("unexpected keys %v and %v", rows, series)
+	}
+	want := [][]float64{{12, 5}, {30, math.NaN()}, {math.NaN(), 0}}
+	for s := range want {
+		for r := range want[s] {
+			if got := values[s][r]; got != want[s][r] && !(math.IsNaN(got) && math.IsNaN(want[s][r])) {
+				t.Errorf("value of %s in %s = %v, expected %v", series[s], rows[r], values[s][r], want[s][r])
+			}
+		}
+	}
+	if _, _, _, err := Pivot(data, 0, -1, 2, ""); err == nil {
+		t.Errorf("expected an error without a series column")
+	}
+}
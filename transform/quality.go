@@ -0,0 +1,35 @@
+package transform
+
+import "datacmd/loader"
+
+// Quality counts the rows of data a widget leaves out because a cell of one
+// of its numeric columns cols is empty or not a number, or a cell of one of
+// its time columns times isn't a timestamp, and the cells of the numeric
+// columns replaced by their on_invalid policy when the data was loaded.
+func Quality(data *loader.DataDataSource, cols, times []int) (dropped, imputed int) {
+	for _, record := range data.Records {
+		if !validRow(data, record, cols, times) {
+			dropped++
+		}
+	}
+	for _, col := range cols {
+		imputed += data.Imputed(col)
+	}
+	return dropped, imputed
+}
+
+// validRow reports whether the cells of a record in the numeric columns
+// cols are numbers, and those in the time columns times are timestamps.
+func validRow(data *loader.DataDataSource, record []string, cols, times []int) bool {
+	for _, col := range cols {
+		if _, err := data.ParseNumber(col, record[col]); err != nil {
+			return false
+		}
+	}
+	for _, col := range times {
+		if _, err := data.ParseTime(col, record[col]); err != nil {
+			return false
+		}
+	}
+	return true
+}
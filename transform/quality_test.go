@@ -0,0 +1,28 @@
+package transform
+
+import (
+	"testing"
+
+	"datacmd/loader"
+)
+
+func TestQuality(t *testing.T) {
+	data := &loader.DataDataSource{
+		Header: []string{"name", "x", "y", "at"},
+		Records: [][]string{
+			{"a", "1", "2", "2024-03-01"},
+			{"b", "", "2", "2024-03-02"},
+			{"c", "n/a", "oops", "2024-03-03"},
+			{"d", "4", "5", "yesterday"},
+		},
+	}
+	if dropped, imputed := Quality(data, []int{1, 2}, nil); dropped != 2 || imputed != 0 {
+		t.Errorf("Quality = %d dropped, %d imputed; expected 2 dropped", dropped, imputed)
+	}
+	if dropped, _ := Quality(data, []int{1}, []int{3}); dropped != 3 {
+		t.Errorf("rows with an invalid timestamp should be dropped, got %d dropped", dropped)
+	}
+	if dropped, _ := Quality(data, nil, nil); dropped != 0 {
+		t.Errorf("widgets without numeric columns drop no rows, got %d", dropped)
+	}
+}
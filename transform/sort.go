@@ -0,0 +1,100 @@
+package transform
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+
+	"datacmd/loader"
+)
+
+// GroupField selects what SortGroups orders the groups by.
+type GroupField int
+
+const (
+	ByValue GroupField = iota
+	ByKey
+	ByLabel
+)
+
+// SortRows returns the rows of data ordered by the cells of col, keeping the
+// order of equal rows.
+func SortRows(data *loader.DataDataSource, col int, desc bool) *loader.DataDataSource {
+	if col < 0 {
+		return data
+	}
+	parse := func(s string) (float64, error) { return data.ParseNumber(col, s) }
+	records := append([][]string(nil), data.Records...)
+	sort.SliceStable(records, func(i, j int) bool {
+		return lessCell(records[i][col], records[j][col], parse, desc)
+	})
+	return data.WithRecords(records)
+}
+
+// LimitRows returns the first n rows of data. A non-positive n returns data
+// unchanged.
+func LimitRows(data *loader.DataDataSource, n int) *loader.DataDataSource {
+	if n <= 0 || len(data.Records) <= n {
+		return data
+	}
+	return data.WithRecords(data.Records[:n:n])
+}
+
+// SortGroups returns the groups ordered by value, key or label, keeping the
+// order of equal groups.
+func SortGroups(groups []Group, by GroupField, desc bool) []Group {
+	sorted := append([]Group(nil), groups...)
+	parse := func(s string) (float64, error) { return loader.ParseNumber(s, "") }
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch by {
+		case ByKey:
+			return lessCell(a.Key, b.Key, parse, desc)
+		case ByLabel:
+			return lessCell(a.Label, b.Label, parse, desc)
+		}
+		if desc {
+			return a.Value > b.Value
+		}
+		return a.Value < b.Value
+	})
+	return sorted
+}
+
+// LimitGroups returns the first n groups. A non-positive n returns groups
+// unchanged.
+func LimitGroups(groups []Group, n int) []Group {
+	if n <= 0 || len(groups) <= n {
+		return groups
+	}
+	return groups[:n:n]
+}
+
+// lessCell orders two cells: numbers by value and before text, and text
+// alphabetically ignoring case. desc reverses the order, but empty cells
+// always come last.
+func lessCell(a, b string, parse func(string) (float64, error), desc bool) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if (a == "") != (b == "") {
+		return b == ""
+	}
+	c := compareCells(a, b, parse)
+	if desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+func compareCells(a, b string, parse func(string) (float64, error)) int {
+	x, errA := parse(a)
+	y, errB := parse(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
@@ -0,0 +1,55 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"datacmd/loader"
+)
+
+func TestSortRows(t *testing.T) {
+	data := &loader.DataDataSource{
+		Header:  []string{"host", "errors"},
+		Records: [][]string{{"a", "10"}, {"b", ""}, {"c", "9"}, {"d", "1,200"}, {"e", "n/a"}, {"f", "9"}},
+	}
+	hosts := func(d *loader.DataDataSource) []string {
+		var out []string
+		for _, r := range d.Records {
+			out = append(out, r[0])
+		}
+		return out
+	}
+	if got, want := hosts(SortRows(data, 1, false)), []string{"c", "f", "a", "d", "e", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ascending = %v, expected %v", got, want)
+	}
+	if got, want := hosts(LimitRows(SortRows(data, 1, true), 3)), []string{"e", "d", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("descending top 3 = %v, expected %v", got, want)
+	}
+	if data.Records[0][0] != "a" {
+		t.Errorf("SortRows should not modify its input")
+	}
+}
+
+func TestSortGroups(t *testing.T) {
+	groups := []Group{
+		{Key: "3", Label: "3. Buy", Value: 5},
+		{Key: "1", Label: "1. Visit", Value: 100},
+		{Key: "2", Label: "2. Signup", Value: 20},
+	}
+	labels := func(groups []Group) []string {
+		var out []string
+		for _, g := range groups {
+			out = append(out, g.Label)
+		}
+		return out
+	}
+	if got, want := labels(SortGroups(groups, ByKey, false)), []string{"1. Visit", "2. Signup", "3. Buy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("by key = %v, expected %v", got, want)
+	}
+	if got, want := labels(LimitGroups(SortGroups(groups, ByValue, true), 2)), []string{"1. Visit", "2. Signup"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("top 2 by value = %v, expected %v", got, want)
+	}
+	if got, want := labels(SortGroups(groups, ByLabel, true)), []string{"3. Buy", "2. Signup", "1. Visit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("by label descending = %v, expected %v", got, want)
+	}
+}
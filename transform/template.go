@@ -0,0 +1,73 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"datacmd/loader"
+)
+
+// Template formats a row as a line of text, replacing the names of columns
+// between braces with their cells, e.g. "{time} [{level}] {message}".
+// Literal braces are written twice.
+type Template struct {
+	// parts alternate between literal text and column indexes.
+	parts []templatePart
+}
+
+// templatePart is a literal text, or the cell of a column when col isn't -1.
+type templatePart struct {
+	text string
+	col  int
+}
+
+// ParseTemplate parses a template and checks its columns against data.
+func ParseTemplate(text string, data *loader.DataDataSource) (*Template, error) {
+	t := &Template{}
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, templatePart{text: literal.String(), col: -1})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case strings.HasPrefix(text[i:], "{{"), strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed '{' in template '%s'", text)
+			}
+			name := strings.TrimSpace(text[i+1 : i+end])
+			col := data.ColumnIndex(name)
+			if col == -1 {
+				return nil, fmt.Errorf("column '%s' of template '%s' not found", name, text)
+			}
+			flush()
+			t.parts = append(t.parts, templatePart{col: col})
+			i += end
+		case c == '}':
+			return nil, fmt.Errorf("unexpected '}' in template '%s', write '}}' for a brace", text)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return t, nil
+}
+
+// Format formats a row.
+func (t *Template) Format(record []string) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.col == -1 {
+			b.WriteString(p.text)
+		} else if p.col < len(record) {
+			b.WriteString(record[p.col])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package transform
+
+import (
+	"testing"
+
+	"datacmd/loader"
+)
+
+func TestTemplate(t *testing.T) {
+	data := &loader.DataDataSource{Header: []string{"time", "level", "message"}}
+	tmpl, err := ParseTemplate("{time} [{ level }] {message} {{ok}}", data)
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+	if got, want := tmpl.Format([]string{"12:00", "warn", "disk full"}), "12:00 [warn] disk full {ok}"; got != want {
+		t.Errorf("Format = %q, expected %q", got, want)
+	}
+
+	for _, bad := range []string{"{host}", "{time", "time}"} {
+		if _, err := ParseTemplate(bad, data); err == nil {
+			t.Errorf("ParseTemplate(%q) succeeded, expected an error", bad)
+		}
+	}
+}
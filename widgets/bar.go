@@ -0,0 +1,535 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// lowerBlocks fill the bottom eighths of a cell, from one to seven, and
+// leftBlocks their left eighths.
+var (
+	lowerBlocks = []rune("▁▂▃▄▅▆▇")
+	leftBlocks  = []rune("▏▎▍▌▋▊▉")
+)
+
+// BarSeries is a named series of a BarChart, with a value for each label.
+type BarSeries struct {
+	Name   string
+	Values []float64
+	Color  cell.Color
+}
+
+// BarChartOption is used to provide options to the bar chart widget.
+type BarChartOption interface {
+	set(*barChartOptions)
+}
+
+// barChartOptions stores the provided options.
+type barChartOptions struct {
+	// max is the value of a full bar, 0 for the largest bar.
+	max        float64
+	horizontal bool
+	stacked    bool
+	legend     LegendPlacement
+	// legendSet is true when the placement was given, in which case the
+	// legend is shown even for a single series.
+	legendSet bool
+}
+
+// validate checks the provided options.
+func (o *barChartOptions) validate() error {
+	if o.max < 0 || math.IsNaN(o.max) {
+		return fmt.Errorf("the maximum must be positive, got %v", o.max)
+	}
+	return nil
+}
+
+// withBarMax is a private type that implements the BarChartOption interface.
+type withBarMax struct {
+	max float64
+}
+
+func (w *withBarMax) set(opts *barChartOptions) {
+	opts.max = w.max
+}
+
+// BarMax sets the value of a full bar above the baseline; larger values are
+// cut. By default it is the largest bar, so that it fills the widget.
+func BarMax(max float64) BarChartOption {
+	return &withBarMax{max: max}
+}
+
+// withBarHorizontal is a private type that implements the BarChartOption interface.
+type withBarHorizontal struct{}
+
+func (*withBarHorizontal) set(opts *barChartOptions) {
+	opts.horizontal = true
+}
+
+// BarHorizontal draws the bars from left to right, with their labels on
+// the left, which leaves room for long labels.
+func BarHorizontal() BarChartOption {
+	return &withBarHorizontal{}
+}
+
+// withBarStacked is a private type that implements the BarChartOption interface.
+type withBarStacked struct{}
+
+func (*withBarStacked) set(opts *barChartOptions) {
+	opts.stacked = true
+}
+
+// BarStacked stacks the series of each label in a single bar. By default
+// they are drawn side by side.
+func BarStacked() BarChartOption {
+	return &withBarStacked{}
+}
+
+// withBarLegend is a private type that implements the BarChartOption interface.
+type withBarLegend struct {
+	placement LegendPlacement
+}
+
+func (w *withBarLegend) set(opts *barChartOptions) {
+	opts.legend = w.placement
+	opts.legendSet = true
+}
+
+// BarLegend places the legend of the series. By default it is placed
+// automatically when there are several series.
+func BarLegend(placement LegendPlacement) BarChartOption {
+	return &withBarLegend{placement: placement}
+}
+
+// BarChart draws a labeled bar for each value of one or several series,
+// side by side or stacked, vertically or horizontally. Bars are drawn to
+// an eighth of a cell; missing values are drawn as zero. Negative values
+// are drawn down, or left, from a baseline at zero, and stacked apart from
+// the positive ones.
+type BarChart struct {
+	mu sync.Mutex
+
+	// labels names the bars, or the groups of bars of the series.
+	labels []string
+	series []BarSeries
+
+	opts *barChartOptions
+}
+
+// NewBarChart returns a new BarChart widget.
+func NewBarChart(opts ...BarChartOption) (*BarChart, error) {
+	opt := &barChartOptions{}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	return &BarChart{opts: opt}, nil
+}
+
+// Values replaces the bars of the chart. Each series must have a value for
+// each label.
+func (b *BarChart) Values(labels []string, series []BarSeries) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range series {
+		if len(s.Values) != len(labels) {
+			return fmt.Errorf("series '%s' has %d values for %d labels", s.Name, len(s.Values), len(labels))
+		}
+	}
+	b.labels = labels
+	b.series = series
+	return nil
+}
+
+// barValue returns the value drawn for v: missing values are drawn as zero.
+func barValue(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+// scale returns the value of a full bar above the baseline, and the
+// magnitude of the lowest bar below it, zero when no value is negative.
+func (b *BarChart) scale() (hi, lo float64) {
+	for i := range b.labels {
+		for _, bar := range b.bars(i) {
+			up, down := bar.split()
+			hi, lo = max(hi, up.total()), max(lo, down.total())
+		}
+	}
+	if b.opts.max > 0 {
+		hi = b.opts.max
+	}
+	if hi == 0 && lo == 0 {
+		return 1, 0
+	}
+	return hi, lo
+}
+
+// splitCells divides the cells of the value axis between the bars above
+// and below the baseline, in proportion to the scale, leaving at least a
+// cell to each side in use.
+func splitCells(cells int, hi, lo float64) (up, down int) {
+	if lo == 0 || cells < 2 {
+		return cells, 0
+	}
+	if hi == 0 {
+		return 0, cells
+	}
+	down = int(math.Round(float64(cells) * lo / (hi + lo)))
+	down = max(1, min(down, cells-1))
+	return cells - down, down
+}
+
+// bar is a single bar of the chart: a value, or the stacked values of the
+// series.
+type bar struct {
+	values []float64
+	colors []cell.Color
+}
+
+// total returns the value of the bar, the sum of its segments.
+func (b bar) total() float64 {
+	sum := 0.0
+	for _, v := range b.values {
+		sum += v
+	}
+	return sum
+}
+
+// split returns the positive segments of a bar and the magnitudes of its
+// negative ones, each stacked from the baseline.
+func (b bar) split() (up, down bar) {
+	for i, v := range b.values {
+		if v < 0 {
+			down.values = append(down.values, -v)
+			down.colors = append(down.colors, b.colors[i])
+		} else {
+			up.values = append(up.values, v)
+			up.colors = append(up.colors, b.colors[i])
+		}
+	}
+	return up, down
+}
+
+// bars returns the bars drawn for the label at index i.
+func (b *BarChart) bars(i int) []bar {
+	if b.opts.stacked {
+		var stack bar
+		for _, s := range b.series {
+			stack.values = append(stack.values, barValue(s.Values[i]))
+			stack.colors = append(stack.colors, s.Color)
+		}
+		return []bar{stack}
+	}
+	bars := make([]bar, len(b.series))
+	for j, s := range b.series {
+		bars[j] = bar{values: []float64{barValue(s.Values[i])}, colors: []cell.Color{s.Color}}
+	}
+	return bars
+}
+
+// eighths returns the boundaries of the segments of a bar, in eighths of a
+// cell for a full bar of the given number of cells.
+func (b bar) eighths(full float64, cells int) []int {
+	bounds := make([]int, len(b.values))
+	sum := 0.0
+	for i, v := range b.values {
+		sum += v
+		bounds[i] = min(int(math.Round(sum/full*float64(cells*8))), cells*8)
+	}
+	return bounds
+}
+
+// cellAt returns the rune and color filling the cell at the given index of
+// a bar from its base, given the boundaries of its segments, and false past
+// its end.
+func (b bar) cellAt(bounds []int, index int, partial []rune) (rune, cell.Color, bool) {
+	lo := index * 8
+	if len(bounds) == 0 || bounds[len(bounds)-1] <= lo {
+		return 0, 0, false
+	}
+	top := bounds[len(bounds)-1]
+	// A cell shared by segments takes the color of its middle.
+	mid := lo + (min(top, lo+8)-lo)/2
+	color := b.colors[len(b.colors)-1]
+	for i, bound := range bounds {
+		if bound > mid {
+			color = b.colors[i]
+			break
+		}
+	}
+	if top >= lo+8 {
+		return '█', color, true
+	}
+	return partial[top-lo-1], color, true
+}
+
+// fill draws a bar from the baseline over at most the given number of
+// cells, a full bar being worth full, calling set with the distance of
+// each cell from the baseline. It returns the number of cells drawn and the
+// color of the last one. The partial blocks only fill the side of a cell
+// towards the baseline of bars going up or right, so those of inverted bars
+// are drawn with the opposite block in inverse video.
+func (b bar) fill(full float64, cells int, partial []rune, inverted bool, set func(c int, r rune, opts ...cell.Option) error) (int, cell.Color, error) {
+	if cells == 0 || full == 0 {
+		return 0, 0, nil
+	}
+	bounds := b.eighths(full, cells)
+	var last cell.Color
+	for c := 0; c < cells; c++ {
+		r, color, ok := b.cellAt(bounds, c, partial)
+		if !ok {
+			return c, last, nil
+		}
+		last = color
+		opts := []cell.Option{cell.FgColor(color)}
+		if inverted && r != '█' {
+			r = partial[len(partial)-1-slices.Index(partial, r)]
+			opts = append(opts, cell.Inverse())
+		}
+		if err := set(c, r, opts...); err != nil {
+			return 0, 0, err
+		}
+	}
+	return cells, last, nil
+}
+
+// legendEntries lists the series, when there is a legend.
+func (b *BarChart) legendEntries() []LegendEntry {
+	if len(b.series) < 2 && !b.opts.legendSet {
+		return nil
+	}
+	entries := make([]LegendEntry, len(b.series))
+	for i, s := range b.series {
+		entries[i] = LegendEntry{Color: s.Color, Text: s.Name}
+	}
+	return entries
+}
+
+// Draw draws the BarChart widget onto the canvas.
+func (b *BarChart) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.labels) == 0 || len(b.series) == 0 {
+		return nil
+	}
+	entries := b.legendEntries()
+	chartAr, legendAr := splitLegend(cvs.Area(), entries, b.opts.legend)
+	drawBars := b.drawVertical
+	if b.opts.horizontal {
+		drawBars = b.drawHorizontal
+	}
+	if err := drawBars(cvs, chartAr); err != nil {
+		return err
+	}
+	return drawLegend(cvs, legendAr, entries)
+}
+
+// drawVertical draws the bars upwards from the baseline, or downwards for
+// negative values, with the labels on the bottom row. Labels that don't fit
+// the width are left out.
+func (b *BarChart) drawVertical(cvs *canvas.Canvas, ar image.Rectangle) error {
+	height := ar.Dy() - 1
+	if height < 1 {
+		return nil
+	}
+	perLabel := len(b.bars(0))
+	n := len(b.labels)
+	// Each label takes its bars and a gap.
+	width := ((ar.Dx()+1)/n - 1) / perLabel
+	if width < 1 {
+		width = 1
+		n = min(n, (ar.Dx()+1)/(perLabel+1))
+	}
+	hi, lo := b.scale()
+	up, down := splitCells(height, hi, lo)
+	// base is the row of the first cell above the baseline.
+	base := ar.Min.Y + up - 1
+
+	for i := 0; i < n; i++ {
+		x := ar.Min.X + i*(perLabel*width+1)
+		for j, bar := range b.bars(i) {
+			bx := x + j*width
+			row := func(y int, r rune, opts ...cell.Option) error {
+				for dx := 0; dx < width; dx++ {
+					if _, err := cvs.SetCell(image.Point{X: bx + dx, Y: y}, r, opts...); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			pos, neg := bar.split()
+			ups, _, err := pos.fill(hi, up, lowerBlocks, false, func(c int, r rune, opts ...cell.Option) error {
+				return row(base-c, r, opts...)
+			})
+			if err != nil {
+				return err
+			}
+			downs, _, err := neg.fill(lo, down, lowerBlocks, true, func(c int, r rune, opts ...cell.Option) error {
+				return row(base+1+c, r, opts...)
+			})
+			if err != nil {
+				return err
+			}
+			// The value sits on top of its bar, or below it when negative,
+			// when there is room.
+			total := bar.total()
+			value := formatBarValue(total)
+			if runewidth.StringWidth(value) > width {
+				continue
+			}
+			switch {
+			case total >= 0 && ups < up:
+				err = drawCentered(cvs, value, bx, width, base-ups, cell.ColorDefault)
+			case total < 0 && downs < down:
+				err = drawCentered(cvs, value, bx, width, base+1+downs, cell.ColorDefault)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := drawCentered(cvs, b.labels[i], x, perLabel*width, ar.Max.Y-1, cell.ColorGreen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawHorizontal draws the bars rightwards from the baseline, or leftwards
+// for negative values, with the labels on their left. Labels that don't fit
+// the height are left out.
+func (b *BarChart) drawHorizontal(cvs *canvas.Canvas, ar image.Rectangle) error {
+	labelWidth := 0
+	for _, label := range b.labels {
+		labelWidth = max(labelWidth, runewidth.StringWidth(label))
+	}
+	labelWidth = min(labelWidth, ar.Dx()/3)
+	x := ar.Min.X + labelWidth
+	if labelWidth > 0 {
+		x++
+	}
+	length := ar.Max.X - x
+	if length < 1 {
+		return nil
+	}
+	perLabel := len(b.bars(0))
+	// Labels are separated by an empty row when they all fit with it.
+	rows := perLabel + 1
+	if len(b.labels)*rows-1 > ar.Dy() {
+		rows = perLabel
+	}
+	n := min(len(b.labels), (ar.Dy()+rows-perLabel)/rows)
+	hi, lo := b.scale()
+	right, left := splitCells(length, hi, lo)
+	// base is the column of the first cell right of the baseline.
+	base := x + left
+
+	for i := 0; i < n; i++ {
+		y := ar.Min.Y + i*rows
+		if labelWidth > 0 {
+			if err := draw.Text(cvs, b.labels[i], image.Point{X: ar.Min.X, Y: y},
+				draw.TextMaxX(ar.Min.X+labelWidth), draw.TextOverrunMode(draw.OverrunModeThreeDot),
+				draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+				return err
+			}
+		}
+		for j, bar := range b.bars(i) {
+			by := y + j
+			pos, neg := bar.split()
+			rights, lastRight, err := pos.fill(hi, right, leftBlocks, false, func(c int, r rune, opts ...cell.Option) error {
+				_, err := cvs.SetCell(image.Point{X: base + c, Y: by}, r, opts...)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			lefts, lastLeft, err := neg.fill(lo, left, leftBlocks, true, func(c int, r rune, opts ...cell.Option) error {
+				_, err := cvs.SetCell(image.Point{X: base - 1 - c, Y: by}, r, opts...)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			// The value follows its bar, or precedes it when negative, or
+			// ends it when there is no room.
+			total := bar.total()
+			value := formatBarValue(total)
+			w := runewidth.StringWidth(value)
+			inside := draw.TextCellOpts(cell.FgColor(cell.ColorBlack), cell.BgColor(lastRight))
+			if total < 0 {
+				inside = draw.TextCellOpts(cell.FgColor(cell.ColorBlack), cell.BgColor(lastLeft))
+			}
+			switch {
+			case total >= 0 && base+rights+1+w <= ar.Max.X:
+				err = draw.Text(cvs, value, image.Point{X: base + rights + 1, Y: by})
+			case total >= 0 && w <= rights:
+				err = draw.Text(cvs, value, image.Point{X: base + rights - w, Y: by}, inside)
+			case total < 0 && base-lefts-1-w >= x:
+				err = draw.Text(cvs, value, image.Point{X: base - lefts - 1 - w, Y: by})
+			case total < 0 && w <= lefts:
+				err = draw.Text(cvs, value, image.Point{X: base - lefts, Y: by}, inside)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// drawCentered writes text centered in the given columns of a row, trimming
+// it with three dots when it doesn't fit.
+func drawCentered(cvs *canvas.Canvas, text string, x, width, y int, color cell.Color) error {
+	if width < 1 || text == "" {
+		return nil
+	}
+	x += max(0, (width-runewidth.StringWidth(text))/2)
+	return draw.Text(cvs, text, image.Point{X: x, Y: y},
+		draw.TextMaxX(min(x+width, cvs.Area().Max.X)), draw.TextOverrunMode(draw.OverrunModeThreeDot),
+		draw.TextCellOpts(cell.FgColor(color)))
+}
+
+// formatBarValue writes whole values as such and others with up to 4
+// significant digits.
+func formatBarValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e9 {
+		return fmt.Sprintf("%d", int64(v))
+	}
+	return formatAxisValue(v)
+}
+
+// Keyboard input isn't supported on the BarChart widget.
+func (*BarChart) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	return errors.New("the BarChart widget doesn't support keyboard events")
+}
+
+// Mouse input isn't supported on the BarChart widget.
+func (*BarChart) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the BarChart widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (b *BarChart) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{3, 2},
+		WantKeyboard: widgetapi.KeyScopeNone,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
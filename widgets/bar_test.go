@@ -0,0 +1,103 @@
+package widgets
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+func TestBarChart_Scale(t *testing.T) {
+	series := []BarSeries{
+		{Name: "EU", Values: []float64{120, math.NaN()}, Color: cell.ColorRed},
+		{Name: "US", Values: []float64{30, -5}, Color: cell.ColorBlue},
+	}
+	tests := []struct {
+		name   string
+		opts   []BarChartOption
+		hi, lo float64
+	}{
+		{"side by side", nil, 120, 5},
+		{"stacked", []BarChartOption{BarStacked()}, 150, 5},
+		{"configured", []BarChartOption{BarMax(100)}, 100, 5},
+	}
+	for _, tt := range tests {
+		bc, err := NewBarChart(tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: failed to create bar chart: %v", tt.name, err)
+		}
+		if err := bc.Values([]string{"jan", "feb"}, series); err != nil {
+			t.Fatalf("%s: Values failed: %v", tt.name, err)
+		}
+		if hi, lo := bc.scale(); hi != tt.hi || lo != tt.lo {
+			t.Errorf("%s: scale() = %v, %v, expected %v, %v", tt.name, hi, lo, tt.hi, tt.lo)
+		}
+	}
+
+	bc, _ := NewBarChart()
+	if err := bc.Values([]string{"jan"}, series); err == nil {
+		t.Errorf("expected an error for series longer than the labels")
+	}
+}
+
+func TestBar_CellAt(t *testing.T) {
+	b := bar{values: []float64{3, 2}, colors: []cell.Color{cell.ColorRed, cell.ColorBlue}}
+	bounds := b.eighths(10, 2)
+	if bounds[0] != 5 || bounds[1] != 8 {
+		t.Fatalf("unexpected bounds %v", bounds)
+	}
+	if r, color, ok := b.cellAt(bounds, 0, lowerBlocks); !ok || r != '█' || color != cell.ColorRed {
+		t.Errorf("unexpected first cell %q %v %v", r, color, ok)
+	}
+	if _, _, ok := b.cellAt(bounds, 1, lowerBlocks); ok {
+		t.Errorf("expected the second cell to be empty")
+	}
+	bounds = b.eighths(5, 2)
+	if r, color, _ := b.cellAt(bounds, 1, lowerBlocks); r != '█' || color != cell.ColorBlue {
+		t.Errorf("unexpected top cell %q %v", r, color)
+	}
+	bounds = b.eighths(8, 1)
+	if r, _, _ := b.cellAt(bounds, 0, lowerBlocks); r != '▅' {
+		t.Errorf("expected a partial cell, got %q", r)
+	}
+}
+
+func TestSplitCells(t *testing.T) {
+	tests := []struct {
+		cells    int
+		hi, lo   float64
+		up, down int
+	}{
+		{10, 100, 0, 10, 0},
+		{10, 80, 20, 8, 2},
+		{10, 100, 1, 9, 1},
+		{10, 0, 4, 0, 10},
+		{1, 10, 10, 1, 0},
+	}
+	for _, tt := range tests {
+		if up, down := splitCells(tt.cells, tt.hi, tt.lo); up != tt.up || down != tt.down {
+			t.Errorf("splitCells(%d, %v, %v) = %d, %d, expected %d, %d", tt.cells, tt.hi, tt.lo, up, down, tt.up, tt.down)
+		}
+	}
+}
+
+func TestBar_FillNegative(t *testing.T) {
+	b := bar{values: []float64{4, -3}, colors: []cell.Color{cell.ColorRed, cell.ColorBlue}}
+	if b.total() != 1 {
+		t.Errorf("the label of a bar should show its real value, got %v", b.total())
+	}
+	_, down := b.split()
+	var runes []rune
+	cells, last, err := down.fill(4, 2, lowerBlocks, true, func(c int, r rune, opts ...cell.Option) error {
+		runes = append(runes, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 of 4 fill 12 of 16 eighths: a full cell, then the top half of the
+	// next one, drawn as an inverted lower half.
+	if cells != 2 || last != cell.ColorBlue || string(runes) != "█▄" {
+		t.Errorf("got %d cells %q of color %v", cells, string(runes), last)
+	}
+}
@@ -0,0 +1,418 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// outlierRune marks an outlier.
+const outlierRune = '•'
+
+// BoxplotBox is the distribution of the values of a category of a Boxplot:
+// its quartiles, the ends of its whiskers and the values beyond them.
+type BoxplotBox struct {
+	Label          string
+	Q1, Median, Q3 float64
+	Low, High      float64
+	Outliers       []float64
+}
+
+// BoxplotOption is used to provide options to the boxplot widget.
+type BoxplotOption interface {
+	set(*boxplotOptions)
+}
+
+// boxplotOptions stores the provided options.
+type boxplotOptions struct {
+	vertical bool
+}
+
+// withBoxplotVertical is a private type that implements the BoxplotOption interface.
+type withBoxplotVertical struct{}
+
+func (*withBoxplotVertical) set(opts *boxplotOptions) {
+	opts.vertical = true
+}
+
+// BoxplotVertical draws the boxes side by side with the values going up,
+// instead of one below the other with the values going right.
+func BoxplotVertical() BoxplotOption {
+	return &withBoxplotVertical{}
+}
+
+// Boxplot draws the distributions of the values of several categories as
+// boxes spanning their first to third quartiles, split at the median, with
+// whiskers and outliers, against a shared numeric axis. The boxes are drawn
+// with box-drawing characters, or as solid bars when there is not enough
+// room.
+type Boxplot struct {
+	mu sync.Mutex
+
+	boxes []BoxplotBox
+
+	opts *boxplotOptions
+}
+
+// NewBoxplot returns a new Boxplot widget.
+func NewBoxplot(opts ...BoxplotOption) (*Boxplot, error) {
+	opt := &boxplotOptions{}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	return &Boxplot{opts: opt}, nil
+}
+
+// Boxes replaces the boxes, drawn in order.
+func (b *Boxplot) Boxes(boxes []BoxplotBox) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, box := range boxes {
+		if !(box.Low <= box.Q1 && box.Q1 <= box.Median && box.Median <= box.Q3 && box.Q3 <= box.High) {
+			return fmt.Errorf("the box of '%s' is not ordered: %v, %v, %v, %v, %v", box.Label, box.Low, box.Q1, box.Median, box.Q3, box.High)
+		}
+	}
+	b.boxes = boxes
+	return nil
+}
+
+// valueRange returns the range of the values of the boxes, never empty.
+func (b *Boxplot) valueRange() (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, box := range b.boxes {
+		lo, hi = math.Min(lo, box.Low), math.Max(hi, box.High)
+		for _, v := range box.Outliers {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if lo == hi {
+		lo, hi = lo-1, hi+1
+	}
+	return lo, hi
+}
+
+// niceTicks returns up to about n round values between lo and hi, spaced by
+// 1, 2 or 5 times a power of ten.
+func niceTicks(lo, hi float64, n int) []float64 {
+	if n < 1 || hi <= lo {
+		return nil
+	}
+	raw := (hi - lo) / float64(n)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * magnitude
+	for _, m := range []float64{1, 2, 5} {
+		if m*magnitude >= raw {
+			step = m * magnitude
+			break
+		}
+	}
+	var ticks []float64
+	for i := math.Ceil(lo / step); i*step <= hi+step*1e-9; i++ {
+		// Multiplying avoids accumulating rounding errors, and adding zero
+		// turns -0 into 0.
+		ticks = append(ticks, i*step+0)
+	}
+	return ticks
+}
+
+// boxPositions are the positions of the values of a box along the value
+// axis, in cells.
+type boxPositions struct {
+	low, q1, median, q3, high int
+}
+
+// boxRune returns the rune drawing a box at the position p along the value
+// axis and across it at the given offset: -1 for the top or left side of
+// the box, 0 for the middle and 1 for the bottom or right side. Compact
+// boxes only have a middle, where the box is a solid bar and its median is
+// drawn on the color of the bar, in which case inverse is true.
+func boxRune(b boxPositions, p, across int, vertical, compact bool) (r rune, inverse, ok bool) {
+	inBox := p >= b.q1 && p <= b.q3
+	if compact {
+		switch {
+		case p == b.median && vertical:
+			return '━', true, true
+		case p == b.median:
+			return '┃', true, true
+		case inBox:
+			return '█', false, true
+		}
+	} else if across != 0 {
+		if !inBox {
+			return 0, false, false
+		}
+		// The runes of the side at q1, q3, the median and in between.
+		var side [4]rune
+		switch {
+		case vertical && across < 0:
+			side = [4]rune{'└', '┌', '├', '│'}
+		case vertical:
+			side = [4]rune{'┘', '┐', '┤', '│'}
+		case across < 0:
+			side = [4]rune{'┌', '┐', '┬', '─'}
+		default:
+			side = [4]rune{'└', '┘', '┴', '─'}
+		}
+		switch p {
+		case b.median:
+			return side[2], false, true
+		case b.q1:
+			return side[0], false, true
+		case b.q3:
+			return side[1], false, true
+		}
+		return side[3], false, true
+	} else {
+		// The median, the ends of the box joining the whiskers, and the
+		// inside of the box.
+		var middle [3]rune
+		switch {
+		case vertical:
+			middle = [3]rune{'─', '┬', '┴'}
+		default:
+			middle = [3]rune{'│', '┤', '├'}
+		}
+		switch {
+		case p == b.median:
+			return middle[0], false, true
+		case p == b.q1 && b.low < b.q1:
+			return middle[1], false, true
+		case p == b.q1:
+			return middle[0], false, true
+		case p == b.q3 && b.high > b.q3:
+			return middle[2], false, true
+		case p == b.q3:
+			return middle[0], false, true
+		case inBox:
+			return 0, false, false
+		}
+	}
+
+	// The whiskers and their ends.
+	switch {
+	case p == b.low && vertical:
+		return '┴', false, true
+	case p == b.low:
+		return '├', false, true
+	case p == b.high && vertical:
+		return '┬', false, true
+	case p == b.high:
+		return '┤', false, true
+	case p > b.low && p < b.high && vertical:
+		return '│', false, true
+	case p > b.low && p < b.high:
+		return '─', false, true
+	}
+	return 0, false, false
+}
+
+// scale returns the position of values along an axis of the given length.
+func scale(lo, hi float64, length int) func(float64) int {
+	return func(v float64) int {
+		return int(math.Round((v - lo) / (hi - lo) * float64(length-1)))
+	}
+}
+
+// Draw draws the Boxplot widget onto the canvas.
+func (b *Boxplot) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.boxes) == 0 {
+		return nil
+	}
+	if b.opts.vertical {
+		return b.drawVertical(cvs)
+	}
+	return b.drawHorizontal(cvs)
+}
+
+// drawBox draws a box along the value axis, with at(p, across) returning
+// the cell at a position along the axis and an offset across it.
+func (b *Boxplot) drawBox(cvs *canvas.Canvas, box BoxplotBox, pos func(float64) int, length int, at func(p, across int) image.Point, compact bool, color cell.Color) error {
+	for _, v := range box.Outliers {
+		if _, err := cvs.SetCell(at(pos(v), 0), outlierRune, cell.FgColor(color)); err != nil {
+			return err
+		}
+	}
+	bp := boxPositions{pos(box.Low), pos(box.Q1), pos(box.Median), pos(box.Q3), pos(box.High)}
+	across := []int{-1, 0, 1}
+	if compact {
+		across = []int{0}
+	}
+	for _, a := range across {
+		for p := bp.low; p <= bp.high && p < length; p++ {
+			r, inverse, ok := boxRune(bp, p, a, b.opts.vertical, compact)
+			if !ok {
+				continue
+			}
+			opts := []cell.Option{cell.FgColor(color)}
+			if inverse {
+				opts = []cell.Option{cell.FgColor(cell.ColorBlack), cell.BgColor(color)}
+			}
+			if _, err := cvs.SetCell(at(p, a), r, opts...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// drawHorizontal draws the boxes one below the other, labeled on the left,
+// with the values going right on an axis at the bottom.
+func (b *Boxplot) drawHorizontal(cvs *canvas.Canvas) error {
+	ar := cvs.Area()
+	gutter := 0
+	for _, box := range b.boxes {
+		gutter = max(gutter, runewidth.StringWidth(box.Label))
+	}
+	gutter = min(gutter, ar.Dx()/3) + 1
+	x0 := ar.Min.X + gutter
+	// The last column leaves room for the label of the last tick.
+	length := ar.Max.X - 1 - x0
+	plotRows := ar.Dy() - 2
+	if length < 2 || plotRows < 1 {
+		return nil
+	}
+
+	// Boxes get three rows and a gap when there is room, and are compact
+	// otherwise.
+	slot := min(4, plotRows/len(b.boxes))
+	compact := slot < 3
+	slot = max(1, slot)
+	lo, hi := b.valueRange()
+	pos := scale(lo, hi, length)
+	for i, box := range b.boxes[:min(len(b.boxes), plotRows/slot)] {
+		y := ar.Min.Y + i*slot
+		if !compact {
+			y++
+		}
+		color := Palette[i%len(Palette)]
+		if err := draw.Text(cvs, box.Label, image.Point{X: ar.Min.X, Y: y}, draw.TextMaxX(x0-1),
+			draw.TextOverrunMode(draw.OverrunModeThreeDot), draw.TextCellOpts(cell.FgColor(color))); err != nil {
+			return err
+		}
+		at := func(p, across int) image.Point { return image.Point{X: x0 + p, Y: y + across} }
+		if err := b.drawBox(cvs, box, pos, length, at, compact, color); err != nil {
+			return err
+		}
+	}
+
+	// The axis, with ticks spaced by the width of their labels.
+	axisY := ar.Max.Y - 2
+	axis := []draw.HVLine{{Start: image.Point{X: x0, Y: axisY}, End: image.Point{X: x0 + length - 1, Y: axisY}}}
+	if err := draw.HVLines(cvs, axis, draw.HVLineCellOpts(cell.FgColor(cell.ColorRed))); err != nil {
+		return err
+	}
+	labelWidth := max(runewidth.StringWidth(formatAxisValue(lo)), runewidth.StringWidth(formatAxisValue(hi))) + 2
+	end := ar.Min.X
+	for _, v := range niceTicks(lo, hi, max(1, length/labelWidth)) {
+		x := x0 + pos(v)
+		if _, err := cvs.SetCell(image.Point{X: x, Y: axisY}, '┬', cell.FgColor(cell.ColorRed)); err != nil {
+			return err
+		}
+		label := formatAxisValue(v)
+		lx := x - runewidth.StringWidth(label)/2
+		if lx < end || lx+runewidth.StringWidth(label) > ar.Max.X {
+			continue
+		}
+		if err := draw.Text(cvs, label, image.Point{X: lx, Y: ar.Max.Y - 1}, draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+		end = lx + runewidth.StringWidth(label) + 1
+	}
+	return nil
+}
+
+// drawVertical draws the boxes side by side, labeled below, with the values
+// going up on an axis on the left.
+func (b *Boxplot) drawVertical(cvs *canvas.Canvas) error {
+	ar := cvs.Area()
+	lo, hi := b.valueRange()
+	length := ar.Dy() - 1
+	if length < 2 {
+		return nil
+	}
+	ticks := niceTicks(lo, hi, max(1, length/2))
+	gutter := 0
+	for _, v := range ticks {
+		gutter = max(gutter, runewidth.StringWidth(formatAxisValue(v)))
+	}
+	gutter++
+	x0 := ar.Min.X + gutter
+	if ar.Max.X-x0 < 2 {
+		return nil
+	}
+	pos := scale(lo, hi, length)
+	bottom := ar.Max.Y - 2
+
+	axis := []draw.HVLine{{Start: image.Point{X: x0 - 1, Y: ar.Min.Y}, End: image.Point{X: x0 - 1, Y: bottom}}}
+	if err := draw.HVLines(cvs, axis, draw.HVLineCellOpts(cell.FgColor(cell.ColorRed))); err != nil {
+		return err
+	}
+	for _, v := range ticks {
+		y := bottom - pos(v)
+		if _, err := cvs.SetCell(image.Point{X: x0 - 1, Y: y}, '┤', cell.FgColor(cell.ColorRed)); err != nil {
+			return err
+		}
+		label := formatAxisValue(v)
+		if err := draw.Text(cvs, label, image.Point{X: x0 - 1 - runewidth.StringWidth(label), Y: y},
+			draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+	}
+
+	// Boxes are three columns wide, with a gap, when there is room, and
+	// compact otherwise. The widest labels fit in the slot of their box.
+	width := ar.Max.X - x0
+	widest := 0
+	for _, box := range b.boxes {
+		widest = max(widest, runewidth.StringWidth(box.Label))
+	}
+	slot := min(width/len(b.boxes), max(8, widest+2))
+	compact := slot < 4
+	slot = max(2, slot)
+	for i, box := range b.boxes[:min(len(b.boxes), width/slot)] {
+		x := x0 + i*slot + (slot-1)/2
+		color := Palette[i%len(Palette)]
+		at := func(p, across int) image.Point { return image.Point{X: x + across, Y: bottom - p} }
+		if err := b.drawBox(cvs, box, pos, length, at, compact, color); err != nil {
+			return err
+		}
+		lx := x0 + i*slot + max(0, slot-1-runewidth.StringWidth(box.Label))/2
+		if err := draw.Text(cvs, box.Label, image.Point{X: lx, Y: ar.Max.Y - 1}, draw.TextMaxX(min(ar.Max.X, x0+(i+1)*slot-1)),
+			draw.TextOverrunMode(draw.OverrunModeTrim), draw.TextCellOpts(cell.FgColor(color))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keyboard input isn't supported on the Boxplot widget.
+func (*Boxplot) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	return errors.New("the Boxplot widget doesn't support keyboard events")
+}
+
+// Mouse input isn't supported on the Boxplot widget.
+func (*Boxplot) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Boxplot widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (b *Boxplot) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{10, 4},
+		WantKeyboard: widgetapi.KeyScopeNone,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
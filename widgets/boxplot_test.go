@@ -0,0 +1,73 @@
+package widgets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNiceTicks(t *testing.T) {
+	tests := []struct {
+		lo, hi float64
+		n      int
+		want   []float64
+	}{
+		{0, 10, 5, []float64{0, 2, 4, 6, 8, 10}},
+		{13, 742, 7, []float64{200, 400, 600}},
+		{13, 742, 8, []float64{100, 200, 300, 400, 500, 600, 700}},
+		{-0.3, 0.35, 4, []float64{-0.2, 0, 0.2}},
+		{1, 1, 3, nil},
+	}
+	for _, tt := range tests {
+		if got := niceTicks(tt.lo, tt.hi, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("niceTicks(%v, %v, %d) = %v, expected %v", tt.lo, tt.hi, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBoxRune(t *testing.T) {
+	b := boxPositions{low: 0, q1: 2, median: 4, q3: 6, high: 8}
+	draw := func(across int, vertical, compact bool) string {
+		var s []rune
+		for p := 0; p <= 8; p++ {
+			r, _, ok := boxRune(b, p, across, vertical, compact)
+			if !ok {
+				r = ' '
+			}
+			s = append(s, r)
+		}
+		return string(s)
+	}
+	tests := []struct {
+		across            int
+		vertical, compact bool
+		want              string
+	}{
+		{-1, false, false, "  ┌─┬─┐  "},
+		{0, false, false, "├─┤ │ ├─┤"},
+		{1, false, false, "  └─┴─┘  "},
+		{0, true, false, "┴│┬ ─ ┴│┬"},
+		{-1, true, false, "  └│├│┌  "},
+		{0, false, true, "├─██┃██─┤"},
+	}
+	for _, tt := range tests {
+		if got := draw(tt.across, tt.vertical, tt.compact); got != tt.want {
+			t.Errorf("box across %d, vertical %v, compact %v = %q, expected %q", tt.across, tt.vertical, tt.compact, got, tt.want)
+		}
+	}
+}
+
+func TestBoxplot_Boxes(t *testing.T) {
+	b, err := NewBoxplot()
+	if err != nil {
+		t.Fatalf("failed to create boxplot: %v", err)
+	}
+	if err := b.Boxes([]BoxplotBox{{Label: "api", Low: 1, Q1: 2, Median: 3, Q3: 4, High: 5, Outliers: []float64{-4, 9}}}); err != nil {
+		t.Fatalf("Boxes failed: %v", err)
+	}
+	if lo, hi := b.valueRange(); lo != -4 || hi != 9 {
+		t.Errorf("valueRange = %v, %v, expected -4, 9", lo, hi)
+	}
+	if err := b.Boxes([]BoxplotBox{{Label: "bad", Low: 1, Q1: 3, Median: 2, Q3: 4, High: 5}}); err == nil {
+		t.Error("Boxes accepted a median below the first quartile")
+	}
+}
@@ -0,0 +1,271 @@
+package widgets
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// CalendarDefaultGradient is the color ramp of calendars when none is
+// configured.
+const CalendarDefaultGradient = "github"
+
+const (
+	// calendarLabelWidth is the width of the weekday labels.
+	calendarLabelWidth = 4
+	// calendarWeekWidth is the width of a week: a square and a space.
+	calendarWeekWidth = 2
+	// calendarSquare marks a day.
+	calendarSquare = '■'
+)
+
+// calendarLevels are the positions on the gradient of the squares of the
+// scale, from the days without any value to the largest one.
+var calendarLevels = []float64{0, 0.25, 0.5, 0.75, 1}
+
+// CalendarOption is used to provide options to the calendar widget.
+type CalendarOption interface {
+	set(*calendarOptions)
+}
+
+// calendarOptions stores the provided options.
+type calendarOptions struct {
+	gradient Gradient
+}
+
+// withCalendarGradient is a private type that implements the CalendarOption interface.
+type withCalendarGradient struct {
+	gradient Gradient
+}
+
+func (w *withCalendarGradient) set(opts *calendarOptions) {
+	opts.gradient = w.gradient
+}
+
+// CalendarGradient sets the color ramp of the days, from zero to the largest
+// value. It defaults to CalendarDefaultGradient.
+func CalendarGradient(g Gradient) CalendarOption {
+	return &withCalendarGradient{gradient: g}
+}
+
+// Calendar draws a value per day as a GitHub style calendar: a column per
+// week, starting on Monday, and a row per weekday, with the months labeled
+// above. It shows the most recent weeks that fit the width, up to today, so
+// that the days without activity since the last value are seen, or up to
+// the last day of the values when it is later.
+type Calendar struct {
+	mu sync.Mutex
+
+	// start is the day of the first value.
+	start time.Time
+	// values holds a value per day; days without any are NaN.
+	values []float64
+	// end is the last day drawn.
+	end time.Time
+	// hi is the largest value.
+	hi float64
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+
+	opts *calendarOptions
+}
+
+// NewCalendar returns a new Calendar widget.
+func NewCalendar(opts ...CalendarOption) (*Calendar, error) {
+	gradient, err := ParseGradient([]string{CalendarDefaultGradient})
+	if err != nil {
+		return nil, err
+	}
+	opt := &calendarOptions{gradient: gradient}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	return &Calendar{now: time.Now, opts: opt}, nil
+}
+
+// Values replaces the days of the calendar, the first of which is start.
+func (c *Calendar) Values(start time.Time, values []float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(values) > 0 && start.IsZero() {
+		return errors.New("the start of the values cannot be empty")
+	}
+	hi := 0.0
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			hi = math.Max(hi, v)
+		}
+	}
+	y, m, d := start.Date()
+	c.start = time.Date(y, m, d, 0, 0, 0, 0, start.Location())
+	c.values = values
+	c.hi = hi
+	y, m, d = c.now().In(start.Location()).Date()
+	c.end = time.Date(y, m, d, 0, 0, 0, 0, start.Location())
+	if last := c.start.AddDate(0, 0, len(values)-1); last.After(c.end) {
+		c.end = last
+	}
+	return nil
+}
+
+// dayIndex returns the number of calendar days from start to t, which is
+// not a multiple of 24 hours across daylight saving changes.
+func dayIndex(start, t time.Time) int {
+	y, m, d := start.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = t.Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// firstWeek returns the Monday of the first of the given number of weeks
+// ending with the week of the last day drawn.
+func (c *Calendar) firstWeek(weeks int) time.Time {
+	monday := c.end.AddDate(0, 0, -(int(c.end.Weekday())+6)%7)
+	return monday.AddDate(0, 0, -7*(weeks-1))
+}
+
+// level returns the position on the gradient of a day, and false for the
+// days after the last one drawn. Days without a value are at zero, and the
+// others above the first quarter so that they stand out.
+func (c *Calendar) level(day time.Time) (float64, bool) {
+	if dayIndex(c.end, day) > 0 {
+		return 0, false
+	}
+	i := dayIndex(c.start, day)
+	if i < 0 || i >= len(c.values) || math.IsNaN(c.values[i]) || c.values[i] <= 0 || c.hi <= 0 {
+		return 0, true
+	}
+	return 0.25 + 0.75*c.values[i]/c.hi, true
+}
+
+// Draw draws the Calendar widget onto the canvas.
+func (c *Calendar) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.values) == 0 {
+		return nil
+	}
+	ar := cvs.Area()
+	weeks := (ar.Dx() - calendarLabelWidth) / calendarWeekWidth
+	if weeks < 1 || ar.Dy() < 7 {
+		return nil
+	}
+	// The month labels and the scale are left out of short widgets.
+	top := ar.Min.Y
+	if ar.Dy() >= 8 {
+		top++
+	}
+	first := c.firstWeek(weeks)
+
+	for d, name := range []string{"Mon", "", "Wed", "", "Fri", "", ""} {
+		if name == "" {
+			continue
+		}
+		if err := draw.Text(cvs, name, image.Point{X: ar.Min.X, Y: top + d}, draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+	}
+	labelEnd := 0
+	for w := 0; w < weeks; w++ {
+		x := ar.Min.X + calendarLabelWidth + w*calendarWeekWidth
+		monday := first.AddDate(0, 0, 7*w)
+		for d := 0; d < 7; d++ {
+			day := monday.AddDate(0, 0, d)
+			t, ok := c.level(day)
+			if !ok {
+				break
+			}
+			if _, err := cvs.SetCell(image.Point{X: x, Y: top + d}, calendarSquare, cell.FgColor(c.opts.gradient.At(t))); err != nil {
+				return err
+			}
+		}
+
+		if top == ar.Min.Y || x < labelEnd {
+			continue
+		}
+		name, ok := monthLabel(monday, w == 0)
+		if !ok {
+			continue
+		}
+		if err := draw.Text(cvs, name, image.Point{X: x, Y: ar.Min.Y},
+			draw.TextMaxX(ar.Max.X), draw.TextOverrunMode(draw.OverrunModeTrim), draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+		labelEnd = x + len(name) + 1
+	}
+
+	if top+7 < ar.Max.Y {
+		return c.drawScale(cvs, image.Point{X: ar.Min.X + calendarLabelWidth, Y: top + 7}, ar.Max.X)
+	}
+	return nil
+}
+
+// monthLabel returns the name of the month starting in the week of monday.
+// The first week drawn is labeled with its own month when the next one is at
+// least two weeks away, leaving room for the name.
+func monthLabel(monday time.Time, first bool) (string, bool) {
+	sunday := monday.AddDate(0, 0, 6)
+	if sunday.Day() <= 7 {
+		return sunday.Month().String()[:3], true
+	}
+	next := time.Date(monday.Year(), monday.Month()+1, 1, 0, 0, 0, 0, monday.Location())
+	if first && dayIndex(monday, next) >= 14 {
+		return monday.Month().String()[:3], true
+	}
+	return "", false
+}
+
+// drawScale draws the colors of the levels between zero and the largest
+// value.
+func (c *Calendar) drawScale(cvs *canvas.Canvas, at image.Point, maxX int) error {
+	text := func(s string, opts ...cell.Option) error {
+		if at.X >= maxX {
+			return nil
+		}
+		if err := draw.Text(cvs, s, at, draw.TextMaxX(maxX), draw.TextOverrunMode(draw.OverrunModeTrim), draw.TextCellOpts(opts...)); err != nil {
+			return err
+		}
+		at.X += runewidth.StringWidth(s)
+		return nil
+	}
+	if err := text("0 "); err != nil {
+		return err
+	}
+	for _, level := range calendarLevels {
+		if err := text(string(calendarSquare), cell.FgColor(c.opts.gradient.At(level))); err != nil {
+			return err
+		}
+	}
+	return text(" " + formatAxisValue(c.hi))
+}
+
+// Keyboard input isn't supported on the Calendar widget.
+func (*Calendar) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	return errors.New("the Calendar widget doesn't support keyboard events")
+}
+
+// Mouse input isn't supported on the Calendar widget.
+func (*Calendar) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Calendar widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (c *Calendar) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{calendarLabelWidth + calendarWeekWidth, 7},
+		WantKeyboard: widgetapi.KeyScopeNone,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
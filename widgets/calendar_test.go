@@ -0,0 +1,68 @@
+package widgets
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Level(t *testing.T) {
+	c, err := NewCalendar()
+	if err != nil {
+		t.Fatalf("failed to create calendar: %v", err)
+	}
+	// Wednesday 3 January to Sunday 7 January 2024, seen on the next
+	// Wednesday.
+	c.now = func() time.Time { return time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC) }
+	start := time.Date(2024, 1, 3, 15, 0, 0, 0, time.UTC)
+	if err := c.Values(start, []float64{4, math.NaN(), 0, 2, 1}); err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	if got, want := c.firstWeek(2), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("firstWeek(2) = %v, expected %v", got, want)
+	}
+	tests := []struct {
+		day   int
+		level float64
+		ok    bool
+	}{
+		{1, 0, true},
+		{3, 1, true},
+		{4, 0, true},
+		{6, 0.625, true},
+		{8, 0, true},
+		{10, 0, true},
+		{11, 0, false},
+	}
+	for _, tt := range tests {
+		level, ok := c.level(time.Date(2024, 1, tt.day, 0, 0, 0, 0, time.UTC))
+		if level != tt.level || ok != tt.ok {
+			t.Errorf("level of January %d = %v, %v, expected %v, %v", tt.day, level, ok, tt.level, tt.ok)
+		}
+	}
+
+	// Values after today are still drawn.
+	c.now = func() time.Time { return time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC) }
+	c.Values(start, []float64{4, math.NaN(), 0, 2, 1})
+	if _, ok := c.level(time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)); !ok {
+		t.Errorf("the last day of the values should be drawn")
+	}
+}
+
+func TestMonthLabel(t *testing.T) {
+	tests := []struct {
+		monday time.Time
+		first  bool
+		want   string
+	}{
+		{time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC), false, "Feb"},
+		{time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), false, ""},
+		{time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), true, "Feb"},
+		{time.Date(2024, 2, 19, 0, 0, 0, 0, time.UTC), true, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := monthLabel(tt.monday, tt.first); got != tt.want {
+			t.Errorf("monthLabel(%v, %v) = %q, expected %q", tt.monday, tt.first, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// candleWidth is the number of columns of a candle: its body and a gap.
+const candleWidth = 2
+
+// Candle is a candle of a Candlestick chart. Candles with NaN prices are
+// drawn as gaps.
+type Candle struct {
+	Label                  string
+	Open, High, Low, Close float64
+	// Volume is NaN when unknown.
+	Volume float64
+}
+
+// up reports whether the price closed at or above its open.
+func (c Candle) up() bool {
+	return c.Close >= c.Open
+}
+
+// CandlestickOption is used to provide options to the candlestick widget.
+type CandlestickOption interface {
+	set(*candlestickOptions)
+}
+
+// candlestickOptions stores the provided options.
+type candlestickOptions struct {
+	up, down cell.Color
+	volume   bool
+}
+
+// withCandleColors is a private type that implements the CandlestickOption interface.
+type withCandleColors struct {
+	up, down cell.Color
+}
+
+func (w *withCandleColors) set(opts *candlestickOptions) {
+	opts.up, opts.down = w.up, w.down
+}
+
+// CandleColors sets the colors of the candles closing at or above their
+// open, green by default, and of those closing below it, red by default.
+func CandleColors(up, down cell.Color) CandlestickOption {
+	return &withCandleColors{up: up, down: down}
+}
+
+// withCandleVolume is a private type that implements the CandlestickOption interface.
+type withCandleVolume struct{}
+
+func (*withCandleVolume) set(opts *candlestickOptions) {
+	opts.volume = true
+}
+
+// CandleVolume draws the volume of each candle in a panel below the prices.
+func CandleVolume() CandlestickOption {
+	return &withCandleVolume{}
+}
+
+// Candlestick draws the open, high, low and close prices of consecutive
+// periods as candles, with an optional panel of their volumes. It shows the
+// newest candles that fit the width; when the widget is focused, the left
+// and right arrow keys scroll through the older ones, and End goes back to
+// the newest.
+type Candlestick struct {
+	mu sync.Mutex
+
+	candles []Candle
+	// offset is the number of the newest candles scrolled out of view.
+	offset int
+
+	opts *candlestickOptions
+}
+
+// NewCandlestick returns a new Candlestick widget.
+func NewCandlestick(opts ...CandlestickOption) (*Candlestick, error) {
+	opt := &candlestickOptions{up: cell.ColorNumber(42), down: cell.ColorNumber(196)}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	return &Candlestick{opts: opt}, nil
+}
+
+// Candles replaces the candles, from the oldest to the newest. The view
+// keeps its distance from the newest candle, following new ones.
+func (c *Candlestick) Candles(candles []Candle) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, candle := range candles {
+		if candle.High < candle.Low {
+			return fmt.Errorf("candle '%s' has a high of %v below its low of %v", candle.Label, candle.High, candle.Low)
+		}
+	}
+	c.candles = candles
+	c.offset = max(0, min(c.offset, len(candles)-1))
+	return nil
+}
+
+// visible returns the range of the candles drawn in the given number of
+// columns.
+func (c *Candlestick) visible(columns int) (start, end int) {
+	end = len(c.candles) - c.offset
+	return max(0, end-columns/candleWidth), end
+}
+
+// priceRange returns the lowest low and highest high of candles, never
+// empty, and false when none has prices.
+func priceRange(candles []Candle) (float64, float64, bool) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range candles {
+		if !math.IsNaN(c.Low) && !math.IsNaN(c.High) {
+			lo, hi = math.Min(lo, c.Low), math.Max(hi, c.High)
+		}
+	}
+	if math.IsInf(lo, 0) {
+		return 0, 0, false
+	}
+	if lo == hi {
+		lo, hi = lo-1, hi+1
+	}
+	return lo, hi, true
+}
+
+// candleRune returns the rune drawing the two halves of a cell of a candle,
+// each of which may be covered by its body, its wick or neither.
+func candleRune(upperBody, lowerBody, upperWick, lowerWick bool) (rune, bool) {
+	switch {
+	case upperBody && lowerBody:
+		return '█', true
+	case upperBody:
+		return '▀', true
+	case lowerBody:
+		return '▄', true
+	case upperWick && lowerWick:
+		return '│', true
+	case upperWick:
+		return '╵', true
+	case lowerWick:
+		return '╷', true
+	}
+	return 0, false
+}
+
+// Draw draws the Candlestick widget onto the canvas.
+func (c *Candlestick) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ar := cvs.Area()
+	lo, hi, ok := priceRange(c.candles)
+	if !ok {
+		return nil
+	}
+	// The first row describes the newest candle drawn, and the last one
+	// holds the labels.
+	volumeRows := 0
+	if c.opts.volume && ar.Dy() >= 10 {
+		volumeRows = max(2, (ar.Dy()-2)/5)
+	}
+	prices := image.Rect(ar.Min.X, ar.Min.Y+1, ar.Max.X, ar.Max.Y-1-volumeRows)
+	if prices.Dy() < 2 {
+		return nil
+	}
+
+	// The gutter fits the labels of the whole range, so that it doesn't
+	// change while scrolling.
+	gutter := 0
+	for _, v := range []float64{lo, hi, (lo + hi) / 2} {
+		gutter = max(gutter, runewidth.StringWidth(formatAxisValue(v)))
+	}
+	gutter++
+	x0 := ar.Min.X + gutter
+	if ar.Max.X-x0 < candleWidth {
+		return nil
+	}
+	start, end := c.visible(ar.Max.X - x0)
+	shown := c.candles[start:end]
+	if lo, hi, ok = priceRange(shown); !ok {
+		lo, hi, _ = priceRange(c.candles)
+	}
+
+	if err := c.drawPriceAxis(cvs, prices, x0-1, lo, hi); err != nil {
+		return err
+	}
+	halves := prices.Dy() * 2
+	half := func(v float64) int {
+		return int(math.Round((hi - v) / (hi - lo) * float64(halves-1)))
+	}
+	for i, candle := range shown {
+		if math.IsNaN(candle.Open) || math.IsNaN(candle.Close) {
+			continue
+		}
+		color := c.opts.up
+		if !candle.up() {
+			color = c.opts.down
+		}
+		wickTop, wickBottom := half(candle.High), half(candle.Low)
+		bodyTop, bodyBottom := half(math.Max(candle.Open, candle.Close)), half(math.Min(candle.Open, candle.Close))
+		for r := 0; r < prices.Dy(); r++ {
+			upper, lower := 2*r, 2*r+1
+			ch, ok := candleRune(
+				upper >= bodyTop && upper <= bodyBottom, lower >= bodyTop && lower <= bodyBottom,
+				upper >= wickTop && upper <= wickBottom, lower >= wickTop && lower <= wickBottom,
+			)
+			if !ok {
+				continue
+			}
+			if _, err := cvs.SetCell(image.Point{X: x0 + i*candleWidth, Y: prices.Min.Y + r}, ch, cell.FgColor(color)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if volumeRows > 0 {
+		volumes := image.Rect(x0, prices.Max.Y, ar.Max.X, prices.Max.Y+volumeRows)
+		if err := c.drawVolumes(cvs, volumes, shown); err != nil {
+			return err
+		}
+	}
+	if err := c.drawLabels(cvs, x0, ar.Max.Y-1, start, shown); err != nil {
+		return err
+	}
+	if len(shown) == 0 {
+		return nil
+	}
+	return c.drawInfo(cvs, image.Point{X: ar.Min.X, Y: ar.Min.Y}, ar.Max.X, shown[len(shown)-1])
+}
+
+// drawPriceAxis draws the axis left of the candles at x, with the highest,
+// middle and lowest prices.
+func (c *Candlestick) drawPriceAxis(cvs *canvas.Canvas, prices image.Rectangle, x int, lo, hi float64) error {
+	axis := []draw.HVLine{{Start: image.Point{X: x, Y: prices.Min.Y}, End: image.Point{X: x, Y: prices.Max.Y - 1}}}
+	if err := draw.HVLines(cvs, axis, draw.HVLineCellOpts(cell.FgColor(cell.ColorRed))); err != nil {
+		return err
+	}
+	ticks := []struct {
+		y int
+		v float64
+	}{
+		{prices.Min.Y, hi},
+		{prices.Min.Y + (prices.Dy()-1)/2, hi - (hi-lo)*float64((prices.Dy()-1)/2)/float64(prices.Dy()-1)},
+		{prices.Max.Y - 1, lo},
+	}
+	for _, t := range ticks {
+		label := formatAxisValue(t.v)
+		at := image.Point{X: x - runewidth.StringWidth(label), Y: t.y}
+		if at.X < prices.Min.X {
+			continue
+		}
+		if err := draw.Text(cvs, label, at, draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawVolumes draws the volume of each candle as a bar in its color, scaled
+// to the largest volume drawn.
+func (c *Candlestick) drawVolumes(cvs *canvas.Canvas, area image.Rectangle, candles []Candle) error {
+	largest := 0.0
+	for _, candle := range candles {
+		if !math.IsNaN(candle.Volume) {
+			largest = math.Max(largest, candle.Volume)
+		}
+	}
+	if largest <= 0 {
+		return nil
+	}
+	for i, candle := range candles {
+		color := c.opts.up
+		if !candle.up() {
+			color = c.opts.down
+		}
+		b := bar{values: []float64{barValue(candle.Volume)}, colors: []cell.Color{color}}
+		bounds := b.eighths(largest, area.Dy())
+		for r := 0; r < area.Dy(); r++ {
+			ch, color, ok := b.cellAt(bounds, r, lowerBlocks)
+			if !ok {
+				break
+			}
+			if _, err := cvs.SetCell(image.Point{X: area.Min.X + i*candleWidth, Y: area.Max.Y - 1 - r}, ch, cell.FgColor(color)); err != nil {
+				return err
+			}
+		}
+	}
+	// The largest volume is written where there is no candle.
+	label := formatAxisValue(largest)
+	if w := runewidth.StringWidth(label); w+1 < area.Min.X {
+		return draw.Text(cvs, label, image.Point{X: area.Min.X - 1 - w, Y: area.Min.Y}, draw.TextCellOpts(cell.FgColor(cell.ColorNumber(244))))
+	}
+	return nil
+}
+
+// drawLabels writes the labels of the candles on the given row, at regular
+// intervals of the candle index so that they don't move while scrolling.
+func (c *Candlestick) drawLabels(cvs *canvas.Canvas, x, y, start int, candles []Candle) error {
+	widest := 0
+	for _, candle := range candles {
+		widest = max(widest, runewidth.StringWidth(candle.Label))
+	}
+	if widest == 0 {
+		return nil
+	}
+	every := (widest + candleWidth) / candleWidth
+	maxX := cvs.Area().Max.X
+	for i, candle := range candles {
+		at := image.Point{X: x + i*candleWidth, Y: y}
+		if (start+i)%every != 0 || at.X+runewidth.StringWidth(candle.Label) > maxX {
+			continue
+		}
+		if err := draw.Text(cvs, candle.Label, at, draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawInfo describes a candle: its label, prices and volume, with the close
+// in the color of the candle.
+func (c *Candlestick) drawInfo(cvs *canvas.Canvas, at image.Point, maxX int, candle Candle) error {
+	if math.IsNaN(candle.Close) {
+		return nil
+	}
+	var info strings.Builder
+	if candle.Label != "" {
+		info.WriteString(candle.Label + "  ")
+	}
+	fmt.Fprintf(&info, "O %s  H %s  L %s  ", formatAxisValue(candle.Open), formatAxisValue(candle.High), formatAxisValue(candle.Low))
+	close := "C " + formatAxisValue(candle.Close)
+	if !math.IsNaN(candle.Volume) {
+		close += "  V " + formatAxisValue(candle.Volume)
+	}
+	if c.offset > 0 {
+		close += fmt.Sprintf("  (%d newer)", c.offset)
+	}
+	color := c.opts.up
+	if !candle.up() {
+		color = c.opts.down
+	}
+	if err := draw.Text(cvs, info.String(), at, draw.TextMaxX(maxX), draw.TextOverrunMode(draw.OverrunModeTrim)); err != nil {
+		return err
+	}
+	at.X += runewidth.StringWidth(info.String())
+	if at.X >= maxX {
+		return nil
+	}
+	return draw.Text(cvs, close, at, draw.TextMaxX(maxX), draw.TextOverrunMode(draw.OverrunModeTrim), draw.TextCellOpts(cell.FgColor(color)))
+}
+
+// Keyboard scrolls through the candles with the arrow keys, and back to the
+// newest ones with End.
+func (c *Candlestick) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch k.Key {
+	case keyboard.KeyArrowLeft:
+		c.offset = max(0, min(c.offset+1, len(c.candles)-1))
+	case keyboard.KeyArrowRight:
+		c.offset = max(0, c.offset-1)
+	case keyboard.KeyEnd:
+		c.offset = 0
+	}
+	return nil
+}
+
+// Mouse input isn't supported on the Candlestick widget.
+func (*Candlestick) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Candlestick widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (c *Candlestick) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{12, 6},
+		WantKeyboard: widgetapi.KeyScopeFocused,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
@@ -0,0 +1,82 @@
+package widgets
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+func TestCandlestick_Scroll(t *testing.T) {
+	c, err := NewCandlestick()
+	if err != nil {
+		t.Fatalf("failed to create candlestick: %v", err)
+	}
+	candles := make([]Candle, 10)
+	for i := range candles {
+		candles[i] = Candle{Open: 1, High: 2, Low: 0, Close: 1.5, Volume: math.NaN()}
+	}
+	if err := c.Candles(candles); err != nil {
+		t.Fatalf("Candles failed: %v", err)
+	}
+	if start, end := c.visible(8); start != 6 || end != 10 {
+		t.Errorf("visible(8) = %d, %d, expected 6, 10", start, end)
+	}
+	left := &terminalapi.Keyboard{Key: keyboard.KeyArrowLeft}
+	for i := 0; i < 3; i++ {
+		if err := c.Keyboard(left, nil); err != nil {
+			t.Fatalf("Keyboard failed: %v", err)
+		}
+	}
+	if start, end := c.visible(8); start != 3 || end != 7 {
+		t.Errorf("visible(8) after scrolling = %d, %d, expected 3, 7", start, end)
+	}
+	// The view stays three candles behind the newest one.
+	if err := c.Candles(append(candles, candles[0])); err != nil {
+		t.Fatalf("Candles failed: %v", err)
+	}
+	if start, end := c.visible(8); start != 4 || end != 8 {
+		t.Errorf("visible(8) after a new candle = %d, %d, expected 4, 8", start, end)
+	}
+
+	if err := c.Candles([]Candle{{Label: "bad", High: 1, Low: 2}}); err == nil {
+		t.Error("Candles accepted a high below the low")
+	}
+}
+
+func TestPriceRange(t *testing.T) {
+	nan := math.NaN()
+	lo, hi, ok := priceRange([]Candle{{High: 3, Low: 1}, {High: nan, Low: nan}, {High: 5, Low: 2}})
+	if lo != 1 || hi != 5 || !ok {
+		t.Errorf("priceRange = %v, %v, %v, expected 1, 5, true", lo, hi, ok)
+	}
+	if lo, hi, ok = priceRange([]Candle{{High: 2, Low: 2}}); lo != 1 || hi != 3 || !ok {
+		t.Errorf("priceRange of a flat candle = %v, %v, %v, expected 1, 3, true", lo, hi, ok)
+	}
+	if _, _, ok = priceRange([]Candle{{High: nan, Low: nan}}); ok {
+		t.Error("priceRange of gaps is not empty")
+	}
+}
+
+func TestCandleRune(t *testing.T) {
+	tests := []struct {
+		upperBody, lowerBody, upperWick, lowerWick bool
+		want                                       rune
+	}{
+		{true, true, true, true, '█'},
+		{true, false, true, true, '▀'},
+		{false, true, true, true, '▄'},
+		{false, false, true, true, '│'},
+		{false, false, true, false, '╵'},
+		{false, false, false, true, '╷'},
+	}
+	for _, tt := range tests {
+		if got, _ := candleRune(tt.upperBody, tt.lowerBody, tt.upperWick, tt.lowerWick); got != tt.want {
+			t.Errorf("candleRune(%v, %v, %v, %v) = %q, expected %q", tt.upperBody, tt.lowerBody, tt.upperWick, tt.lowerWick, got, tt.want)
+		}
+	}
+	if _, ok := candleRune(false, false, false, false); ok {
+		t.Error("candleRune drew an empty cell")
+	}
+}
@@ -0,0 +1,141 @@
+package widgets
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+// DefaultGradient is the color scale of heatmaps when none is configured.
+const DefaultGradient = "viridis"
+
+// gradients are the named color scales, from the lowest value to the
+// highest.
+var gradients = map[string][]string{
+	"viridis": {"#440154", "#3b528b", "#21918c", "#5ec962", "#fde725"},
+	"magma":   {"#000004", "#51127c", "#b73779", "#fc8961", "#fcfdbf"},
+	"heat":    {"#1a1a1a", "#8b0000", "#ff4500", "#ffa500", "#ffff66"},
+	"greens":  {"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"},
+	"github":  {"#161b22", "#0e4429", "#006d32", "#26a641", "#39d353"},
+	"blues":   {"#eff3ff", "#bdd7e7", "#6baed6", "#3182bd", "#08519c"},
+	"reds":    {"#fee5d9", "#fcae91", "#fb6a4a", "#de2d26", "#a50f15"},
+	"gray":    {"#262626", "#eeeeee"},
+}
+
+// rgb is a color of a gradient.
+type rgb struct {
+	r, g, b float64
+}
+
+// Gradient maps values between 0 and 1 to colors, interpolating between
+// evenly spaced color stops. Terminal colors are limited to the 256 color
+// palette, so the colors are rounded to the nearest one.
+type Gradient struct {
+	stops []rgb
+}
+
+// ParseGradient returns the gradient named by spec, one of viridis, magma,
+// heat, greens, github, blues, reds or gray, or going through two or more
+// colors such as "#000080" and "#ffff00". An empty spec is DefaultGradient.
+func ParseGradient(spec []string) (Gradient, error) {
+	if len(spec) == 0 {
+		spec = []string{DefaultGradient}
+	}
+	if len(spec) == 1 {
+		named, ok := gradients[strings.ToLower(spec[0])]
+		if !ok {
+			return Gradient{}, fmt.Errorf("unknown color scale '%s', use one of viridis, magma, heat, greens, github, blues, reds, gray or a list of colors", spec[0])
+		}
+		spec = named
+	}
+	g := Gradient{stops: make([]rgb, len(spec))}
+	for i, s := range spec {
+		c, err := parseHexColor(s)
+		if err != nil {
+			return Gradient{}, err
+		}
+		g.stops[i] = c
+	}
+	return g, nil
+}
+
+// parseHexColor parses a "#rrggbb" color.
+func parseHexColor(s string) (rgb, error) {
+	hex := strings.TrimPrefix(s, "#")
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return rgb{}, fmt.Errorf("invalid color '%s', expected #rrggbb", s)
+	}
+	return rgb{float64(n >> 16), float64(n >> 8 & 0xff), float64(n & 0xff)}, nil
+}
+
+// at returns the color at t, clamped to [0, 1].
+func (g Gradient) at(t float64) rgb {
+	if len(g.stops) == 0 {
+		return rgb{}
+	}
+	if len(g.stops) == 1 || math.IsNaN(t) || t <= 0 {
+		return g.stops[0]
+	}
+	if t >= 1 {
+		return g.stops[len(g.stops)-1]
+	}
+	pos := t * float64(len(g.stops)-1)
+	i := int(pos)
+	frac := pos - float64(i)
+	a, b := g.stops[i], g.stops[i+1]
+	return rgb{a.r + (b.r-a.r)*frac, a.g + (b.g-a.g)*frac, a.b + (b.b-a.b)*frac}
+}
+
+// At returns the terminal color at t, a value between 0 and 1.
+func (g Gradient) At(t float64) cell.Color {
+	return g.at(t).color()
+}
+
+// Contrast returns black or white, whichever is more readable on the color
+// at t.
+func (g Gradient) Contrast(t float64) cell.Color {
+	c := g.at(t)
+	if 0.299*c.r+0.587*c.g+0.114*c.b > 140 {
+		return cell.ColorBlack
+	}
+	return cell.ColorWhite
+}
+
+// cubeLevels are the intensities of the 6x6x6 color cube of the 256 color
+// palette.
+var cubeLevels = []float64{0, 95, 135, 175, 215, 255}
+
+// color returns the nearest color of the 256 color palette, from the color
+// cube or the grayscale ramp.
+func (c rgb) color() cell.Color {
+	nearest := func(v float64) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if math.Abs(level-v) < math.Abs(cubeLevels[best]-v) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearest(c.r), nearest(c.g), nearest(c.b)
+	cube := rgb{cubeLevels[r], cubeLevels[g], cubeLevels[b]}
+
+	// The grayscale ramp goes from 8 to 238 in steps of 10.
+	mean := (c.r + c.g + c.b) / 3
+	step := int(math.Round((mean - 8) / 10))
+	step = max(0, min(23, step))
+	gray := float64(8 + 10*step)
+	if c.distance(rgb{gray, gray, gray}) < c.distance(cube) {
+		return cell.ColorNumber(232 + step)
+	}
+	return cell.ColorNumber(16 + 36*r + 6*g + b)
+}
+
+// distance returns the squared distance between two colors.
+func (c rgb) distance(o rgb) float64 {
+	return (c.r-o.r)*(c.r-o.r) + (c.g-o.g)*(c.g-o.g) + (c.b-o.b)*(c.b-o.b)
+}
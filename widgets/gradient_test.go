@@ -0,0 +1,45 @@
+package widgets
+
+import (
+	"testing"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+func TestParseGradient(t *testing.T) {
+	g, err := ParseGradient([]string{"#000000", "#ffffff"})
+	if err != nil {
+		t.Fatalf("ParseGradient failed: %v", err)
+	}
+	if mid := g.at(0.5); mid != (rgb{127.5, 127.5, 127.5}) {
+		t.Errorf("unexpected middle color %v", mid)
+	}
+	if g.Contrast(0) != cell.ColorWhite || g.Contrast(1) != cell.ColorBlack {
+		t.Errorf("unexpected contrast colors")
+	}
+	if _, err := ParseGradient([]string{"Viridis"}); err != nil {
+		t.Errorf("named gradients should be found regardless of case: %v", err)
+	}
+	for _, spec := range [][]string{{"rainbow"}, {"#000000", "white"}, {"#0000001"}} {
+		if _, err := ParseGradient(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestRGBColor(t *testing.T) {
+	tests := []struct {
+		c    rgb
+		want cell.Color
+	}{
+		{rgb{255, 0, 0}, cell.ColorNumber(196)},
+		{rgb{0, 95, 135}, cell.ColorNumber(24)},
+		{rgb{128, 128, 128}, cell.ColorNumber(244)},
+		{rgb{0, 0, 0}, cell.ColorNumber(16)},
+	}
+	for _, tt := range tests {
+		if got := tt.c.color(); got != tt.want {
+			t.Errorf("%v.color() = %v, expected %v", tt.c, got, tt.want)
+		}
+	}
+}
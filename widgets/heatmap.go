@@ -0,0 +1,333 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// scaleWidth is the number of cells of the color scale of a heatmap.
+const scaleWidth = 16
+
+// HeatmapOption is used to provide options to the heatmap widget.
+type HeatmapOption interface {
+	set(*heatmapOptions)
+}
+
+// heatmapOptions stores the provided options.
+type heatmapOptions struct {
+	gradient Gradient
+}
+
+// withGradient is a private type that implements the HeatmapOption interface.
+type withGradient struct {
+	gradient Gradient
+}
+
+func (w *withGradient) set(opts *heatmapOptions) {
+	opts.gradient = w.gradient
+}
+
+// HeatmapGradient sets the colors of the cells, from the lowest value to the
+// highest. It defaults to DefaultGradient.
+func HeatmapGradient(g Gradient) HeatmapOption {
+	return &withGradient{gradient: g}
+}
+
+// Heatmap draws a grid of values as cells colored along a gradient, with
+// the labels of its columns above and of its rows on the left, and a color
+// scale below. When the widget is focused, the arrow keys move a cursor
+// whose cell is described next to the scale; the grid scrolls to keep it
+// visible.
+type Heatmap struct {
+	mu sync.Mutex
+
+	xLabels []string
+	yLabels []string
+	// values are indexed by row then column; missing ones are NaN.
+	values [][]float64
+	// lo and hi are the range of the values.
+	lo, hi float64
+
+	// cursor is the column and row of the inspected cell.
+	cursor image.Point
+	// offset is the first column and row drawn.
+	offset image.Point
+
+	opts *heatmapOptions
+}
+
+// NewHeatmap returns a new Heatmap widget.
+func NewHeatmap(opts ...HeatmapOption) (*Heatmap, error) {
+	gradient, err := ParseGradient(nil)
+	if err != nil {
+		return nil, err
+	}
+	opt := &heatmapOptions{gradient: gradient}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	return &Heatmap{opts: opt}, nil
+}
+
+// Values replaces the grid, with a row of values for each row label and a
+// value for each column label in every row.
+func (h *Heatmap) Values(xLabels, yLabels []string, values [][]float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(values) != len(yLabels) {
+		return fmt.Errorf("got %d rows for %d row labels", len(values), len(yLabels))
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, row := range values {
+		if len(row) != len(xLabels) {
+			return fmt.Errorf("row '%s' has %d values for %d column labels", yLabels[i], len(row), len(xLabels))
+		}
+		for _, v := range row {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	h.xLabels = xLabels
+	h.yLabels = yLabels
+	h.values = values
+	h.lo, h.hi = lo, hi
+	h.cursor.X = max(0, min(h.cursor.X, len(xLabels)-1))
+	h.cursor.Y = max(0, min(h.cursor.Y, len(yLabels)-1))
+	return nil
+}
+
+// scaled returns the position of a value between the lowest and the
+// highest one.
+func (h *Heatmap) scaled(v float64) float64 {
+	if h.hi == h.lo {
+		return 0.5
+	}
+	return (v - h.lo) / (h.hi - h.lo)
+}
+
+// inspect describes the cell under the cursor.
+func (h *Heatmap) inspect() string {
+	if len(h.values) == 0 || len(h.xLabels) == 0 {
+		return ""
+	}
+	v := h.values[h.cursor.Y][h.cursor.X]
+	value := "no data"
+	if !math.IsNaN(v) {
+		value = formatAxisValue(v)
+	}
+	return fmt.Sprintf("%s × %s: %s", h.yLabels[h.cursor.Y], h.xLabels[h.cursor.X], value)
+}
+
+// scroll moves the first drawn index of a dimension so that the cursor is
+// among the visible ones.
+func scroll(offset, cursor, visible, total int) int {
+	if cursor < offset {
+		offset = cursor
+	}
+	if cursor >= offset+visible {
+		offset = cursor - visible + 1
+	}
+	return max(0, min(offset, total-visible))
+}
+
+// Draw draws the Heatmap widget onto the canvas.
+func (h *Heatmap) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.xLabels) == 0 || len(h.yLabels) == 0 {
+		return nil
+	}
+	ar := cvs.Area()
+	labelWidth := 0
+	for _, label := range h.yLabels {
+		labelWidth = max(labelWidth, runewidth.StringWidth(label))
+	}
+	labelWidth = min(labelWidth, ar.Dx()/4)
+	// The column labels are on the first row and the scale on the last.
+	grid := image.Rect(ar.Min.X+labelWidth+1, ar.Min.Y+1, ar.Max.X, ar.Max.Y-1)
+	if grid.Dx() < 1 || grid.Dy() < 1 {
+		return nil
+	}
+
+	cellWidth := max(1, grid.Dx()/len(h.xLabels))
+	cols := min(len(h.xLabels), grid.Dx()/cellWidth)
+	rows := min(len(h.yLabels), grid.Dy())
+	h.offset.X = scroll(h.offset.X, h.cursor.X, cols, len(h.xLabels))
+	h.offset.Y = scroll(h.offset.Y, h.cursor.Y, rows, len(h.yLabels))
+
+	for r := 0; r < rows; r++ {
+		y := grid.Min.Y + r
+		row := h.offset.Y + r
+		labelOpts := []cell.Option{cell.FgColor(cell.ColorGreen)}
+		if meta.Focused && row == h.cursor.Y {
+			labelOpts = append(labelOpts, cell.Inverse())
+		}
+		if labelWidth > 0 {
+			if err := draw.Text(cvs, h.yLabels[row], image.Point{X: ar.Min.X, Y: y},
+				draw.TextMaxX(ar.Min.X+labelWidth), draw.TextOverrunMode(draw.OverrunModeThreeDot),
+				draw.TextCellOpts(labelOpts...)); err != nil {
+				return err
+			}
+		}
+		for c := 0; c < cols; c++ {
+			cursor := meta.Focused && row == h.cursor.Y && h.offset.X+c == h.cursor.X
+			if err := h.drawCell(cvs, image.Point{X: grid.Min.X + c*cellWidth, Y: y}, cellWidth, h.values[row][h.offset.X+c], cursor); err != nil {
+				return err
+			}
+		}
+	}
+	if err := h.drawColumnLabels(cvs, grid.Min.X, ar.Min.Y, cellWidth, cols, meta.Focused); err != nil {
+		return err
+	}
+	return h.drawScale(cvs, image.Rect(ar.Min.X, ar.Max.Y-1, ar.Max.X, ar.Max.Y), meta.Focused)
+}
+
+// drawCell fills a cell of the grid with the color of its value, and writes
+// the value when it fits. The cursor is marked in bold and underlined, or
+// with a diamond when the value doesn't fit.
+func (h *Heatmap) drawCell(cvs *canvas.Canvas, at image.Point, width int, v float64, cursor bool) error {
+	opts := []cell.Option{cell.FgColor(cell.ColorNumber(240))}
+	fill, text := ' ', ""
+	if math.IsNaN(v) {
+		fill = '·'
+	} else {
+		t := h.scaled(v)
+		opts = []cell.Option{cell.BgColor(h.opts.gradient.At(t)), cell.FgColor(h.opts.gradient.Contrast(t))}
+		if s := formatAxisValue(v); runewidth.StringWidth(s) < width {
+			text = s
+		}
+	}
+	if cursor {
+		opts = append(opts, cell.Bold(), cell.Underline())
+		if text == "" {
+			text = "◆"
+		}
+	}
+	for dx := 0; dx < width; dx++ {
+		if _, err := cvs.SetCell(image.Point{X: at.X + dx, Y: at.Y}, fill, opts...); err != nil {
+			return err
+		}
+	}
+	if text == "" {
+		return nil
+	}
+	at.X += (width - runewidth.StringWidth(text)) / 2
+	return draw.Text(cvs, text, at, draw.TextCellOpts(opts...))
+}
+
+// drawColumnLabels writes the labels of the visible columns. When they are
+// wider than the cells, only every few columns are labeled, and the label of
+// the cursor is drawn over its neighbors.
+func (h *Heatmap) drawColumnLabels(cvs *canvas.Canvas, x, y, cellWidth, cols int, focused bool) error {
+	widest := 0
+	for _, label := range h.xLabels[h.offset.X : h.offset.X+cols] {
+		widest = max(widest, runewidth.StringWidth(label))
+	}
+	// Labels are followed by at least one empty cell.
+	every := (widest + cellWidth) / cellWidth
+	label := func(c int, opts ...cell.Option) error {
+		text := h.xLabels[h.offset.X+c]
+		start := x + c*cellWidth
+		if every == 1 {
+			start += (cellWidth - runewidth.StringWidth(text)) / 2
+		}
+		maxX := min(x+(c+every)*cellWidth-1, cvs.Area().Max.X)
+		if start >= maxX {
+			return nil
+		}
+		return draw.Text(cvs, text, image.Point{X: start, Y: y}, draw.TextMaxX(maxX),
+			draw.TextOverrunMode(draw.OverrunModeThreeDot), draw.TextCellOpts(append(opts, cell.FgColor(cell.ColorGreen))...))
+	}
+	for c := 0; c < cols; c += every {
+		if err := label(c); err != nil {
+			return err
+		}
+	}
+	if focused {
+		return label(h.cursor.X-h.offset.X, cell.Inverse())
+	}
+	return nil
+}
+
+// drawScale draws the range of the values along the gradient, followed by
+// the description of the cell under the cursor when the widget is focused.
+func (h *Heatmap) drawScale(cvs *canvas.Canvas, area image.Rectangle, focused bool) error {
+	if math.IsInf(h.lo, 0) {
+		return nil
+	}
+	x := area.Min.X
+	text := func(s string, opts ...cell.Option) error {
+		if x >= area.Max.X {
+			return nil
+		}
+		if err := draw.Text(cvs, s, image.Point{X: x, Y: area.Min.Y}, draw.TextMaxX(area.Max.X),
+			draw.TextOverrunMode(draw.OverrunModeThreeDot), draw.TextCellOpts(opts...)); err != nil {
+			return err
+		}
+		x += runewidth.StringWidth(s)
+		return nil
+	}
+	if err := text(formatAxisValue(h.lo) + " "); err != nil {
+		return err
+	}
+	for i := 0; i < scaleWidth && x < area.Max.X; i++ {
+		t := float64(i) / (scaleWidth - 1)
+		if _, err := cvs.SetCell(image.Point{X: x, Y: area.Min.Y}, ' ', cell.BgColor(h.opts.gradient.At(t))); err != nil {
+			return err
+		}
+		x++
+	}
+	if err := text(" " + formatAxisValue(h.hi)); err != nil {
+		return err
+	}
+	if !focused {
+		return nil
+	}
+	return text("   "+h.inspect(), cell.Bold())
+}
+
+// Keyboard moves the cursor with the arrow keys.
+func (h *Heatmap) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch k.Key {
+	case keyboard.KeyArrowLeft:
+		h.cursor.X = max(0, h.cursor.X-1)
+	case keyboard.KeyArrowRight:
+		h.cursor.X = max(0, min(len(h.xLabels)-1, h.cursor.X+1))
+	case keyboard.KeyArrowUp:
+		h.cursor.Y = max(0, h.cursor.Y-1)
+	case keyboard.KeyArrowDown:
+		h.cursor.Y = max(0, min(len(h.yLabels)-1, h.cursor.Y+1))
+	}
+	return nil
+}
+
+// Mouse input isn't supported on the Heatmap widget.
+func (*Heatmap) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Heatmap widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (h *Heatmap) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{10, 4},
+		WantKeyboard: widgetapi.KeyScopeFocused,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
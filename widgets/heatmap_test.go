@@ -0,0 +1,60 @@
+package widgets
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+func TestHeatmap_Inspect(t *testing.T) {
+	h, err := NewHeatmap()
+	if err != nil {
+		t.Fatalf("failed to create heatmap: %v", err)
+	}
+	values := [][]float64{{1, 2, 3}, {4, math.NaN(), 6}}
+	if err := h.Values([]string{"0", "1", "2"}, []string{"mon", "tue"}, values); err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	if h.inspect() != "mon × 0: 1" {
+		t.Errorf("unexpected description %q", h.inspect())
+	}
+	for _, key := range []keyboard.Key{keyboard.KeyArrowDown, keyboard.KeyArrowDown, keyboard.KeyArrowRight, keyboard.KeyArrowUp, keyboard.KeyArrowDown} {
+		if err := h.Keyboard(&terminalapi.Keyboard{Key: key}, &widgetapi.EventMeta{Focused: true}); err != nil {
+			t.Fatalf("Keyboard failed: %v", err)
+		}
+	}
+	if h.inspect() != "tue × 1: no data" {
+		t.Errorf("unexpected description %q", h.inspect())
+	}
+
+	// The cursor stays on the grid when it shrinks.
+	if err := h.Values([]string{"0"}, []string{"mon"}, [][]float64{{5}}); err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	if h.inspect() != "mon × 0: 5" {
+		t.Errorf("unexpected description %q", h.inspect())
+	}
+	if err := h.Values([]string{"0"}, []string{"mon"}, [][]float64{{1, 2}}); err == nil {
+		t.Errorf("expected an error for a row longer than the labels")
+	}
+}
+
+func TestScroll(t *testing.T) {
+	tests := []struct {
+		offset, cursor, visible, total, want int
+	}{
+		{0, 3, 5, 10, 0},
+		{0, 7, 5, 10, 3},
+		{4, 2, 5, 10, 2},
+		{8, 9, 5, 10, 5},
+		{0, 0, 5, 3, 0},
+	}
+	for _, tt := range tests {
+		if got := scroll(tt.offset, tt.cursor, tt.visible, tt.total); got != tt.want {
+			t.Errorf("scroll(%d, %d, %d, %d) = %d, expected %d", tt.offset, tt.cursor, tt.visible, tt.total, got, tt.want)
+		}
+	}
+}
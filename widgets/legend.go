@@ -0,0 +1,129 @@
+package widgets
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+)
+
+// LegendPlacement is where a chart draws its legend.
+type LegendPlacement int
+
+const (
+	// LegendAuto places the legend right of wide charts and below tall ones.
+	LegendAuto LegendPlacement = iota
+	LegendRight
+	LegendBelow
+	LegendNone
+)
+
+// ParseLegendPlacement parses "auto", the default when empty, "right",
+// "below" or "none".
+func ParseLegendPlacement(s string) (LegendPlacement, error) {
+	switch s {
+	case "", "auto":
+		return LegendAuto, nil
+	case "right":
+		return LegendRight, nil
+	case "below":
+		return LegendBelow, nil
+	case "none":
+		return LegendNone, nil
+	}
+	return LegendAuto, fmt.Errorf("unsupported legend placement '%s', use auto, right, below or none", s)
+}
+
+// Palette holds distinct colors for the series of a chart, in order. Charts
+// with more series reuse them.
+var Palette = []cell.Color{
+	cell.ColorNumber(42),
+	cell.ColorNumber(39),
+	cell.ColorNumber(214),
+	cell.ColorNumber(197),
+	cell.ColorNumber(141),
+	cell.ColorNumber(226),
+	cell.ColorNumber(51),
+	cell.ColorNumber(203),
+	cell.ColorNumber(112),
+	cell.ColorNumber(250),
+}
+
+// LegendEntry is a line of a legend: a colored marker and its text.
+type LegendEntry struct {
+	Color cell.Color
+	Text  string
+}
+
+// legendMarker precedes the text of each entry.
+const legendMarker = '■'
+
+// splitLegend divides an area between a chart and its legend. The legend
+// area is empty when there is no legend or no room for it next to a usable
+// chart.
+func splitLegend(ar image.Rectangle, entries []LegendEntry, placement LegendPlacement) (chart, legend image.Rectangle) {
+	if len(entries) == 0 || placement == LegendNone {
+		return ar, image.Rectangle{}
+	}
+	if placement == LegendAuto {
+		// Cells are about twice as tall as they are wide, so an area is
+		// wide when it has more than twice as many columns as rows.
+		placement = LegendBelow
+		if ar.Dx() > 2*ar.Dy() {
+			placement = LegendRight
+		}
+	}
+
+	if placement == LegendRight {
+		width := 0
+		for _, e := range entries {
+			width = max(width, runewidth.StringWidth(e.Text)+2)
+		}
+		width = min(width, ar.Dx()/2)
+		chart = image.Rect(ar.Min.X, ar.Min.Y, ar.Max.X-width-1, ar.Max.Y)
+		legend = image.Rect(ar.Max.X-width, ar.Min.Y, ar.Max.X, ar.Max.Y)
+	} else {
+		height := min(len(entries), ar.Dy()/3)
+		chart = image.Rect(ar.Min.X, ar.Min.Y, ar.Max.X, ar.Max.Y-height)
+		legend = image.Rect(ar.Min.X, ar.Max.Y-height, ar.Max.X, ar.Max.Y)
+	}
+	if chart.Dx() < 5 || chart.Dy() < 3 || legend.Dx() < 3 || legend.Dy() < 1 {
+		return ar, image.Rectangle{}
+	}
+	return chart, legend
+}
+
+// drawLegend writes one entry per line of the area, trimming long texts.
+// When the entries don't fit, the last line counts the missing ones.
+func drawLegend(cvs *canvas.Canvas, area image.Rectangle, entries []LegendEntry) error {
+	if area.Empty() {
+		return nil
+	}
+	shown := entries
+	if len(entries) > area.Dy() {
+		shown = entries[:area.Dy()-1]
+	}
+	for i, e := range shown {
+		p := image.Point{X: area.Min.X, Y: area.Min.Y + i}
+		if _, err := cvs.SetCell(p, legendMarker, cell.FgColor(e.Color)); err != nil {
+			return err
+		}
+		if area.Dx() <= 2 {
+			continue
+		}
+		if err := draw.Text(cvs, e.Text, image.Point{X: p.X + 2, Y: p.Y},
+			draw.TextMaxX(area.Max.X), draw.TextOverrunMode(draw.OverrunModeThreeDot)); err != nil {
+			return err
+		}
+	}
+	if len(shown) < len(entries) {
+		more := fmt.Sprintf("+%d more", len(entries)-len(shown))
+		return draw.Text(cvs, more, image.Point{X: area.Min.X, Y: area.Min.Y + len(shown)},
+			draw.TextMaxX(area.Max.X), draw.TextOverrunMode(draw.OverrunModeTrim),
+			draw.TextCellOpts(cell.FgColor(cell.ColorNumber(244))))
+	}
+	return nil
+}
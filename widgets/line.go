@@ -0,0 +1,276 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/linechart"
+)
+
+// xAxisHeight is the number of rows below the graph of a line chart: the X
+// axis and its horizontal labels.
+const xAxisHeight = 2
+
+// LineSeries is a named series of a LineChart.
+type LineSeries struct {
+	Name   string
+	Values []float64
+	Color  cell.Color
+	// Secondary draws the series against the Y axis on the right.
+	Secondary bool
+}
+
+// LineChartOption is used to provide options to the line chart widget.
+type LineChartOption interface {
+	set(*lineChartOptions)
+}
+
+// lineChartOptions stores the provided options.
+type lineChartOptions struct {
+	legend LegendPlacement
+	// legendSet is true when the placement was given, in which case the
+	// legend is shown even for a single series.
+	legendSet bool
+}
+
+// withLineLegend is a private type that implements the LineChartOption interface.
+type withLineLegend struct {
+	placement LegendPlacement
+}
+
+func (w *withLineLegend) set(opts *lineChartOptions) {
+	opts.legend = w.placement
+	opts.legendSet = true
+}
+
+// LineLegend places the legend of the series. By default it is placed
+// automatically when there are several series.
+func LineLegend(placement LegendPlacement) LineChartOption {
+	return &withLineLegend{placement: placement}
+}
+
+// LineChart draws several series on the same axes, with a legend and an
+// optional secondary Y axis on the right. The series are drawn by a termdash
+// line chart: those on the secondary axis are scaled to the primary one and
+// the right axis shows their own values.
+type LineChart struct {
+	mu sync.Mutex
+
+	lc *linechart.LineChart
+	// names are the series currently drawn by lc.
+	names []string
+	// legend describes the series.
+	legend []LegendEntry
+	// secondary is the range of the secondary axis, when there is one.
+	secondary *[2]float64
+
+	opts *lineChartOptions
+}
+
+// NewLineChart returns a new LineChart widget.
+func NewLineChart(opts ...LineChartOption) (*LineChart, error) {
+	opt := &lineChartOptions{}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	lc, err := linechart.New(
+		linechart.AxesCellOpts(cell.FgColor(cell.ColorRed)),
+		linechart.YLabelCellOpts(cell.FgColor(cell.ColorGreen)),
+		linechart.XLabelCellOpts(cell.FgColor(cell.ColorGreen)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LineChart{lc: lc, opts: opt}, nil
+}
+
+// SetSeries replaces the series of the chart, whose values share the X axis
+// labels. NaN values are drawn as gaps.
+func (l *LineChart) SetSeries(series []LineSeries, xLabels map[int]string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	labels := make(map[int]string, len(xLabels))
+	for i, label := range xLabels {
+		// Termdash rejects empty labels.
+		if label != "" && i >= 0 {
+			labels[i] = label
+		}
+	}
+
+	var primary, secondary []float64
+	for _, s := range series {
+		if s.Secondary {
+			secondary = append(secondary, s.Values...)
+		} else {
+			primary = append(primary, s.Values...)
+		}
+	}
+	// A secondary axis needs a primary one to be scaled to.
+	scale := func(v float64) float64 { return v }
+	l.secondary = nil
+	if len(primary) > 0 && len(secondary) > 0 {
+		pMin, pMax := anchoredRange(primary)
+		sMin, sMax := anchoredRange(secondary)
+		scale = func(v float64) float64 { return pMin + (v-sMin)*(pMax-pMin)/(sMax-sMin) }
+		l.secondary = &[2]float64{sMin, sMax}
+	}
+
+	names := make([]string, 0, len(series))
+	l.legend = l.legend[:0]
+	for _, s := range series {
+		if s.Name == "" {
+			return errors.New("the series name cannot be empty")
+		}
+		values := s.Values
+		text := s.Name
+		if s.Secondary && l.secondary != nil {
+			values = make([]float64, len(s.Values))
+			for i, v := range s.Values {
+				values[i] = scale(v)
+			}
+			text += " (right)"
+		}
+		opts := []linechart.SeriesOption{linechart.SeriesCellOpts(cell.FgColor(s.Color))}
+		if len(labels) > 0 {
+			opts = append(opts, linechart.SeriesXLabels(labels))
+		}
+		if err := l.lc.Series(s.Name, values, opts...); err != nil {
+			return err
+		}
+		names = append(names, s.Name)
+		l.legend = append(l.legend, LegendEntry{Color: s.Color, Text: text})
+	}
+	// Series that are gone, such as a category no longer in the data, are
+	// emptied since termdash can't remove them.
+	for _, name := range l.names {
+		if !slices.Contains(names, name) {
+			if err := l.lc.Series(name, nil); err != nil {
+				return err
+			}
+		}
+	}
+	l.names = names
+	return nil
+}
+
+// anchoredRange returns the range of values the way termdash scales its Y
+// axis: including zero, and never empty.
+func anchoredRange(values []float64) (float64, float64) {
+	lo, hi := 0.0, 0.0
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if lo == hi {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// Draw draws the LineChart widget onto the canvas.
+func (l *LineChart) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	placement := l.opts.legend
+	if len(l.legend) < 2 && !l.opts.legendSet {
+		placement = LegendNone
+	}
+	chartAr, legendAr := splitLegend(cvs.Area(), l.legend, placement)
+
+	var labels []string
+	lineAr := chartAr
+	if l.secondary != nil {
+		lo, hi := l.secondary[0], l.secondary[1]
+		labels = []string{formatAxisValue(hi), formatAxisValue((lo + hi) / 2), formatAxisValue(lo)}
+		width := 0
+		for _, label := range labels {
+			width = max(width, runewidth.StringWidth(label))
+		}
+		if chartAr.Dx()-width-1 >= chartAr.Dx()/2 {
+			lineAr.Max.X -= width + 1
+		} else {
+			labels = nil
+		}
+	}
+
+	sub, err := canvas.New(lineAr)
+	if err != nil {
+		return err
+	}
+	if err := l.lc.Draw(sub, meta); err != nil {
+		return err
+	}
+	if err := sub.CopyTo(cvs); err != nil {
+		return err
+	}
+	if labels != nil {
+		if err := drawRightAxis(cvs, image.Rect(lineAr.Max.X, lineAr.Min.Y, chartAr.Max.X, lineAr.Max.Y-xAxisHeight), labels); err != nil {
+			return err
+		}
+	}
+	return drawLegend(cvs, legendAr, l.legend)
+}
+
+// drawRightAxis draws the secondary Y axis along the left edge of area, with
+// its labels at the top, middle and bottom rows.
+func drawRightAxis(cvs *canvas.Canvas, area image.Rectangle, labels []string) error {
+	if area.Dy() < 1 {
+		return nil
+	}
+	axis := []draw.HVLine{{Start: area.Min, End: image.Point{X: area.Min.X, Y: area.Max.Y - 1}}}
+	if err := draw.HVLines(cvs, axis, draw.HVLineCellOpts(cell.FgColor(cell.ColorRed))); err != nil {
+		return err
+	}
+	rows := []int{area.Min.Y, area.Min.Y + (area.Dy()-1)/2, area.Max.Y - 1}
+	for i, label := range labels {
+		if i > 0 && rows[i] == rows[i-1] {
+			continue
+		}
+		if err := draw.Text(cvs, label, image.Point{X: area.Min.X + 1, Y: rows[i]},
+			draw.TextMaxX(cvs.Area().Max.X), draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatAxisValue writes an axis label with up to 4 significant digits.
+func formatAxisValue(v float64) string {
+	return fmt.Sprintf("%.4g", v)
+}
+
+// Keyboard input isn't supported on the LineChart widget.
+func (*LineChart) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	return errors.New("the LineChart widget doesn't support keyboard events")
+}
+
+// Mouse forwards the mouse events to the termdash line chart, which zooms on
+// them. The chart is drawn from the top left corner, so the coordinates are
+// the same.
+func (l *LineChart) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return l.lc.Mouse(m, meta)
+}
+
+// Options implements widgetapi.Widget.Options.
+func (l *LineChart) Options() widgetapi.Options {
+	opts := l.lc.Options()
+	return widgetapi.Options{
+		MinimumSize:  opts.MinimumSize,
+		WantKeyboard: widgetapi.KeyScopeNone,
+		WantMouse:    opts.WantMouse,
+	}
+}
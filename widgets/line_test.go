@@ -0,0 +1,59 @@
+package widgets
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+func TestLineChart_SetSeries(t *testing.T) {
+	lc, err := NewLineChart()
+	if err != nil {
+		t.Fatalf("failed to create line chart: %v", err)
+	}
+	series := []LineSeries{
+		{Name: "requests", Values: []float64{0, 50, 100}, Color: cell.ColorRed},
+		{Name: "latency", Values: []float64{0.1, math.NaN(), 0.4}, Color: cell.ColorBlue, Secondary: true},
+	}
+	if err := lc.SetSeries(series, map[int]string{0: "a", 1: "", 2: "c"}); err != nil {
+		t.Fatalf("SetSeries failed: %v", err)
+	}
+	if lc.secondary == nil || *lc.secondary != [2]float64{0, 0.4} {
+		t.Errorf("unexpected secondary range %v", lc.secondary)
+	}
+	if len(lc.legend) != 2 || lc.legend[0].Text != "requests" || lc.legend[1].Text != "latency (right)" {
+		t.Errorf("unexpected legend %v", lc.legend)
+	}
+
+	// Without a primary series there is nothing to scale to.
+	if err := lc.SetSeries(series[1:], nil); err != nil {
+		t.Fatalf("SetSeries failed: %v", err)
+	}
+	if lc.secondary != nil || lc.legend[0].Text != "latency" {
+		t.Errorf("expected no secondary axis, got %v and legend %v", lc.secondary, lc.legend)
+	}
+	if len(lc.names) != 1 {
+		t.Errorf("expected one series left, got %v", lc.names)
+	}
+
+	if err := lc.SetSeries([]LineSeries{{Values: []float64{1}}}, nil); err == nil {
+		t.Errorf("expected an error for an unnamed series")
+	}
+}
+
+func TestAnchoredRange(t *testing.T) {
+	tests := []struct {
+		values []float64
+		lo, hi float64
+	}{
+		{[]float64{2, 5}, 0, 5},
+		{[]float64{-3, math.NaN(), 1}, -3, 1},
+		{nil, 0, 1},
+	}
+	for _, tt := range tests {
+		if lo, hi := anchoredRange(tt.values); lo != tt.lo || hi != tt.hi {
+			t.Errorf("anchoredRange(%v) = %v, %v, expected %v, %v", tt.values, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// DefaultLogMaxLines is the number of lines a log keeps when no maximum is
+// configured.
+const DefaultLogMaxLines = 1000
+
+// LogLine is a line of a Log, and the level coloring it such as "error" or
+// "warn".
+type LogLine struct {
+	Text  string
+	Level string
+}
+
+// logLevelColor returns the color of the lines of a level, and false for
+// the levels drawn in the default color.
+func logLevelColor(level string) (cell.Color, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "fatal", "panic", "critical", "crit", "emerg", "alert", "error", "err":
+		return cell.ColorNumber(196), true
+	case "warning", "warn":
+		return cell.ColorNumber(214), true
+	case "debug", "trace":
+		return cell.ColorNumber(244), true
+	}
+	return 0, false
+}
+
+// LogOption is used to provide options to the log widget.
+type LogOption interface {
+	set(*logOptions)
+}
+
+// logOptions stores the provided options.
+type logOptions struct {
+	maxLines int
+}
+
+// withLogMaxLines is a private type that implements the LogOption interface.
+type withLogMaxLines struct {
+	maxLines int
+}
+
+func (w *withLogMaxLines) set(opts *logOptions) {
+	opts.maxLines = w.maxLines
+}
+
+// LogMaxLines sets the number of lines kept, dropping the oldest ones. It
+// defaults to DefaultLogMaxLines.
+func LogMaxLines(n int) LogOption {
+	return &withLogMaxLines{maxLines: n}
+}
+
+// Log draws the newest lines of a backlog, following new lines as they are
+// added unless it is paused. When the widget is focused:
+//   - space pauses and resumes following new lines,
+//   - the arrow keys, page up and page down scroll, pausing above the newest
+//     line, and Home and End go to the oldest and newest lines,
+//   - / starts typing a search, confirmed with Enter, whose matches are
+//     highlighted; n and N go to the older and newer matching lines.
+type Log struct {
+	mu sync.Mutex
+
+	// lines is the backlog, from the oldest.
+	lines []LogLine
+	// offset is the number of the newest lines below the view.
+	offset int
+	paused bool
+	// height is the number of lines drawn the last time.
+	height int
+
+	// typing is true while the search is typed in input.
+	typing bool
+	input  []rune
+	// query is the search highlighted in the lines.
+	query []rune
+	// match is the index of the line of the current match, or -1.
+	match int
+
+	opts *logOptions
+}
+
+// NewLog returns a new Log widget.
+func NewLog(opts ...LogOption) (*Log, error) {
+	opt := &logOptions{maxLines: DefaultLogMaxLines}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	if opt.maxLines < 1 {
+		return nil, fmt.Errorf("the maximum number of lines must be positive, got %d", opt.maxLines)
+	}
+	return &Log{match: -1, opts: opt}, nil
+}
+
+// Lines adds the lines of a snapshot of the newest rows that aren't already
+// in the backlog: those following the longest tail of the backlog that
+// starts the snapshot. A source read again from its start on every refresh
+// thus only adds its new rows.
+func (l *Log) Lines(lines []LogLine) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fresh := lines[overlap(l.lines, lines):]
+	l.lines = append(l.lines, fresh...)
+	if l.paused {
+		l.offset += len(fresh)
+	}
+	if drop := len(l.lines) - l.opts.maxLines; drop > 0 {
+		l.lines = slices.Clone(l.lines[drop:])
+		l.match = max(-1, l.match-drop)
+	}
+	l.offset = max(0, min(l.offset, len(l.lines)-1))
+	return nil
+}
+
+// overlap returns the length of the longest tail of backlog that starts
+// lines.
+func overlap(backlog, lines []LogLine) int {
+	for k := min(len(backlog), len(lines)); k > 0; k-- {
+		if slices.Equal(backlog[len(backlog)-k:], lines[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+// visible returns the range of the lines drawn.
+func (l *Log) visible() (start, end int) {
+	end = len(l.lines) - l.offset
+	return max(0, end-max(1, l.height)), end
+}
+
+// matches returns the start of the matches of the query in text, ignoring
+// case, without overlaps.
+func matches(text, query []rune) []int {
+	if len(query) == 0 {
+		return nil
+	}
+	var found []int
+	for i := 0; i+len(query) <= len(text); i++ {
+		ok := true
+		for j, q := range query {
+			if unicode.ToLower(text[i+j]) != unicode.ToLower(q) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			found = append(found, i)
+			i += len(query) - 1
+		}
+	}
+	return found
+}
+
+// status describes the search and whether the log is paused, and is empty
+// when there is nothing to tell.
+func (l *Log) status() string {
+	if l.typing {
+		return "/" + string(l.input) + "█"
+	}
+	var parts []string
+	if len(l.query) > 0 {
+		count := 0
+		for _, line := range l.lines {
+			if len(matches([]rune(line.Text), l.query)) > 0 {
+				count++
+			}
+		}
+		parts = append(parts, fmt.Sprintf("/%s: %d matching lines, n/N to jump", string(l.query), count))
+	}
+	if l.paused {
+		parts = append(parts, fmt.Sprintf("paused, %d newer, space to resume", l.offset))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Draw draws the Log widget onto the canvas.
+func (l *Log) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ar := cvs.Area()
+	status := l.status()
+	l.height = ar.Dy()
+	if status != "" {
+		l.height--
+	}
+	start, end := l.visible()
+	for i := start; i < end && l.height > 0; i++ {
+		if err := l.drawLine(cvs, l.lines[i], image.Point{X: ar.Min.X, Y: ar.Min.Y + i - start}, ar.Max.X, i == l.match); err != nil {
+			return err
+		}
+	}
+	if status == "" {
+		return nil
+	}
+	return draw.Text(cvs, status, image.Point{X: ar.Min.X, Y: ar.Max.Y - 1}, draw.TextMaxX(ar.Max.X),
+		draw.TextOverrunMode(draw.OverrunModeThreeDot), draw.TextCellOpts(cell.FgColor(cell.ColorGreen)))
+}
+
+// drawLine writes a line in the color of its level, highlighting the
+// matches of the query, and marking the line of the current match.
+func (l *Log) drawLine(cvs *canvas.Canvas, line LogLine, at image.Point, maxX int, current bool) error {
+	text := []rune(line.Text)
+	highlighted := make([]bool, len(text))
+	for _, i := range matches(text, l.query) {
+		for j := range l.query {
+			highlighted[i+j] = true
+		}
+	}
+	var opts []cell.Option
+	if color, ok := logLevelColor(line.Level); ok {
+		opts = append(opts, cell.FgColor(color))
+	}
+	if current {
+		opts = append(opts, cell.Bold())
+	}
+	for i, r := range text {
+		w := runewidth.RuneWidth(r)
+		if w == 0 {
+			continue
+		}
+		if at.X+w > maxX || (at.X+w == maxX && i < len(text)-1) {
+			// The line doesn't fit: it ends with an ellipsis.
+			_, err := cvs.SetCell(at, '…', opts...)
+			return err
+		}
+		cellOpts := opts
+		if highlighted[i] {
+			cellOpts = append(slices.Clone(opts), cell.FgColor(cell.ColorBlack), cell.BgColor(cell.ColorNumber(226)))
+		}
+		if _, err := cvs.SetCell(at, r, cellOpts...); err != nil {
+			return err
+		}
+		at.X += w
+	}
+	return nil
+}
+
+// scroll moves the view by n lines towards the oldest ones. The log pauses
+// above the newest line and follows new lines again when back to it.
+func (l *Log) scroll(n int) {
+	l.offset = max(0, min(l.offset+n, len(l.lines)-1))
+	l.paused = l.offset > 0
+}
+
+// jump goes to the next line matching the query, older or newer than the
+// current match or, without one, than the newest line drawn.
+func (l *Log) jump(older bool) {
+	if len(l.query) == 0 {
+		return
+	}
+	from := l.match
+	if from == -1 {
+		_, end := l.visible()
+		from = end
+		if !older {
+			from = end - 1
+		}
+	}
+	step := 1
+	if older {
+		step = -1
+	}
+	for i := from + step; i >= 0 && i < len(l.lines); i += step {
+		if len(matches([]rune(l.lines[i].Text), l.query)) == 0 {
+			continue
+		}
+		l.match = i
+		// The match is centered, when there are lines below it.
+		end := min(len(l.lines), i+max(1, l.height)/2+1)
+		l.offset = len(l.lines) - end
+		l.paused = l.offset > 0 || l.paused
+		return
+	}
+}
+
+// Keyboard pauses, scrolls and searches the log.
+func (l *Log) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.typing {
+		switch {
+		case k.Key == keyboard.KeyEnter:
+			l.typing, l.query, l.match = false, l.input, -1
+			l.jump(true)
+		case k.Key == keyboard.KeyBackspace || k.Key == keyboard.KeyBackspace2:
+			if len(l.input) == 0 {
+				l.typing = false
+			} else {
+				l.input = l.input[:len(l.input)-1]
+			}
+		case k.Key >= keyboard.KeySpace:
+			l.input = append(l.input, rune(k.Key))
+		}
+		return nil
+	}
+
+	page := max(1, l.height-1)
+	switch k.Key {
+	case '/':
+		l.typing, l.input = true, nil
+	case keyboard.KeySpace:
+		l.paused = !l.paused
+		if !l.paused {
+			l.offset = 0
+		}
+	case 'n':
+		l.jump(true)
+	case 'N':
+		l.jump(false)
+	case keyboard.KeyArrowUp:
+		l.scroll(1)
+	case keyboard.KeyArrowDown:
+		l.scroll(-1)
+	case keyboard.KeyPgUp:
+		l.scroll(page)
+	case keyboard.KeyPgDn:
+		l.scroll(-page)
+	case keyboard.KeyHome:
+		l.scroll(len(l.lines))
+	case keyboard.KeyEnd:
+		l.scroll(-len(l.lines))
+	}
+	return nil
+}
+
+// Mouse input isn't supported on the Log widget.
+func (*Log) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Log widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (l *Log) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{10, 2},
+		WantKeyboard: widgetapi.KeyScopeFocused,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
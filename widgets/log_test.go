@@ -0,0 +1,90 @@
+package widgets
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// logLines returns lines named from first to last.
+func logLines(first, last int) []LogLine {
+	var lines []LogLine
+	for i := first; i <= last; i++ {
+		lines = append(lines, LogLine{Text: fmt.Sprintf("line %d", i)})
+	}
+	return lines
+}
+
+func TestLog_Lines(t *testing.T) {
+	l, err := NewLog(LogMaxLines(5))
+	if err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	if err := l.Lines(logLines(1, 3)); err != nil {
+		t.Fatalf("Lines failed: %v", err)
+	}
+	// A source read again from its start only adds its new rows, and a
+	// window of the newest rows only the ones after the backlog.
+	l.Lines(logLines(1, 4))
+	l.Lines(logLines(3, 6))
+	if got, want := l.lines, logLines(2, 6); !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %v, expected %v", got, want)
+	}
+
+	// A paused log keeps showing the same lines.
+	l.paused = true
+	l.Lines(logLines(5, 7))
+	if l.offset != 1 || l.lines[len(l.lines)-1-l.offset].Text != "line 6" {
+		t.Errorf("offset = %d, expected the view to stay on line 6", l.offset)
+	}
+}
+
+func TestLog_Keyboard(t *testing.T) {
+	l, err := NewLog()
+	if err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	lines := logLines(1, 20)
+	lines[4].Text = "disk FULL"
+	lines[14].Text = "disk full again"
+	l.Lines(lines)
+	l.height = 4
+
+	keys := func(ks ...keyboard.Key) {
+		for _, k := range ks {
+			if err := l.Keyboard(&terminalapi.Keyboard{Key: k}, nil); err != nil {
+				t.Fatalf("Keyboard failed: %v", err)
+			}
+		}
+	}
+	keys(keyboard.KeyArrowUp, keyboard.KeyArrowUp)
+	if l.offset != 2 || !l.paused {
+		t.Errorf("after scrolling up, offset = %d and paused = %v, expected 2 and true", l.offset, l.paused)
+	}
+	keys(keyboard.KeyEnd)
+	if l.offset != 0 || l.paused {
+		t.Errorf("after End, offset = %d and paused = %v, expected 0 and false", l.offset, l.paused)
+	}
+
+	keys('/', 'd', 'x', keyboard.KeyBackspace2, 'i', 's', 'k', keyboard.KeyEnter)
+	if string(l.query) != "disk" || l.match != 14 {
+		t.Errorf("search %q matched line %d, expected disk at 14", string(l.query), l.match)
+	}
+	if start, end := l.visible(); l.match < start || l.match >= end {
+		t.Errorf("the match at %d is out of the view %d-%d", l.match, start, end)
+	}
+	keys('n')
+	if l.match != 4 {
+		t.Errorf("the next older match is %d, expected 4", l.match)
+	}
+	keys('n', 'N')
+	if l.match != 14 {
+		t.Errorf("the next newer match is %d, expected 14", l.match)
+	}
+	if got := matches([]rune("Disk full, disk"), []rune("DISK")); !reflect.DeepEqual(got, []int{0, 11}) {
+		t.Errorf("matches = %v, expected [0 11]", got)
+	}
+}
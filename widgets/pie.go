@@ -17,6 +17,10 @@ import (
 
 // probably it will be substituted with my termdash/pie once approved by murr (please approve my PR)
 
+// DefaultPieInnerRadius is the hole of the donut drawn by default, as a
+// fraction of the radius of the pie.
+const DefaultPieInnerRadius = 0.6
+
 // PieChart displays data as a pie chart.
 // Each value is a proportion of the total sum.
 type PieChart struct {
@@ -24,29 +28,111 @@ type PieChart struct {
 
 	// values holds the data for each slice.
 	values []int
+	// labels names the slices in the legend; they may be missing.
+	labels []string
 	// colors holds the color for each slice.
 	colors []cell.Color
 	// total is the sum of all values.
 	total int
+
+	// opts are the provided options.
+	opts *pieChartOptions
 }
 
-// pieChartOption is used to provide options to the piechart widget.
-type pieChartOption interface {
+// PieChartOption is used to provide options to the piechart widget.
+type PieChartOption interface {
 	set(*pieChartOptions)
 }
 
 // pieChartOptions stores the provided options.
-type pieChartOptions struct{}
+type pieChartOptions struct {
+	// innerRadius is the radius of the hole as a fraction of the radius of
+	// the pie, 0 for a full pie.
+	innerRadius float64
+	legend      LegendPlacement
+	// sliceLabels is the smallest percentage of the slices annotated with
+	// it, 0 for none.
+	sliceLabels float64
+}
+
+// validate checks the provided options.
+func (o *pieChartOptions) validate() error {
+	if o.innerRadius < 0 || o.innerRadius >= 1 {
+		return fmt.Errorf("the inner radius must be in [0, 1), got %v", o.innerRadius)
+	}
+	if o.sliceLabels < 0 || o.sliceLabels > 100 {
+		return fmt.Errorf("the slice labels percentage must be in [0, 100], got %v", o.sliceLabels)
+	}
+	return nil
+}
+
+// withInnerRadius is a private type that implements the PieChartOption interface.
+type withInnerRadius struct {
+	r float64
+}
+
+func (w *withInnerRadius) set(opts *pieChartOptions) {
+	opts.innerRadius = w.r
+}
+
+// PieInnerRadius sets the hole of the donut as a fraction of the radius.
+// It defaults to DefaultPieInnerRadius, and 0 draws a full pie.
+func PieInnerRadius(r float64) PieChartOption {
+	return &withInnerRadius{r: r}
+}
+
+// withPieLegend is a private type that implements the PieChartOption interface.
+type withPieLegend struct {
+	placement LegendPlacement
+}
+
+func (w *withPieLegend) set(opts *pieChartOptions) {
+	opts.legend = w.placement
+}
+
+// PieLegend places the legend of the slices, with their labels, values and
+// percentages. It is placed automatically by default.
+func PieLegend(placement LegendPlacement) PieChartOption {
+	return &withPieLegend{placement: placement}
+}
+
+// withSliceLabels is a private type that implements the PieChartOption interface.
+type withSliceLabels struct {
+	minPercent float64
+}
+
+func (w *withSliceLabels) set(opts *pieChartOptions) {
+	opts.sliceLabels = w.minPercent
+}
+
+// PieSliceLabels writes their percentage on the slices of at least
+// minPercent of the total, which are large enough to hold it.
+func PieSliceLabels(minPercent float64) PieChartOption {
+	return &withSliceLabels{minPercent: minPercent}
+}
 
 // NewPieChart returns a new PieChart widget.
-func NewPieChart() (*PieChart, error) {
-	return &PieChart{}, nil
+func NewPieChart(opts ...PieChartOption) (*PieChart, error) {
+	opt := &pieChartOptions{innerRadius: DefaultPieInnerRadius}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	return &PieChart{opts: opt}, nil
 }
 
 // Values sets the data for the pie chart.
 // The values must be non-negative. A color must be provided for each value.
 // If not enough colors are provided, they will be reused.
 func (p *PieChart) Values(values []int, colors []cell.Color) error {
+	return p.LabeledValues(values, nil, colors)
+}
+
+// LabeledValues is like Values, also naming the slices in the legend. Slices
+// without a label are listed by value only.
+func (p *PieChart) LabeledValues(values []int, labels []string, colors []cell.Color) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -57,32 +143,50 @@ func (p *PieChart) Values(values []int, colors []cell.Color) error {
 		return errors.New("colors cannot be empty")
 	}
 
-	p.values = values
-	p.colors = colors
-	p.total = 0
+	total := 0
 	for _, v := range values {
 		if v < 0 {
 			return errors.New("all values must be non-negative")
 		}
-		p.total += v
+		total += v
 	}
-
+	p.values = values
+	p.labels = labels
+	p.colors = colors
+	p.total = total
 	return nil
 }
 
+// legendEntries describes each slice with its label, value and percentage.
+func (p *PieChart) legendEntries() []LegendEntry {
+	entries := make([]LegendEntry, len(p.values))
+	for i, v := range p.values {
+		text := fmt.Sprintf("%d (%.1f%%)", v, p.percent(v))
+		if i < len(p.labels) && p.labels[i] != "" {
+			text = p.labels[i] + " " + text
+		}
+		entries[i] = LegendEntry{Color: p.colors[i%len(p.colors)], Text: text}
+	}
+	return entries
+}
+
+// percent returns the share of the total of a value.
+func (p *PieChart) percent(v int) float64 {
+	return float64(v) / float64(p.total) * 100
+}
+
 // pieChartMidAndRadii returns the center point and horizontal and vertical radii.
+// Braille pixels are about square, so the radii are equal and the pie is round
+// whatever the shape of the area.
 func pieChartMidAndRadii(ar image.Rectangle) (image.Point, int, int) {
 	width := ar.Dx() * braille.ColMult
 	height := ar.Dy() * braille.RowMult
 
-	radiusX := width/2 - 2
-	radiusY := height/2 - 2
+	radiusX := min(width, height)/2 - 2
 	if radiusX < 1 {
 		radiusX = 1
 	}
-	if radiusY < 1 {
-		radiusY = 1
-	}
+	radiusY := radiusX
 	mid := image.Point{
 		X: ar.Min.X*braille.ColMult + width/2,
 		Y: ar.Min.Y*braille.RowMult + height/2,
@@ -99,22 +203,26 @@ func (p *PieChart) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
 		return nil
 	}
 
-	bc, err := braille.New(cvs.Area())
+	entries := p.legendEntries()
+	chartAr, legendAr := splitLegend(cvs.Area(), entries, p.opts.legend)
+
+	bc, err := braille.New(chartAr)
 	if err != nil {
 		return fmt.Errorf("braille.New => %v", err)
 	}
 
-	mid, radiusX, radiusY := pieChartMidAndRadii(cvs.Area())
+	// The braille canvas is zero based and copied onto the chart area.
+	mid, radiusX, radiusY := pieChartMidAndRadii(image.Rect(0, 0, chartAr.Dx(), chartAr.Dy()))
 
-	innerRadiusX := int(float64(radiusX) * 0.6)
-	innerRadiusY := int(float64(radiusY) * 0.6)
+	innerRadiusX := int(float64(radiusX) * p.opts.innerRadius)
+	innerRadiusY := int(float64(radiusY) * p.opts.innerRadius)
 
 	currentAngle := 0.0
 	for i, value := range p.values {
 		endAngle := currentAngle + float64(value)/float64(p.total)*2*math.Pi
 		color := p.colors[i%len(p.colors)]
 
-		for angle := currentAngle; angle < endAngle; angle += 0.01 { 
+		for angle := currentAngle; angle < endAngle; angle += 0.01 {
 			startX := mid.X + int(float64(innerRadiusX)*math.Cos(angle))
 			startY := mid.Y + int(float64(innerRadiusY)*math.Sin(angle))
 
@@ -125,7 +233,7 @@ func (p *PieChart) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
 			endPoint := image.Point{X: endX, Y: endY}
 
 			if err := draw.BrailleLine(bc, startPoint, endPoint, draw.BrailleLineCellOpts(cell.FgColor(color))); err != nil {
-				return fmt.Errorf("failed to draw donut slice line: %v", err)
+				return fmt.Errorf("failed to draw pie slice line: %v", err)
 			}
 		}
 
@@ -135,7 +243,44 @@ func (p *PieChart) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
 	if err := bc.CopyTo(cvs); err != nil {
 		return err
 	}
+	if p.opts.sliceLabels > 0 {
+		if err := p.drawSliceLabels(cvs, chartAr, mid, radiusX, radiusY); err != nil {
+			return err
+		}
+	}
+	return drawLegend(cvs, legendAr, entries)
+}
 
+// drawSliceLabels writes the percentage of the large slices halfway along
+// their middle radius, on the color of the slice.
+func (p *PieChart) drawSliceLabels(cvs *canvas.Canvas, chartAr image.Rectangle, mid image.Point, radiusX, radiusY int) error {
+	// The labels sit in the middle of the ring, or a bit out of the center
+	// of a full pie where slices are wider.
+	frac := max((1+p.opts.innerRadius)/2, 0.6)
+	currentAngle := 0.0
+	for i, value := range p.values {
+		sweep := float64(value) / float64(p.total) * 2 * math.Pi
+		angle := currentAngle + sweep/2
+		currentAngle += sweep
+		pct := p.percent(value)
+		if value == 0 || pct < p.opts.sliceLabels {
+			continue
+		}
+
+		text := fmt.Sprintf("%.0f%%", pct)
+		at := chartAr.Min.Add(image.Point{
+			X: (mid.X+int(frac*float64(radiusX)*math.Cos(angle)))/braille.ColMult - len(text)/2,
+			Y: (mid.Y + int(frac*float64(radiusY)*math.Sin(angle))) / braille.RowMult,
+		})
+		end := image.Point{X: at.X + len(text) - 1, Y: at.Y}
+		if !at.In(chartAr) || !end.In(chartAr) {
+			continue
+		}
+		color := p.colors[i%len(p.colors)]
+		if err := draw.Text(cvs, text, at, draw.TextCellOpts(cell.FgColor(cell.ColorBlack), cell.BgColor(color))); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -151,10 +296,15 @@ func (*PieChart) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
 
 // Options implements widgetapi.Widget.Options.
 func (p *PieChart) Options() widgetapi.Options {
-	return widgetapi.Options{
-		Ratio:        image.Point{braille.RowMult, braille.ColMult},
+	opts := widgetapi.Options{
 		MinimumSize:  image.Point{5, 5},
 		WantKeyboard: widgetapi.KeyScopeNone,
 		WantMouse:    widgetapi.MouseScopeNone,
 	}
+	// Without a legend the canvas is kept square, the legend otherwise
+	// takes the spare room.
+	if p.opts.legend == LegendNone {
+		opts.Ratio = image.Point{braille.RowMult, braille.ColMult}
+	}
+	return opts
 }
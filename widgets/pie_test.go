@@ -0,0 +1,51 @@
+package widgets
+
+import (
+	"image"
+	"testing"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+func TestPieChart_LegendEntries(t *testing.T) {
+	pc, err := NewPieChart()
+	if err != nil {
+		t.Fatalf("failed to create pie chart: %v", err)
+	}
+	if pc.opts.innerRadius != DefaultPieInnerRadius {
+		t.Errorf("pie charts should be donuts by default, got an inner radius of %v", pc.opts.innerRadius)
+	}
+	if err := pc.LabeledValues([]int{30, 10}, []string{"EU"}, []cell.Color{cell.ColorRed}); err != nil {
+		t.Fatalf("LabeledValues failed: %v", err)
+	}
+	entries := pc.legendEntries()
+	if entries[0].Text != "EU 30 (75.0%)" || entries[1].Text != "10 (25.0%)" {
+		t.Errorf("unexpected legend %v", entries)
+	}
+	if _, err := NewPieChart(PieInnerRadius(1)); err == nil {
+		t.Errorf("expected an error for an inner radius of 1")
+	}
+}
+
+func TestSplitLegend(t *testing.T) {
+	entries := []LegendEntry{{Text: "first"}, {Text: "second"}}
+	tests := []struct {
+		name      string
+		ar        image.Rectangle
+		placement LegendPlacement
+		chart     image.Rectangle
+		legend    image.Rectangle
+	}{
+		{"auto wide", image.Rect(0, 0, 60, 10), LegendAuto, image.Rect(0, 0, 51, 10), image.Rect(52, 0, 60, 10)},
+		{"auto tall", image.Rect(0, 0, 20, 20), LegendAuto, image.Rect(0, 0, 20, 18), image.Rect(0, 18, 20, 20)},
+		{"below", image.Rect(0, 0, 60, 10), LegendBelow, image.Rect(0, 0, 60, 8), image.Rect(0, 8, 60, 10)},
+		{"none", image.Rect(0, 0, 60, 10), LegendNone, image.Rect(0, 0, 60, 10), image.Rectangle{}},
+		{"no room", image.Rect(0, 0, 8, 3), LegendRight, image.Rect(0, 0, 8, 3), image.Rectangle{}},
+	}
+	for _, tt := range tests {
+		chart, legend := splitLegend(tt.ar, entries, tt.placement)
+		if chart != tt.chart || legend != tt.legend {
+			t.Errorf("%s: got chart %v and legend %v, expected %v and %v", tt.name, chart, legend, tt.chart, tt.legend)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// StatCompact is the format writing large values with a k, M, B or T
+// suffix, such as 1.23M.
+const StatCompact = "compact"
+
+// bigRows is the height in cells of the big digits, whose glyphs are five
+// pixels tall and drawn with half blocks.
+const bigRows = 3
+
+// bigGlyphs are the characters of the big font, a row of pixels per string.
+// The other characters of a value are written in the normal font.
+var bigGlyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'-': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	'%': {"#.#", "..#", ".#.", "#..", "#.#"},
+	'.': {".", ".", ".", ".", "#"},
+	',': {".", ".", ".", "#", "#"},
+	':': {".", "#", ".", "#", "."},
+}
+
+// StatOption is used to provide options to the stat widget.
+type StatOption interface {
+	set(*statOptions)
+}
+
+// statOptions stores the provided options.
+type statOptions struct {
+	unit       string
+	format     string
+	comparison string
+	downIsGood bool
+	color      cell.Color
+}
+
+// withStatUnit is a private type that implements the StatOption interface.
+type withStatUnit struct {
+	unit string
+}
+
+func (w *withStatUnit) set(opts *statOptions) {
+	opts.unit = w.unit
+}
+
+// StatUnit sets the unit written after the value, such as "ms".
+func StatUnit(unit string) StatOption {
+	return &withStatUnit{unit: unit}
+}
+
+// withStatFormat is a private type that implements the StatOption interface.
+type withStatFormat struct {
+	format string
+}
+
+func (w *withStatFormat) set(opts *statOptions) {
+	opts.format = w.format
+}
+
+// StatFormat sets how the value and its change are written: a fmt format
+// for a float64 such as "$%.2f", or StatCompact. By default whole values
+// are written with thousands separators and the others with two decimals.
+func StatFormat(format string) StatOption {
+	return &withStatFormat{format: format}
+}
+
+// withStatComparison is a private type that implements the StatOption interface.
+type withStatComparison struct {
+	label string
+}
+
+func (w *withStatComparison) set(opts *statOptions) {
+	opts.comparison = w.label
+}
+
+// StatComparison sets the text written after the change, naming the value
+// it is compared with, such as "vs last refresh".
+func StatComparison(label string) StatOption {
+	return &withStatComparison{label: label}
+}
+
+// withStatDownIsGood is a private type that implements the StatOption interface.
+type withStatDownIsGood struct{}
+
+func (*withStatDownIsGood) set(opts *statOptions) {
+	opts.downIsGood = true
+}
+
+// StatDownIsGood colors decreases green and increases red, for values such
+// as latencies or error counts.
+func StatDownIsGood() StatOption {
+	return &withStatDownIsGood{}
+}
+
+// withStatColor is a private type that implements the StatOption interface.
+type withStatColor struct {
+	color cell.Color
+}
+
+func (w *withStatColor) set(opts *statOptions) {
+	opts.color = w.color
+}
+
+// StatColor sets the color of the value.
+func StatColor(color cell.Color) StatOption {
+	return &withStatColor{color: color}
+}
+
+// Stat draws a single value in big digits, its change against a previous
+// value, in absolute terms and in percent, with an arrow colored by whether
+// the change is good, and a sparkline of its recent values below.
+type Stat struct {
+	mu sync.Mutex
+
+	// value is NaN when there is no data, and previous when there is
+	// nothing to compare with.
+	value, previous float64
+	history         []float64
+
+	opts *statOptions
+}
+
+// NewStat returns a new Stat widget.
+func NewStat(opts ...StatOption) (*Stat, error) {
+	opt := &statOptions{color: cell.ColorNumber(42)}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	if opt.format != "" && opt.format != StatCompact {
+		if s := fmt.Sprintf(opt.format, 1.5); strings.Contains(s, "%!") {
+			return nil, fmt.Errorf("invalid format '%s', expected a number format such as '%%.2f' or '%s'", opt.format, StatCompact)
+		}
+	}
+	return &Stat{value: math.NaN(), previous: math.NaN(), opts: opt}, nil
+}
+
+// Value replaces the value, the previous value it is compared with, and the
+// recent values drawn as a sparkline, from the oldest.
+func (s *Stat) Value(value, previous float64, history []float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.value, s.previous, s.history = value, previous, history
+	return nil
+}
+
+// format writes a value in the configured format.
+func (s *Stat) format(v float64) string {
+	switch s.opts.format {
+	case "":
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return groupThousands(strconv.FormatFloat(v, 'f', 0, 64))
+		}
+		return groupThousands(strconv.FormatFloat(v, 'f', 2, 64))
+	case StatCompact:
+		return formatCompact(v)
+	}
+	return fmt.Sprintf(s.opts.format, v)
+}
+
+// groupThousands separates the thousands of the integer part of a formatted
+// number with commas.
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, d := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+	return sign + b.String()
+}
+
+// formatCompact writes a value with up to three significant digits and the
+// suffix of its magnitude.
+func formatCompact(v float64) string {
+	for _, m := range []struct {
+		size   float64
+		suffix string
+	}{{1e12, "T"}, {1e9, "B"}, {1e6, "M"}, {1e3, "k"}} {
+		if math.Abs(v) >= m.size {
+			return strconv.FormatFloat(v/m.size, 'g', 3, 64) + m.suffix
+		}
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// change describes the change from the previous value, and returns the
+// color of its arrow. It is empty when there is nothing to compare.
+func (s *Stat) change() (string, cell.Color) {
+	if math.IsNaN(s.value) || math.IsNaN(s.previous) {
+		return "", 0
+	}
+	delta := s.value - s.previous
+	if delta == 0 {
+		return "= unchanged", cell.ColorNumber(244)
+	}
+	arrow, sign := "▼", ""
+	if delta > 0 {
+		arrow, sign = "▲", "+"
+	}
+	color := cell.ColorNumber(196)
+	if (delta > 0) != s.opts.downIsGood {
+		color = cell.ColorNumber(42)
+	}
+	text := fmt.Sprintf("%s %s%s", arrow, sign, s.format(delta))
+	if s.previous != 0 {
+		text += fmt.Sprintf(" (%+.1f%%)", delta/math.Abs(s.previous)*100)
+	}
+	return text, color
+}
+
+// bigWidth returns the width of a value written in the big font.
+func bigWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		if g, ok := bigGlyphs[r]; ok {
+			width += len(g[0]) + 1
+		} else {
+			width += runewidth.RuneWidth(r)
+		}
+	}
+	return width
+}
+
+// drawBig writes a value in the big font, with the characters it doesn't
+// have written on its bottom row.
+func drawBig(cvs *canvas.Canvas, text string, at image.Point, color cell.Color) error {
+	for _, r := range text {
+		g, ok := bigGlyphs[r]
+		if !ok {
+			if _, err := cvs.SetCell(image.Point{X: at.X, Y: at.Y + bigRows - 1}, r, cell.FgColor(color)); err != nil {
+				return err
+			}
+			at.X += runewidth.RuneWidth(r)
+			continue
+		}
+		for x := 0; x < len(g[0]); x++ {
+			for row := 0; row < bigRows; row++ {
+				top := g[2*row][x] == '#'
+				bottom := 2*row+1 < len(g) && g[2*row+1][x] == '#'
+				ch := ' '
+				switch {
+				case top && bottom:
+					ch = '█'
+				case top:
+					ch = '▀'
+				case bottom:
+					ch = '▄'
+				default:
+					continue
+				}
+				if _, err := cvs.SetCell(image.Point{X: at.X + x, Y: at.Y + row}, ch, cell.FgColor(color)); err != nil {
+					return err
+				}
+			}
+		}
+		at.X += len(g[0]) + 1
+	}
+	return nil
+}
+
+// Draw draws the Stat widget onto the canvas.
+func (s *Stat) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ar := cvs.Area()
+	value := "n/a"
+	if !math.IsNaN(s.value) {
+		value = s.format(s.value)
+	}
+	unit := ""
+	if s.opts.unit != "" {
+		unit = " " + s.opts.unit
+	}
+	change, changeColor := s.change()
+	if change != "" && s.opts.comparison != "" {
+		change += " " + s.opts.comparison
+	}
+
+	// The big digits need room for the change below them, and the
+	// sparkline gets the rows left, up to four.
+	big := ar.Dy() >= bigRows+1 && bigWidth(value)+runewidth.StringWidth(unit) <= ar.Dx()
+	valueRows := 1
+	if big {
+		valueRows = bigRows
+	}
+	sparkRows := 0
+	if len(s.history) > 1 {
+		sparkRows = min(4, ar.Dy()-valueRows-1)
+	}
+	sparkRows = max(0, sparkRows)
+	y := ar.Min.Y + max(0, ar.Dy()-valueRows-1-sparkRows)/2
+
+	if big {
+		width := bigWidth(value) + runewidth.StringWidth(unit)
+		at := image.Point{X: ar.Min.X + (ar.Dx()-width)/2, Y: y}
+		if err := drawBig(cvs, value, at, s.opts.color); err != nil {
+			return err
+		}
+		if unit != "" {
+			at := image.Point{X: at.X + bigWidth(value), Y: y + bigRows - 1}
+			if err := draw.Text(cvs, unit, at, draw.TextCellOpts(cell.FgColor(s.opts.color))); err != nil {
+				return err
+			}
+		}
+	} else if err := drawCentered(cvs, value+unit, ar.Min.X, ar.Dx(), y, s.opts.color); err != nil {
+		return err
+	}
+	y += valueRows
+
+	if change != "" && y < ar.Max.Y {
+		if err := drawCentered(cvs, change, ar.Min.X, ar.Dx(), y, changeColor); err != nil {
+			return err
+		}
+	}
+	if sparkRows > 0 {
+		return s.drawSparkline(cvs, image.Rect(ar.Min.X, ar.Max.Y-sparkRows, ar.Max.X, ar.Max.Y))
+	}
+	return nil
+}
+
+// drawSparkline draws the newest values that fit the area, scaled between
+// the smallest and the largest of them. Missing values are left out.
+func (s *Stat) drawSparkline(cvs *canvas.Canvas, area image.Rectangle) error {
+	values := s.history[max(0, len(s.history)-area.Dx()):]
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if math.IsInf(lo, 0) {
+		return nil
+	}
+	// The smallest value is drawn an eighth of a cell high.
+	floor := (hi - lo) / float64(8*area.Dy())
+	if hi == lo {
+		floor = 1
+	}
+	x := area.Max.X - len(values)
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		b := bar{values: []float64{v - lo + floor}, colors: []cell.Color{cell.ColorNumber(39)}}
+		bounds := b.eighths(hi-lo+floor, area.Dy())
+		for r := 0; r < area.Dy(); r++ {
+			ch, color, ok := b.cellAt(bounds, r, lowerBlocks)
+			if !ok {
+				break
+			}
+			if _, err := cvs.SetCell(image.Point{X: x + i, Y: area.Max.Y - 1 - r}, ch, cell.FgColor(color)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Keyboard input isn't supported on the Stat widget.
+func (*Stat) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	return errors.New("the Stat widget doesn't support keyboard events")
+}
+
+// Mouse input isn't supported on the Stat widget.
+func (*Stat) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Stat widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (s *Stat) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{8, 1},
+		WantKeyboard: widgetapi.KeyScopeNone,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
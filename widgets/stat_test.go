@@ -0,0 +1,80 @@
+package widgets
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+func TestStat_Format(t *testing.T) {
+	tests := []struct {
+		format string
+		v      float64
+		want   string
+	}{
+		{"", 1234567, "1,234,567"},
+		{"", -1234.5, "-1,234.50"},
+		{"", 12, "12"},
+		{StatCompact, 1234567, "1.23M"},
+		{StatCompact, -2500, "-2.5k"},
+		{StatCompact, 12.345, "12.3"},
+		{"$%.1f", 3.14159, "$3.1"},
+	}
+	for _, tt := range tests {
+		s, err := NewStat(StatFormat(tt.format))
+		if err != nil {
+			t.Fatalf("failed to create stat: %v", err)
+		}
+		if got := s.format(tt.v); got != tt.want {
+			t.Errorf("format %q of %v = %q, expected %q", tt.format, tt.v, got, tt.want)
+		}
+	}
+	if _, err := NewStat(StatFormat("%d items")); err == nil {
+		t.Error("NewStat accepted an integer format")
+	}
+}
+
+func TestStat_Change(t *testing.T) {
+	tests := []struct {
+		value, previous float64
+		downIsGood      bool
+		want            string
+		good            bool
+	}{
+		{110, 100, false, "▲ +10 (+10.0%)", true},
+		{90, 100, false, "▼ -10 (-10.0%)", false},
+		{90, 100, true, "▼ -10 (-10.0%)", true},
+		{5, 0, false, "▲ +5", true},
+		{5, -10, false, "▲ +15 (+150.0%)", true},
+	}
+	for _, tt := range tests {
+		var opts []StatOption
+		if tt.downIsGood {
+			opts = append(opts, StatDownIsGood())
+		}
+		s, err := NewStat(opts...)
+		if err != nil {
+			t.Fatalf("failed to create stat: %v", err)
+		}
+		if err := s.Value(tt.value, tt.previous, nil); err != nil {
+			t.Fatalf("Value failed: %v", err)
+		}
+		got, color := s.change()
+		if got != tt.want || (color == cell.ColorNumber(42)) != tt.good {
+			t.Errorf("change from %v to %v = %q in %v, expected %q, good %v", tt.previous, tt.value, got, color, tt.want, tt.good)
+		}
+	}
+
+	s, _ := NewStat()
+	s.Value(1, math.NaN(), nil)
+	if got, _ := s.change(); got != "" {
+		t.Errorf("change without a previous value = %q, expected none", got)
+	}
+}
+
+func TestBigWidth(t *testing.T) {
+	if got := bigWidth("1.5k"); got != 4+2+4+1 {
+		t.Errorf("bigWidth(\"1.5k\") = %d, expected 11", got)
+	}
+}
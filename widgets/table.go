@@ -119,6 +119,31 @@ func NewTable(headers []*Cell, rows [][]*Cell, opts ...TableOption) (*Table, err
 	return t, nil
 }
 
+// SetRows replaces the rows of the table, keeping the current page when it
+// still exists. The rows must have as many columns as the headers.
+func (t *Table) SetRows(rows [][]*Cell) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, row := range rows {
+		if len(t.headers) > 0 && len(row) != len(t.headers) {
+			return fmt.Errorf("all rows must have the same number of columns as the headers, expected %d, got %d", len(t.headers), len(row))
+		}
+	}
+	t.rows = rows
+	t.numPages = 0
+	if len(rows) > 0 && t.rowsPerPage > 0 {
+		t.numPages = int(math.Ceil(float64(len(rows)) / float64(t.rowsPerPage)))
+	}
+	if t.numPages == 0 && len(rows) > 0 {
+		t.numPages = 1
+	}
+	if t.currentPage >= t.numPages {
+		t.currentPage = max(t.numPages-1, 0)
+	}
+	return nil
+}
+
 // Draw draws the Table widget onto the canvas.
 // Implements widgetapi.Widget.Draw.
 func (t *Table) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
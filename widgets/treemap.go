@@ -0,0 +1,364 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/private/draw"
+	"github.com/mum4k/termdash/private/runewidth"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// cellAspect is the height of a terminal cell divided by its width, used to
+// lay out rectangles that look square.
+const cellAspect = 2
+
+// TreemapNode is a node of the hierarchy drawn by a Treemap. The value of a
+// node with children is the sum of theirs.
+type TreemapNode struct {
+	Name     string
+	Value    float64
+	Children []*TreemapNode
+}
+
+// total sets the value of the nodes with children to the sum of theirs, and
+// sorts the children from the largest. Missing and negative values count
+// as zero.
+func (n *TreemapNode) total() float64 {
+	if len(n.Children) == 0 {
+		if math.IsNaN(n.Value) || n.Value < 0 {
+			n.Value = 0
+		}
+		return n.Value
+	}
+	n.Value = 0
+	for _, c := range n.Children {
+		n.Value += c.total()
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Value > n.Children[j].Value
+	})
+	return n.Value
+}
+
+// child returns the child with the given name.
+func (n *TreemapNode) child(name string) (int, *TreemapNode) {
+	for i, c := range n.Children {
+		if c.Name == name {
+			return i, c
+		}
+	}
+	return -1, nil
+}
+
+// TreemapOption is used to provide options to the treemap widget.
+type TreemapOption interface {
+	set(*treemapOptions)
+}
+
+// treemapOptions stores the provided options.
+type treemapOptions struct {
+	rootName string
+}
+
+// withTreemapRootName is a private type that implements the TreemapOption interface.
+type withTreemapRootName struct {
+	name string
+}
+
+func (w *withTreemapRootName) set(opts *treemapOptions) {
+	opts.rootName = w.name
+}
+
+// TreemapRootName sets the name of the root of the hierarchy, first in the
+// path of the node drawn. It defaults to "All".
+func TreemapRootName(name string) TreemapOption {
+	return &withTreemapRootName{name: name}
+}
+
+// Treemap draws the children of a node of a hierarchy as rectangles whose
+// areas are proportional to their values, in a squarified layout, with the
+// path of the node above. When the widget is focused, the arrow keys select
+// a child, from the largest to the smallest, Enter drills into it and
+// Backspace goes back up.
+type Treemap struct {
+	mu sync.Mutex
+
+	root *TreemapNode
+	// path holds the names of the nodes drilled into, from the root.
+	path []string
+	// cursor is the index of the selected child.
+	cursor int
+
+	opts *treemapOptions
+}
+
+// NewTreemap returns a new Treemap widget.
+func NewTreemap(opts ...TreemapOption) (*Treemap, error) {
+	opt := &treemapOptions{rootName: "All"}
+	for _, o := range opts {
+		o.set(opt)
+	}
+	return &Treemap{opts: opt}, nil
+}
+
+// Root replaces the hierarchy, setting the values of its inner nodes. The
+// widget stays on the node it was drilled into, or on its closest ancestor
+// that still exists.
+func (t *Treemap) Root(root *TreemapNode) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if root == nil {
+		return errors.New("the root of the treemap cannot be nil")
+	}
+	root.total()
+	t.root = root
+	node := root
+	for i, name := range t.path {
+		_, c := node.child(name)
+		if c == nil || len(c.Children) == 0 {
+			t.path, t.cursor = t.path[:i], 0
+			break
+		}
+		node = c
+	}
+	t.cursor = max(0, min(t.cursor, len(node.Children)-1))
+	return nil
+}
+
+// current returns the node drawn.
+func (t *Treemap) current() *TreemapNode {
+	node := t.root
+	for _, name := range t.path {
+		_, node = node.child(name)
+	}
+	return node
+}
+
+// layoutRect is a rectangle of a treemap layout, in fractional cells.
+type layoutRect struct {
+	x, y, w, h float64
+}
+
+// squarify lays out values, sorted from the largest, in the rectangle r so
+// that their areas are proportional to them. It fills r with rows of
+// rectangles along its shorter side, adding rectangles to a row while that
+// makes them closer to squares.
+func squarify(values []float64, r layoutRect) []layoutRect {
+	out := make([]layoutRect, len(values))
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	if total <= 0 || r.w <= 0 || r.h <= 0 {
+		return out
+	}
+	areas := make([]float64, len(values))
+	for i, v := range values {
+		areas[i] = v / total * r.w * r.h
+	}
+	for i := 0; i < len(areas); {
+		side := math.Min(r.w, r.h)
+		j := i + 1
+		for j < len(areas) && worstRatio(areas[i:j+1], side) <= worstRatio(areas[i:j], side) {
+			j++
+		}
+		sum := 0.0
+		for _, a := range areas[i:j] {
+			sum += a
+		}
+		if sum <= 0 {
+			break
+		}
+		if r.w >= r.h {
+			// A column on the left.
+			width := sum / r.h
+			y := r.y
+			for k := i; k < j; k++ {
+				out[k] = layoutRect{r.x, y, width, areas[k] / width}
+				y += out[k].h
+			}
+			r.x, r.w = r.x+width, r.w-width
+		} else {
+			// A row at the top.
+			height := sum / r.w
+			x := r.x
+			for k := i; k < j; k++ {
+				out[k] = layoutRect{x, r.y, areas[k] / height, height}
+				x += out[k].w
+			}
+			r.y, r.h = r.y+height, r.h-height
+		}
+		i = j
+	}
+	return out
+}
+
+// worstRatio returns the largest aspect ratio of a row of areas laid out
+// along a side.
+func worstRatio(areas []float64, side float64) float64 {
+	sum, lo, hi := 0.0, math.Inf(1), 0.0
+	for _, a := range areas {
+		sum += a
+		lo, hi = math.Min(lo, a), math.Max(hi, a)
+	}
+	if lo <= 0 {
+		return math.Inf(1)
+	}
+	return math.Max(side*side*hi/(sum*sum), sum*sum/(side*side*lo))
+}
+
+// layout returns the cells of the children of node in the area, some of
+// which may be empty when they are too small to be drawn.
+func layout(node *TreemapNode, area image.Rectangle) []image.Rectangle {
+	values := make([]float64, len(node.Children))
+	for i, c := range node.Children {
+		values[i] = c.Value
+	}
+	// The layout is computed in square units, so that the rectangles look
+	// square on screen, and then rounded to cells.
+	rects := squarify(values, layoutRect{0, 0, float64(area.Dx()) / cellAspect, float64(area.Dy())})
+	cells := make([]image.Rectangle, len(rects))
+	for i, r := range rects {
+		x0, x1 := int(math.Round(r.x*cellAspect)), int(math.Round((r.x+r.w)*cellAspect))
+		y0, y1 := int(math.Round(r.y)), int(math.Round(r.y+r.h))
+		cells[i] = image.Rect(x0, y0, x1, y1).Add(area.Min)
+	}
+	return cells
+}
+
+// Draw draws the Treemap widget onto the canvas.
+func (t *Treemap) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.root == nil {
+		return nil
+	}
+	ar := cvs.Area()
+	node := t.current()
+	if err := t.drawPath(cvs, node, ar, meta.Focused); err != nil {
+		return err
+	}
+	area := image.Rect(ar.Min.X, ar.Min.Y+1, ar.Max.X, ar.Max.Y)
+	for i, r := range layout(node, area) {
+		if r.Empty() {
+			continue
+		}
+		if err := t.drawNode(cvs, node.Children[i], r, Palette[i%len(Palette)], meta.Focused && i == t.cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawPath writes the path of the node drawn on the first row, and the
+// selected child with its share of the node when the widget is focused.
+func (t *Treemap) drawPath(cvs *canvas.Canvas, node *TreemapNode, ar image.Rectangle, focused bool) error {
+	path := strings.Join(append([]string{t.opts.rootName}, t.path...), " › ")
+	info := formatBarValue(node.Value)
+	if focused && t.cursor < len(node.Children) && node.Value > 0 {
+		c := node.Children[t.cursor]
+		info = fmt.Sprintf("%s: %s (%.1f%%)", c.Name, formatBarValue(c.Value), c.Value/node.Value*100)
+	}
+	if err := draw.Text(cvs, path, ar.Min, draw.TextMaxX(ar.Max.X), draw.TextOverrunMode(draw.OverrunModeThreeDot),
+		draw.TextCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+		return err
+	}
+	x := ar.Min.X + runewidth.StringWidth(path) + 2
+	if x >= ar.Max.X {
+		return nil
+	}
+	return draw.Text(cvs, info, image.Point{X: x, Y: ar.Min.Y}, draw.TextMaxX(ar.Max.X), draw.TextOverrunMode(draw.OverrunModeThreeDot))
+}
+
+// drawNode fills the cells of a node with its color, leaving a blank column
+// and row between large nodes, and writes its name and value in it. Nodes
+// with children are marked with an arrow.
+func (t *Treemap) drawNode(cvs *canvas.Canvas, node *TreemapNode, r image.Rectangle, color cell.Color, selected bool) error {
+	if r.Dx() >= 3 {
+		r.Max.X--
+	}
+	if r.Dy() >= 3 {
+		r.Max.Y--
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if _, err := cvs.SetCell(image.Point{X: x, Y: y}, ' ', cell.BgColor(color)); err != nil {
+				return err
+			}
+		}
+	}
+	opts := []cell.Option{cell.FgColor(cell.ColorBlack), cell.BgColor(color)}
+	if selected {
+		opts = append(opts, cell.Inverse(), cell.Bold())
+	}
+	name := node.Name
+	if len(node.Children) > 0 {
+		name += " ▸"
+	}
+	lines := []string{name, formatBarValue(node.Value)}
+	for i, line := range lines[:min(len(lines), r.Dy())] {
+		if err := draw.Text(cvs, line, image.Point{X: r.Min.X, Y: r.Min.Y + i}, draw.TextMaxX(r.Max.X),
+			draw.TextOverrunMode(draw.OverrunModeThreeDot), draw.TextCellOpts(opts...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keyboard selects a child with the arrow keys, drills into it with Enter
+// and goes back up with Backspace.
+func (t *Treemap) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.root == nil {
+		return nil
+	}
+	node := t.current()
+	switch k.Key {
+	case keyboard.KeyArrowLeft, keyboard.KeyArrowUp:
+		t.cursor = max(0, t.cursor-1)
+	case keyboard.KeyArrowRight, keyboard.KeyArrowDown:
+		t.cursor = max(0, min(len(node.Children)-1, t.cursor+1))
+	case keyboard.KeyEnter:
+		if t.cursor < len(node.Children) && len(node.Children[t.cursor].Children) > 0 {
+			t.path = append(t.path, node.Children[t.cursor].Name)
+			t.cursor = 0
+		}
+	case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+		if len(t.path) == 0 {
+			return nil
+		}
+		name := t.path[len(t.path)-1]
+		t.path = t.path[:len(t.path)-1]
+		t.cursor, _ = t.current().child(name)
+		t.cursor = max(0, t.cursor)
+	}
+	return nil
+}
+
+// Mouse input isn't supported on the Treemap widget.
+func (*Treemap) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	return errors.New("the Treemap widget doesn't support mouse events")
+}
+
+// Options implements widgetapi.Widget.Options.
+func (t *Treemap) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{10, 4},
+		WantKeyboard: widgetapi.KeyScopeFocused,
+		WantMouse:    widgetapi.MouseScopeNone,
+	}
+}
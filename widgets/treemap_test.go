@@ -0,0 +1,86 @@
+package widgets
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+func TestSquarify(t *testing.T) {
+	values := []float64{6, 6, 4, 3, 2, 2, 1}
+	rects := squarify(values, layoutRect{0, 0, 6, 4})
+	area := 0.0
+	for i, r := range rects {
+		area += r.w * r.h
+		if got := r.w * r.h; math.Abs(got-values[i]) > 1e-9 {
+			t.Errorf("area of rectangle %d = %v, expected %v", i, got, values[i])
+		}
+		if r.x < -1e-9 || r.y < -1e-9 || r.x+r.w > 6+1e-9 || r.y+r.h > 4+1e-9 {
+			t.Errorf("rectangle %d = %+v is out of bounds", i, r)
+		}
+	}
+	if math.Abs(area-24) > 1e-9 {
+		t.Errorf("total area = %v, expected 24", area)
+	}
+	// The first two values fill a column of two squares.
+	if r := rects[0]; math.Abs(r.w-3) > 1e-9 || math.Abs(r.h-2) > 1e-9 {
+		t.Errorf("first rectangle = %+v, expected 3x2", r)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	node := &TreemapNode{Children: []*TreemapNode{{Value: 1}, {Value: 1}}}
+	cells := layout(node, image.Rect(0, 1, 20, 6))
+	if cells[0] != image.Rect(0, 1, 10, 6) || cells[1] != image.Rect(10, 1, 20, 6) {
+		t.Errorf("layout = %v, expected two halves", cells)
+	}
+}
+
+func TestTreemap_Drill(t *testing.T) {
+	tm, err := NewTreemap()
+	if err != nil {
+		t.Fatalf("failed to create treemap: %v", err)
+	}
+	tree := func() *TreemapNode {
+		return &TreemapNode{Children: []*TreemapNode{
+			{Name: "web", Children: []*TreemapNode{{Name: "cdn", Value: 1}}},
+			{Name: "data", Children: []*TreemapNode{{Name: "etl", Value: 2}, {Name: "ml", Value: 3}}},
+		}}
+	}
+	if err := tm.Root(tree()); err != nil {
+		t.Fatalf("Root failed: %v", err)
+	}
+	if got := tm.root.Value; got != 6 {
+		t.Errorf("root value = %v, expected 6", got)
+	}
+	key := func(k keyboard.Key) {
+		if err := tm.Keyboard(&terminalapi.Keyboard{Key: k}, nil); err != nil {
+			t.Fatalf("Keyboard failed: %v", err)
+		}
+	}
+	// data is the largest, so it comes first.
+	key(keyboard.KeyEnter)
+	if got := tm.current().Name; got != "data" {
+		t.Fatalf("drilled into %q, expected data", got)
+	}
+	// Leaves can't be drilled into.
+	key(keyboard.KeyEnter)
+	if len(tm.path) != 1 {
+		t.Errorf("path = %v, expected [data]", tm.path)
+	}
+	// The drilled node survives a refresh.
+	key(keyboard.KeyArrowRight)
+	if err := tm.Root(tree()); err != nil {
+		t.Fatalf("Root failed: %v", err)
+	}
+	if got := tm.current().Name; got != "data" || tm.cursor != 1 {
+		t.Errorf("after a refresh, on %q at %d, expected data at 1", got, tm.cursor)
+	}
+	key(keyboard.KeyBackspace2)
+	if len(tm.path) != 0 || tm.cursor != 0 {
+		t.Errorf("after going up, path = %v and cursor %d, expected the root at 0", tm.path, tm.cursor)
+	}
+}